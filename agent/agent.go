@@ -2,13 +2,16 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 
 	"github.com/HildaM/logs/slog"
 	"github.com/cloudwego/eino/compose"
 	"github.com/cloudwego/eino/schema"
 	"github.com/hildam/deer-flow-go/agent/coder"
 	"github.com/hildam/deer-flow-go/agent/coordinator"
+	"github.com/hildam/deer-flow-go/agent/custom"
 	"github.com/hildam/deer-flow-go/agent/human"
 	"github.com/hildam/deer-flow-go/agent/investigator"
 	"github.com/hildam/deer-flow-go/agent/planner"
@@ -18,6 +21,9 @@ import (
 	"github.com/hildam/deer-flow-go/entity/consts"
 	"github.com/hildam/deer-flow-go/entity/model"
 	"github.com/hildam/deer-flow-go/repo/checkpoint"
+	"github.com/hildam/deer-flow-go/repo/event"
+	"github.com/hildam/deer-flow-go/repo/usage"
+	"github.com/hildam/deer-flow-go/repo/workflow"
 )
 
 // Agent 定义了一个代理接口，用于创建和管理代理实例
@@ -26,17 +32,99 @@ type Agent[I, O any] interface {
 	NewGraphNode(ctx context.Context) (key string, node compose.AnyGraph, nameOption compose.GraphAddNodeOpt)
 }
 
-// BuildAgentGraph 用于构建代理图
-func BuildAgentGraph[I, O any](ctx context.Context, userMessage []*schema.Message) (compose.Runnable[I, O], error) {
+// StateOption 定制单次运行的初始状态，如请求中携带的运行参数
+type StateOption func(state *model.State)
+
+// BuildAgentGraph 用于构建代理图，使用配置的默认工作流
+func BuildAgentGraph[I, O any](ctx context.Context, userMessage []*schema.Message, opts ...StateOption) (compose.Runnable[I, O], error) {
+	return BuildWorkflowGraph[I, O](ctx, "", userMessage, opts...)
+}
+
+// BuildWorkflowGraph 按工作流构建代理图，只添加工作流中的节点，节点间的流转受工作流约束，name 为空时使用默认工作流
+func BuildWorkflowGraph[I, O any](ctx context.Context, name string, userMessage []*schema.Message, opts ...StateOption) (compose.Runnable[I, O], error) {
+	return buildWorkflowGraph[I, O](ctx, name, "", userMessage, nil, opts...)
+}
+
+// InspectWorkflowGraph 编译工作流的代理图并返回图结构，包含各智能体内部的子图，用于导出流程图
+func InspectWorkflowGraph(ctx context.Context, name string) (*compose.GraphInfo, error) {
+	inspector := &graphInspector{}
+	if _, err := buildWorkflowGraph[string, string](ctx, name, "", nil,
+		[]compose.GraphCompileOption{compose.WithGraphCompileCallbacks(inspector)}); err != nil {
+		return nil, err
+	}
+	return inspector.info, nil
+}
+
+// graphInspector 编译完成时记录图结构
+type graphInspector struct {
+	info *compose.GraphInfo
+}
+
+// OnFinish 实现 compose.GraphCompileCallback
+func (g *graphInspector) OnFinish(_ context.Context, info *compose.GraphInfo) {
+	g.info = info
+}
+
+// SaveCheckpoint 以 state 写入检查点，使用相同检查点ID恢复运行时从 state.Goto 指向的智能体继续执行
+// eino 只在中断时写入检查点，这里以 state.Goto 为入口编译工作流图并在入口前中断，写入的检查点与正常中断的格式一致
+// state.Goto 为空或为结束节点时无需恢复，不写入检查点
+func SaveCheckpoint(ctx context.Context, checkPointID string, state *model.State) error {
+	if state.Goto == "" || state.Goto == compose.END {
+		return nil
+	}
+	graph, err := buildWorkflowGraph[string, string](ctx, state.Workflow, state.Goto, nil,
+		[]compose.GraphCompileOption{compose.WithInterruptBeforeNodes([]string{state.Goto})},
+		func(s *model.State) {
+			*s = *state
+		})
+	if err != nil {
+		return err
+	}
+	// 忽略已有的检查点，WithForceNewRun 需放在最后，之后的选项会将其覆盖
+	_, err = graph.Invoke(ctx, state.Goto, compose.WithCheckPointID(checkPointID), compose.WithForceNewRun())
+	if _, ok := compose.ExtractInterruptInfo(err); !ok {
+		slog.Error("SaveCheckpoint failed, err = %v, checkpoint_id = %s", err, checkPointID)
+		return fmt.Errorf("save checkpoint %s: graph not interrupted, err: %w", checkPointID, err)
+	}
+	return nil
+}
+
+// buildWorkflowGraph 构建并编译代理图，entry 为空时从工作流的入口开始，compileOpts 追加到默认的编译选项之后
+func buildWorkflowGraph[I, O any](ctx context.Context, name, entry string, userMessage []*schema.Message,
+	compileOpts []compose.GraphCompileOption, opts ...StateOption) (compose.Runnable[I, O], error) {
+	// 配置中声明的自定义智能体，处理计划中的自定义步骤类型
+	agents := conf.GetCfg().Agents
+	if err := custom.Validate(agents); err != nil {
+		slog.Error("BuildWorkflowGraph failed, invalid custom agents, err = %v", err)
+		return nil, err
+	}
+	wf, err := workflow.Get(name, agents)
+	if err != nil {
+		slog.Error("BuildWorkflowGraph failed, get workflow err = %v, name = %s", err, name)
+		return nil, err
+	}
+	if entry == "" {
+		entry = wf.Entry
+	} else if !slices.Contains(wf.NodeNames(), entry) {
+		slog.Error("BuildWorkflowGraph failed, entry %s not in workflow %s", entry, wf.Name)
+		return nil, fmt.Errorf("entry %s not in workflow %s", entry, wf.Name)
+	}
+
 	// 初始化状态
 	stateGenFunc := func(ctx context.Context) *model.State {
-		return &model.State{
+		state := &model.State{
 			MaxPlanIterations: conf.GetCfg().Setting.MaxPlanIterations,
 			AutoAcceptedPlan:  true,
 			MaxStepNum:        conf.GetCfg().Setting.TotalMaxRound,
 			Messages:          userMessage,
-			Goto:              consts.Coordinator,
+			Goto:              wf.Entry,
+			StructuredReport:  conf.GetCfg().Setting.StructuredReport,
+			Workflow:          wf.Name,
+		}
+		for _, opt := range opts {
+			opt(state)
 		}
+		return state
 	}
 
 	// 创建 Agent 流程图
@@ -44,21 +132,24 @@ func BuildAgentGraph[I, O any](ctx context.Context, userMessage []*schema.Messag
 		compose.WithGenLocalState(stateGenFunc),
 	)
 
-	// 定义agent实例映射，确保节点名字与实例严格对应
-	agentInstances := map[string]Agent[I, O]{
-		consts.Coordinator:            coordinator.NewCoordinator[I, O](ctx),
-		consts.Planner:                planner.NewPlanner[I, O](ctx),
-		consts.Reporter:               repoter.NewRepoter[I, O](ctx),
-		consts.Researcher:             researcher.NewSingleResearcher[I, O](ctx),
-		consts.ResearchTeam:           researcher.NewResearcherTeam[I, O](ctx),
-		consts.Coder:                  coder.NewCoder[I, O](ctx),
-		consts.BackgroundInvestigator: investigator.NewInvestigator[I, O](ctx),
-		consts.Human:                  human.NewHuman[I, O](ctx),
+	// 定义agent实例映射，确保节点名字与实例严格对应，只创建工作流中的节点
+	constructors := map[string]func() Agent[I, O]{
+		consts.Coordinator:            func() Agent[I, O] { return coordinator.NewCoordinator[I, O](ctx) },
+		consts.Planner:                func() Agent[I, O] { return planner.NewPlanner[I, O](ctx, agents) },
+		consts.Reporter:               func() Agent[I, O] { return repoter.NewRepoter[I, O](ctx) },
+		consts.Researcher:             func() Agent[I, O] { return researcher.NewSingleResearcher[I, O](ctx) },
+		consts.ResearchTeam:           func() Agent[I, O] { return researcher.NewResearcherTeam[I, O](ctx, agents) },
+		consts.Coder:                  func() Agent[I, O] { return coder.NewCoder[I, O](ctx) },
+		consts.BackgroundInvestigator: func() Agent[I, O] { return investigator.NewInvestigator[I, O](ctx) },
+		consts.Human:                  func() Agent[I, O] { return human.NewHuman[I, O](ctx) },
+	}
+	for _, a := range agents {
+		constructors[a.Name] = func() Agent[I, O] { return custom.NewCustom[I, O](ctx, a) }
 	}
 
 	// 构造任务图 - 使用映射确保名字与实例对应
-	for agentName, agentInstance := range agentInstances {
-		key, node, nameOption := agentInstance.NewGraphNode(ctx)
+	for _, agentName := range wf.NodeNames() {
+		key, node, nameOption := constructors[agentName]().NewGraphNode(ctx)
 		// 验证返回的key与预期的agentName一致
 		if key != agentName {
 			slog.Error("Agent key mismatch: expected %s, got %s", agentName, key)
@@ -69,53 +160,89 @@ func BuildAgentGraph[I, O any](ctx context.Context, userMessage []*schema.Messag
 		graph.AddGraphNode(key, node, nameOption)
 	}
 
-	// 构造branch - 只为实际存在的agent创建分支
-	for agentName := range agentInstances {
+	// 构造branch - 只连接工作流允许的流转
+	for _, agentName := range wf.NodeNames() {
 		graph.AddBranch(agentName,
-			compose.NewGraphBranch(routeToNextAgent, getAgentGraphMap()))
+			compose.NewGraphBranch(routeFrom(wf, agentName), getAgentGraphMap(wf, agentName)))
 	}
 
 	// 构造起始边
-	graph.AddEdge(compose.START, consts.Coordinator)
+	graph.AddEdge(compose.START, entry)
 
 	// 编译图
-	runnable, err := graph.Compile(ctx,
+	runnable, err := graph.Compile(ctx, append([]compose.GraphCompileOption{
 		compose.WithGraphName(consts.GraphName),
 		compose.WithNodeTriggerMode(compose.AnyPredecessor),
 		compose.WithCheckPointStore(checkpoint.NewCheckPoint()), // 全局状态存储点
-	)
+	}, compileOpts...)...)
 	if err != nil {
-		slog.Error("BuildAgentGraph failed, err = %v", err)
+		slog.Error("BuildWorkflowGraph failed, err = %v, workflow = %s", err, wf.Name)
 		return nil, err
 	}
 	return runnable, nil
 }
 
+// routeFrom 返回 node 的分支函数，按工作流改写节点设置的 Goto 后路由到下一个代理节点
+// Goto 不在流转表中时返回附带状态摘要的 *model.RouteError，结束本次运行
+func routeFrom(wf *workflow.Workflow, node string) func(ctx context.Context, input string) (string, error) {
+	return func(ctx context.Context, input string) (string, error) {
+		var routeErr error
+		_ = compose.ProcessState[*model.State](ctx, func(_ context.Context, state *model.State) error {
+			next, err := wf.Next(node, state.Goto)
+			if err != nil {
+				var re *model.RouteError
+				if errors.As(err, &re) {
+					re.State = model.NewRouteState(state)
+				}
+				routeErr = err
+				return nil
+			}
+			state.Goto = next
+			return nil
+		})
+		if routeErr != nil {
+			slog.Error("route_to_next_agent failed, err = %v, node = %s", routeErr, node)
+			return "", routeErr
+		}
+		next, err := routeToNextAgent(ctx, input)
+		event.Emit(ctx, &model.Event{Type: model.EventAgentEnd, Agent: node, Next: next})
+		return next, err
+	}
+}
+
 // routeToNextAgent 根据状态中的Goto字段路由到下一个代理节点
 // 该函数从状态中读取目标代理名称，实现代理间的流程控制转移
 func routeToNextAgent(ctx context.Context, input string) (next string, err error) {
 	defer func() {
 		slog.Info("route_to_next_agent info, input = %s, next = %s", input, next)
 	}()
+	// 流式调用的用量在后台记录，等待刚结束的调用计入后再检查预算
+	tracker := usage.FromContext(ctx)
+	if tracker != nil {
+		tracker.Wait()
+	}
 	_ = compose.ProcessState[*model.State](ctx, func(_ context.Context, state *model.State) error {
 		next = state.Goto
+
+		// 记录用量汇总，并在超出预算时提前结束运行
+		if tracker != nil {
+			state.Usage = tracker.Summary()
+			if state.Usage.BudgetExceeded && next != compose.END {
+				slog.Error("route_to_next_agent budget exceeded, input = %s, next = %s, usage = %+v", input, next, state.Usage.Total)
+				next = compose.END
+				state.Goto = next
+			}
+		}
 		return nil
 	})
 	return next, nil
 }
 
-// getAgentGraphMap 返回所有可用的agent节点及其启用状态
-// 注意：这个函数应该与BuildAgentGraph中的agentInstances保持一致
-func getAgentGraphMap() map[string]bool {
-	return map[string]bool{
-		consts.Coordinator:            true, // 任务协调者，负责整体任务调度和协调
-		consts.Planner:                true, // 计划者，负责制定和优化执行计划
-		consts.Reporter:               true, // 报告者，负责生成和整理报告内容
-		consts.Researcher:             true, // 研究者，负责信息收集和分析
-		consts.ResearchTeam:           true, // 研究团队，负责协调多个研究任务
-		consts.Coder:                  true, // 代码生成者，负责编写和优化代码
-		consts.BackgroundInvestigator: true, // 背景调查者，负责深度背景信息挖掘
-		consts.Human:                  true, // 人工代理，负责人工干预和反馈
-		compose.END:                   true, // 流程结束节点，标记任务完成
+// getAgentGraphMap 返回 node 可流转到的agent节点及其启用状态，超出预算时任意节点都可直接结束
+func getAgentGraphMap(wf *workflow.Workflow, node string) map[string]bool {
+	m := map[string]bool{compose.END: true} // 流程结束节点，标记任务完成
+	for _, to := range wf.Targets(node) {
+		m[to] = true
 	}
+	return m
 }
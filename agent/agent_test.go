@@ -0,0 +1,883 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+	"github.com/google/uuid"
+
+	"github.com/hildam/deer-flow-go/agent/agenttest"
+	"github.com/hildam/deer-flow-go/entity/conf"
+	"github.com/hildam/deer-flow-go/entity/consts"
+	"github.com/hildam/deer-flow-go/entity/model"
+	"github.com/hildam/deer-flow-go/repo/callback"
+	"github.com/hildam/deer-flow-go/repo/event"
+	"github.com/hildam/deer-flow-go/repo/replay"
+	"github.com/hildam/deer-flow-go/repo/template"
+	"github.com/hildam/deer-flow-go/repo/usage"
+	"github.com/hildam/deer-flow-go/repo/workflow"
+)
+
+var testdata = agenttest.Testdata // 录制文件目录
+
+func TestMain(m *testing.M) {
+	conf.SetCfg(agenttest.Config())
+	os.Exit(m.Run())
+}
+
+// runFixture 回放录制文件执行一次完整运行，返回最终状态
+func runFixture(t *testing.T, fixture, query string, opts ...StateOption) (*model.State, *usage.Tracker) {
+	t.Helper()
+	startReplay(t, filepath.Join(testdata, fixture))
+	return runGraph(t, query, nil, opts...)
+}
+
+// startReplay 开启回放，测试结束时关闭
+func startReplay(t *testing.T, path string) {
+	t.Helper()
+	if err := replay.Start(replay.ModeReplay, path); err != nil {
+		t.Fatalf("start replay: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = replay.Close()
+	})
+}
+
+// runGraph 执行一次完整运行，logger 不为空时一并注册，返回最终状态
+// 可在多个 goroutine 中并发调用，失败时使用 t.Errorf 而非 t.Fatalf
+func runGraph(t *testing.T, query string, logger *callback.LoggerCallback, opts ...StateOption) (*model.State, *usage.Tracker) {
+	t.Helper()
+	return runGraphCtx(t, context.Background(), query, logger, opts...)
+}
+
+// runGraphCtx 同 runGraph，使用调用方的上下文，如注入了事件接收方的上下文
+func runGraphCtx(t *testing.T, ctx context.Context, query string, logger *callback.LoggerCallback, opts ...StateOption) (*model.State, *usage.Tracker) {
+	t.Helper()
+	var state *model.State
+	opts = append(opts, func(s *model.State) {
+		state = s
+	})
+
+	graph, err := BuildAgentGraph[string, string](ctx, []*schema.Message{schema.UserMessage(query)}, opts...)
+	if err != nil {
+		t.Errorf("BuildAgentGraph: %v", err)
+		return nil, nil
+	}
+
+	// 调用方可在上下文中注入带预算的统计器
+	tracker := usage.FromContext(ctx)
+	if tracker == nil {
+		tracker = usage.NewTracker(0, 0)
+		ctx = usage.WithTracker(ctx, tracker)
+	}
+	handlers := []callbacks.Handler{&callback.UsageCallback{Tracker: tracker}}
+	if logger != nil {
+		handlers = append(handlers, logger)
+	}
+	sr, err := graph.Stream(ctx, consts.Coordinator,
+		compose.WithCheckPointID(uuid.New().String()),
+		compose.WithCallbacks(handlers...),
+	)
+	if err != nil {
+		t.Errorf("Stream: %v", err)
+		return state, tracker
+	}
+	defer sr.Close()
+	for {
+		if _, err := sr.Recv(); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			t.Errorf("Recv: %v", err)
+			break
+		}
+	}
+	if logger != nil {
+		logger.Wait()
+	}
+	return state, tracker
+}
+
+func TestBuildAgentGraphGreeting(t *testing.T) {
+	state, _ := runFixture(t, "greeting.json", "hello")
+
+	if state.Goto != compose.END {
+		t.Errorf("goto = %q, want %q", state.Goto, compose.END)
+	}
+	if state.CurrentPlan != nil {
+		t.Errorf("plan = %+v, want nil for a greeting", state.CurrentPlan)
+	}
+}
+
+func TestBuildAgentGraphResearch(t *testing.T) {
+	state, tracker := runFixture(t, "research.json", "How widely are Go generics adopted?")
+
+	if state.Locale != "en-US" {
+		t.Errorf("locale = %q, want en-US", state.Locale)
+	}
+	if state.CurrentPlan == nil || len(state.CurrentPlan.Steps) != 1 {
+		t.Fatalf("plan = %+v, want one step", state.CurrentPlan)
+	}
+	res := state.CurrentPlan.Steps[0].ExecutionRes
+	if res == nil || *res != "Most Go developers surveyed have used generics at least once." {
+		t.Errorf("step result = %v", res)
+	}
+	want := "# Go generics adoption\n\n## Key Points\n\n- Most Go developers surveyed have used generics at least once."
+	if state.FinalReport != want {
+		t.Errorf("final report = %q, want %q", state.FinalReport, want)
+	}
+	// 流式输出的用量在后台读取，等待统计完成
+	tracker.Wait()
+	if got := tracker.Summary().Total.TotalTokens; got != 150 {
+		t.Errorf("total tokens = %d, want 150", got)
+	}
+}
+
+// TestReplayMatchesAgent 录制的调用按发起调用的智能体回放，与录制文件中的顺序无关
+func TestReplayMatchesAgent(t *testing.T) {
+	fixture, err := replay.LoadFixture(filepath.Join(testdata, "research.json"))
+	if err != nil {
+		t.Fatalf("load fixture: %v", err)
+	}
+	// Reporter 的调用移到最前，Planner 的调用移到最后
+	chats := fixture.Chats
+	fixture.Chats = []replay.ChatExchange{chats[4], chats[0], chats[2], chats[3], chats[1]}
+	path := filepath.Join(t.TempDir(), "research.json")
+	if err := fixture.Save(path); err != nil {
+		t.Fatalf("save fixture: %v", err)
+	}
+	startReplay(t, path)
+
+	state, _ := runGraph(t, "How widely are Go generics adopted?", nil)
+	if state.CurrentPlan == nil || len(state.CurrentPlan.Steps) != 1 {
+		t.Fatalf("plan = %+v, want one step", state.CurrentPlan)
+	}
+	if !strings.HasPrefix(state.FinalReport, "# Go generics adoption") {
+		t.Errorf("final report = %q, want the recorded report", state.FinalReport)
+	}
+}
+
+// TestReplayAgentMismatch 录制文件中没有当前智能体的调用时明确报错，而不是返回其他智能体的响应
+func TestReplayAgentMismatch(t *testing.T) {
+	fixture, err := replay.LoadFixture(filepath.Join(testdata, "research.json"))
+	if err != nil {
+		t.Fatalf("load fixture: %v", err)
+	}
+	fixture.Chats[1].Agent = consts.Reporter
+	path := filepath.Join(t.TempDir(), "research.json")
+	if err := fixture.Save(path); err != nil {
+		t.Fatalf("save fixture: %v", err)
+	}
+	startReplay(t, path)
+
+	ctx := context.Background()
+	graph, err := BuildAgentGraph[string, string](ctx, []*schema.Message{schema.UserMessage("How widely are Go generics adopted?")})
+	if err != nil {
+		t.Fatalf("BuildAgentGraph: %v", err)
+	}
+	// 回调将当前智能体写入上下文，回放模型据此匹配录制的调用
+	_, err = graph.Invoke(ctx, consts.Coordinator,
+		compose.WithCheckPointID(uuid.New().String()),
+		compose.WithCallbacks(&callback.UsageCallback{Tracker: usage.NewTracker(0, 0)}),
+	)
+	if err == nil || !strings.Contains(err.Error(), `agent "planner"`) || !strings.Contains(err.Error(), `recorded for agent "reporter"`) {
+		t.Fatalf("Invoke err = %v, want a replay mismatch for the planner", err)
+	}
+}
+
+// TestBuildAgentGraphConcurrent 多个运行并发执行时状态、回调和输出互不干扰，需配合 -race 运行
+func TestBuildAgentGraphConcurrent(t *testing.T) {
+	const runs = 8
+	fixture, err := replay.LoadFixture(filepath.Join(testdata, "greeting.json"))
+	if err != nil {
+		t.Fatalf("load fixture: %v", err)
+	}
+	// 每个运行各消耗一次相同的模型响应
+	greeting := fixture.Chats[0]
+	fixture.Chats = nil
+	for i := 0; i < runs; i++ {
+		fixture.Chats = append(fixture.Chats, greeting)
+	}
+	path := filepath.Join(t.TempDir(), "greeting.json")
+	if err := fixture.Save(path); err != nil {
+		t.Fatalf("save fixture: %v", err)
+	}
+	startReplay(t, path)
+
+	var wg sync.WaitGroup
+	for i := 0; i < runs; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			query := fmt.Sprintf("hello #%d", i)
+
+			out := make(chan string)
+			var sb strings.Builder
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for s := range out {
+					sb.WriteString(s)
+				}
+			}()
+			state, _ := runGraph(t, query, &callback.LoggerCallback{ID: query, Out: out})
+			close(out)
+			<-done
+
+			if state == nil {
+				return
+			}
+			if state.Goto != compose.END {
+				t.Errorf("run %d: goto = %q, want %q", i, state.Goto, compose.END)
+			}
+			if len(state.Messages) == 0 || state.Messages[0].Content != query {
+				t.Errorf("run %d: messages = %v, want to start with %q", i, state.Messages, query)
+			}
+			if !strings.Contains(sb.String(), greeting.Output.Content) {
+				t.Errorf("run %d: output = %q, want greeting", i, sb.String())
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestBuildAgentGraphStructuredReport(t *testing.T) {
+	fixture, err := replay.LoadFixture(filepath.Join(testdata, "research.json"))
+	if err != nil {
+		t.Fatalf("load fixture: %v", err)
+	}
+	// 最后一次模型调用为 Reporter，替换为结构化输出，代码块标记应被忽略
+	report := fixture.Chats[len(fixture.Chats)-1].Output
+	report.Content = "```json\n" + `{"title":"Go generics adoption","key_points":["Most Go developers surveyed have used generics at least once."],` +
+		`"overview":"Generics shipped in Go 1.18.","sections":[{"title":"Survey results","content":"Adoption keeps growing.",` +
+		`"tables":[{"headers":["Source","Usage"],"rows":[["Go Developer Survey","70% | growing"]]}]}],` +
+		`"citations":[{"title":"Go Developer Survey","url":"https://go.dev/blog/survey"}]}` + "\n```"
+	path := filepath.Join(t.TempDir(), "research.json")
+	if err := fixture.Save(path); err != nil {
+		t.Fatalf("save fixture: %v", err)
+	}
+	startReplay(t, path)
+
+	collector := &eventCollector{}
+	ctx := event.WithEmitter(context.Background(), collector)
+	state, _ := runGraphCtx(t, ctx, "How widely are Go generics adopted?", nil, func(s *model.State) {
+		s.StructuredReport = true
+	})
+	if state.Report == nil || len(state.Report.Sections) != 1 || len(state.Report.Sections[0].Tables) != 1 {
+		t.Fatalf("report = %+v, want one section with a table", state.Report)
+	}
+	want := "# Go generics adoption\n\n" +
+		"## Key Points\n\n- Most Go developers surveyed have used generics at least once.\n\n" +
+		"## Overview\n\nGenerics shipped in Go 1.18.\n\n" +
+		"## Detailed Analysis\n\n### Survey results\n\nAdoption keeps growing.\n\n" +
+		"| Source | Usage |\n|---|---|\n| Go Developer Survey | 70% \\| growing |\n\n" +
+		"## Key Citations\n\n- [Go Developer Survey](https://go.dev/blog/survey)\n"
+	if state.FinalReport != want {
+		t.Errorf("final report = %q, want %q", state.FinalReport, want)
+	}
+
+	// 流式推送的是 JSON，解析后推送渲染好的报告
+	var rendered *model.Event
+	for _, e := range collector.events {
+		if e.Type == model.EventReport {
+			rendered = e
+		}
+	}
+	if rendered == nil || rendered.Content != want || rendered.Report != state.Report {
+		t.Errorf("report event = %+v, want rendered report", rendered)
+	}
+}
+
+func TestBuildAgentGraphCustomAgent(t *testing.T) {
+	fixture, err := replay.LoadFixture(filepath.Join(testdata, "research.json"))
+	if err != nil {
+		t.Fatalf("load fixture: %v", err)
+	}
+	// 计划中的步骤改为自定义类型，由自定义智能体一次调用完成，不使用工具
+	plan := fixture.Chats[1].Output
+	plan.Content = strings.Replace(plan.Content, `"step_type":"research"`, `"step_type":"legal_review"`, 1)
+	review := fixture.Chats[3]
+	review.Agent = "legal_reviewer"
+	review.Tools = nil
+	review.Output.Content = "No licensing issues found."
+	fixture.Chats = append(fixture.Chats[:2], review, fixture.Chats[4])
+	path := filepath.Join(t.TempDir(), "research.json")
+	if err := fixture.Save(path); err != nil {
+		t.Fatalf("save fixture: %v", err)
+	}
+	startReplay(t, path)
+
+	// 自定义智能体的提示词放在覆盖目录中
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "legal.md"), []byte("You review licenses. {{ locale }}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	old := conf.GetCfg()
+	cfg := *old
+	cfg.Prompt.Dir = dir
+	cfg.Agents = []conf.AgentConfig{{
+		Name:        "legal_reviewer",
+		StepType:    "legal_review",
+		Description: "Review licensing and compliance risks.",
+		Prompt:      "legal",
+	}}
+	conf.SetCfg(&cfg)
+	if err := template.Init(); err != nil {
+		t.Fatalf("template.Init: %v", err)
+	}
+	t.Cleanup(func() {
+		conf.SetCfg(old)
+		_ = template.Init()
+	})
+
+	state, _ := runGraph(t, "How widely are Go generics adopted?", nil)
+	if state.CurrentPlan == nil || len(state.CurrentPlan.Steps) != 1 {
+		t.Fatalf("plan = %+v, want one step", state.CurrentPlan)
+	}
+	if res := state.CurrentPlan.Steps[0].ExecutionRes; res == nil || *res != "No licensing issues found." {
+		t.Errorf("step result = %v", res)
+	}
+	if ref, ok := state.Prompts["legal"]; !ok || ref.Source != template.SourceOverride {
+		t.Errorf("prompts = %+v, want legal from override", state.Prompts)
+	}
+
+	// 与内置智能体重名的配置被拒绝
+	bad := cfg
+	bad.Agents = []conf.AgentConfig{{Name: consts.Coder, StepType: "legal_review"}}
+	conf.SetCfg(&bad)
+	if _, err := BuildAgentGraph[string, string](context.Background(), nil); err == nil {
+		t.Error("BuildAgentGraph: want error for duplicate agent name")
+	}
+}
+
+func TestBuildWorkflowGraphQuickAnswer(t *testing.T) {
+	fixture, err := replay.LoadFixture(filepath.Join(testdata, "research.json"))
+	if err != nil {
+		t.Fatalf("load fixture: %v", err)
+	}
+	// 快速回答跳过 Planner，去掉计划的模型调用
+	fixture.Chats = append(fixture.Chats[:1], fixture.Chats[2:]...)
+	path := filepath.Join(t.TempDir(), "research.json")
+	if err := fixture.Save(path); err != nil {
+		t.Fatalf("save fixture: %v", err)
+	}
+	startReplay(t, path)
+
+	const query = "How widely are Go generics adopted?"
+	var state *model.State
+	graph, err := BuildWorkflowGraph[string, string](context.Background(), "quick_answer",
+		[]*schema.Message{schema.UserMessage(query)}, func(s *model.State) { state = s })
+	if err != nil {
+		t.Fatalf("BuildWorkflowGraph: %v", err)
+	}
+	if _, err := graph.Invoke(context.Background(), "", compose.WithCheckPointID(uuid.New().String())); err != nil {
+		t.Fatalf("Invoke: %v", err)
+	}
+
+	if state.Workflow != "quick_answer" {
+		t.Errorf("workflow = %q, want quick_answer", state.Workflow)
+	}
+	// Researcher 以用户问题作为唯一的研究步骤
+	if state.CurrentPlan == nil || len(state.CurrentPlan.Steps) != 1 || state.CurrentPlan.Steps[0].Title != query {
+		t.Fatalf("plan = %+v, want an implicit one-step plan", state.CurrentPlan)
+	}
+	if res := state.CurrentPlan.Steps[0].ExecutionRes; res == nil || *res != "Most Go developers surveyed have used generics at least once." {
+		t.Errorf("step result = %v", res)
+	}
+	if !strings.HasPrefix(state.FinalReport, "# Go generics adoption") {
+		t.Errorf("final report = %q", state.FinalReport)
+	}
+	if _, ok := state.Prompts[consts.Planner]; ok {
+		t.Error("planner should not run in quick_answer")
+	}
+
+	if _, err := BuildWorkflowGraph[string, string](context.Background(), "missing", nil); err == nil {
+		t.Error("BuildWorkflowGraph: want error for unknown workflow")
+	}
+}
+
+func TestRouteFromIllegalTransition(t *testing.T) {
+	wf, err := workflow.Get("quick_answer", nil)
+	if err != nil {
+		t.Fatalf("workflow.Get: %v", err)
+	}
+	// Coordinator 的 router 错误地设置了流转表之外的 Goto
+	graph := compose.NewGraph[string, string](compose.WithGenLocalState(func(ctx context.Context) *model.State {
+		return &model.State{Workflow: wf.Name, PlanIterations: 1}
+	}))
+	graph.AddLambdaNode(consts.Coordinator, compose.InvokableLambda(func(ctx context.Context, input string) (string, error) {
+		err := compose.ProcessState[*model.State](ctx, func(_ context.Context, state *model.State) error {
+			state.Goto = consts.Coder
+			return nil
+		})
+		return input, err
+	}))
+	graph.AddLambdaNode(consts.Researcher, compose.InvokableLambda(func(ctx context.Context, input string) (string, error) {
+		return input, nil
+	}))
+	graph.AddEdge(compose.START, consts.Coordinator)
+	graph.AddBranch(consts.Coordinator, compose.NewGraphBranch(routeFrom(wf, consts.Coordinator), getAgentGraphMap(wf, consts.Coordinator)))
+	graph.AddEdge(consts.Researcher, compose.END)
+	runnable, err := graph.Compile(context.Background())
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+
+	_, err = runnable.Invoke(context.Background(), "")
+	var re *model.RouteError
+	if !errors.As(err, &re) {
+		t.Fatalf("Invoke err = %v, want *model.RouteError", err)
+	}
+	if re.Agent != consts.Coordinator || re.Goto != consts.Coder || re.State == nil || re.State.PlanIterations != 1 {
+		t.Errorf("RouteError = %+v", re)
+	}
+}
+
+func TestInspectWorkflowGraph(t *testing.T) {
+	info, err := InspectWorkflowGraph(context.Background(), "quick_answer")
+	if err != nil {
+		t.Fatalf("InspectWorkflowGraph: %v", err)
+	}
+	if info.Name != consts.GraphName || len(info.Nodes) != 3 {
+		t.Fatalf("graph = %s with %d nodes, want 3 nodes of quick_answer", info.Name, len(info.Nodes))
+	}
+	// 智能体内部的子图同样可见
+	sub := info.Nodes[consts.Researcher].GraphInfo
+	if sub == nil {
+		t.Fatal("researcher: no subgraph info")
+	}
+	for _, key := range []string{"load", "agent", "router"} {
+		if _, ok := sub.Nodes[key]; !ok {
+			t.Errorf("researcher subgraph missing node %s", key)
+		}
+	}
+}
+
+// eventCollector 收集运行中发出的工作流事件
+type eventCollector struct {
+	mu     sync.Mutex
+	events []*model.Event
+}
+
+func (c *eventCollector) Emit(_ context.Context, e *model.Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.events = append(c.events, e)
+}
+
+func TestWorkflowEvents(t *testing.T) {
+	startReplay(t, filepath.Join(testdata, "research.json"))
+	collector := &eventCollector{}
+	ctx := event.WithEmitter(context.Background(), collector)
+	runGraphCtx(t, ctx, "How widely are Go generics adopted?", nil)
+
+	var got []string
+	for _, e := range collector.events {
+		switch e.Type {
+		case model.EventAgentEnd:
+			got = append(got, fmt.Sprintf("%s %s->%s", e.Type, e.Agent, e.Next))
+		case model.EventStepStarted, model.EventStepCompleted:
+			got = append(got, fmt.Sprintf("%s %s %d/%d", e.Type, e.Agent, e.Step.Index+1, e.Step.Total))
+		default:
+			got = append(got, fmt.Sprintf("%s %s", e.Type, e.Agent))
+		}
+		if e.Time.IsZero() {
+			t.Errorf("%s event has zero time", e.Type)
+		}
+	}
+	want := []string{
+		"agent_end coordinator->planner",
+		"plan_created planner",
+		"agent_end planner->human_feedback",
+		"agent_end human_feedback->research_team",
+		"agent_end research_team->researcher",
+		"step_started researcher 1/1",
+		"step_completed researcher 1/1",
+		"agent_end researcher->research_team",
+		"agent_end research_team->reporter",
+		"agent_end reporter->end",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("events:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	for _, e := range collector.events {
+		switch e.Type {
+		case model.EventPlanCreated:
+			if e.Plan == nil || len(e.Plan.Steps) != 1 || e.Plan.Steps[0].ExecutionRes != nil {
+				t.Errorf("plan_created plan = %+v, want one pending step", e.Plan)
+			}
+		case model.EventStepCompleted:
+			if e.Step.Summary != "Most Go developers surveyed have used generics at least once." {
+				t.Errorf("step summary = %q", e.Step.Summary)
+			}
+		}
+	}
+}
+
+// failingResearchFixture 在 Researcher 的第一次模型调用前插入 n 次失败的调用，返回录制文件路径
+// keep 为 false 时去掉 Researcher 原有的调用，步骤无论重试多少次都会失败
+func failingResearchFixture(t *testing.T, n int, keep bool) string {
+	t.Helper()
+	fixture, err := replay.LoadFixture(filepath.Join(testdata, "research.json"))
+	if err != nil {
+		t.Fatalf("load fixture: %v", err)
+	}
+	// 录制文件依次为 Coordinator、Planner、Researcher（两次）和 Reporter 的调用
+	chats := slices.Clone(fixture.Chats[:2])
+	for i := 0; i < n; i++ {
+		chats = append(chats, replay.ChatExchange{Tools: fixture.Chats[2].Tools, Error: "tool web_search failed: rate limited"})
+	}
+	if keep {
+		chats = append(chats, fixture.Chats[2:4]...)
+	}
+	fixture.Chats = append(chats, fixture.Chats[4])
+	path := filepath.Join(t.TempDir(), "research.json")
+	if err := fixture.Save(path); err != nil {
+		t.Fatalf("save fixture: %v", err)
+	}
+	return path
+}
+
+// withStepPolicy 临时修改步骤重试策略
+func withStepPolicy(t *testing.T, retries int, onFailure string) {
+	t.Helper()
+	old := conf.GetCfg()
+	cfg := *old
+	cfg.Setting.StepMaxRetries = retries
+	cfg.Setting.StepOnFailure = onFailure
+	conf.SetCfg(&cfg)
+	t.Cleanup(func() {
+		conf.SetCfg(old)
+	})
+}
+
+func TestStepRetry(t *testing.T) {
+	withStepPolicy(t, 1, "")
+	startReplay(t, failingResearchFixture(t, 1, true))
+
+	state, _ := runGraph(t, "How widely are Go generics adopted?", nil)
+	step := state.CurrentPlan.Steps[0]
+	if step.Error != "" || step.ExecutionRes == nil || *step.ExecutionRes != "Most Go developers surveyed have used generics at least once." {
+		t.Errorf("step = %+v, want the retried result", step)
+	}
+	if step.Status != model.StepSucceeded || step.Attempts != 2 {
+		t.Errorf("status = %q, attempts = %d, want succeeded after 2 attempts", step.Status, step.Attempts)
+	}
+}
+
+func TestStepFailureContinue(t *testing.T) {
+	withStepPolicy(t, 1, consts.StepFailureContinue)
+	startReplay(t, failingResearchFixture(t, 2, false))
+	collector := &eventCollector{}
+	ctx := event.WithEmitter(context.Background(), collector)
+
+	state, _ := runGraphCtx(t, ctx, "How widely are Go generics adopted?", nil)
+	step := state.CurrentPlan.Steps[0]
+	if step.Status != model.StepFailed || step.ExecutionRes != nil || !strings.Contains(step.Error, "rate limited") {
+		t.Fatalf("step = %+v, want failed with the error recorded", step)
+	}
+	if step.Attempts != 2 || step.FinishedAt == nil {
+		t.Errorf("attempts = %d, finished at = %v, want 2 attempts and a finish time", step.Attempts, step.FinishedAt)
+	}
+	// ResearchTeam 跳过失败的步骤，Reporter 仍然生成报告
+	if state.FinalReport == "" {
+		t.Error("final report is empty, want the Reporter to run after the failed step")
+	}
+	var failed *model.Event
+	for _, e := range collector.events {
+		if e.Type == model.EventStepFailed {
+			failed = e
+		}
+	}
+	if failed == nil || failed.Agent != consts.Researcher || failed.Step.Error != step.Error {
+		t.Errorf("step_failed event = %+v, want one from researcher with the step error", failed)
+	}
+}
+
+func TestStepFailureAbort(t *testing.T) {
+	withStepPolicy(t, 0, consts.StepFailureAbort)
+	startReplay(t, failingResearchFixture(t, 1, false))
+
+	graph, err := BuildAgentGraph[string, string](context.Background(), []*schema.Message{schema.UserMessage("How widely are Go generics adopted?")})
+	if err != nil {
+		t.Fatalf("BuildAgentGraph: %v", err)
+	}
+	_, err = graph.Invoke(context.Background(), consts.Coordinator, compose.WithCheckPointID(uuid.New().String()))
+	if err == nil || !strings.Contains(err.Error(), "rate limited") {
+		t.Errorf("Invoke err = %v, want the step error", err)
+	}
+}
+
+// TestStepFailureMidStream Researcher 的最终回答输出到一半断开，重试后仍然断开时将步骤标记为失败并继续运行
+func TestStepFailureMidStream(t *testing.T) {
+	fixture, err := replay.LoadFixture(filepath.Join(testdata, "research.json"))
+	if err != nil {
+		t.Fatalf("load fixture: %v", err)
+	}
+	// 录制文件依次为 Coordinator、Planner、Researcher（两次）和 Reporter 的调用，每次尝试都先调用工具再输出回答
+	broken := replay.ChatExchange{
+		Tools:  fixture.Chats[3].Tools,
+		Output: schema.AssistantMessage("Most Go developers", nil),
+		Error:  "connection reset by peer",
+	}
+	fixture.Chats = []replay.ChatExchange{fixture.Chats[0], fixture.Chats[1],
+		fixture.Chats[2], broken, fixture.Chats[2], broken, fixture.Chats[4]}
+	fixture.ToolCalls = append(fixture.ToolCalls, fixture.ToolCalls...)
+	path := filepath.Join(t.TempDir(), "research.json")
+	if err := fixture.Save(path); err != nil {
+		t.Fatalf("save fixture: %v", err)
+	}
+	withStepPolicy(t, 1, consts.StepFailureContinue)
+	startReplay(t, path)
+
+	out := make(chan string)
+	var sb strings.Builder
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for s := range out {
+			sb.WriteString(s)
+		}
+	}()
+	logger := &callback.LoggerCallback{ID: "mid-stream", Out: out}
+	state, _ := runGraphCtx(t, event.WithEmitter(context.Background(), logger), "How widely are Go generics adopted?", logger)
+	close(out)
+	<-done
+
+	step := state.CurrentPlan.Steps[0]
+	if step.Status != model.StepFailed || step.Attempts != 2 || !strings.Contains(step.Error, "connection reset") {
+		t.Errorf("step = %+v, want failed after 2 attempts with the stream error", step)
+	}
+	if state.FinalReport == "" {
+		t.Error("final report is empty, want the Reporter to run after the failed step")
+	}
+	// 第一次尝试的部分输出之后、重试的输出之前推送 step_retry，客户端据此丢弃部分输出
+	text := sb.String()
+	before, after, ok := strings.Cut(text, "[step_retry] 1/1 Survey adoption (attempt 1): ")
+	retried, failed := strings.Index(after, "Most Go developers"), strings.Index(after, "[step_failed]")
+	if !ok || !strings.Contains(before, "Most Go developers") || retried < 0 || failed < retried || strings.Contains(after, "[step_retry]") {
+		t.Errorf("output = %q, want partial output, step_retry, retried output, step_failed in order", text)
+	}
+}
+
+func TestStepLifecycle(t *testing.T) {
+	state, _ := runFixture(t, "research.json", "How widely are Go generics adopted?")
+
+	step := state.CurrentPlan.Steps[0]
+	if step.Status != model.StepSucceeded || step.Agent != consts.Researcher || step.Attempts != 1 {
+		t.Errorf("step = %+v, want succeeded by researcher in one attempt", step)
+	}
+	if step.StartedAt == nil || step.FinishedAt == nil || step.FinishedAt.Before(*step.StartedAt) {
+		t.Errorf("started at = %v, finished at = %v, want both set in order", step.StartedAt, step.FinishedAt)
+	}
+	want := []model.StepToolCall{{Name: "web_search", Arguments: `{"query":"Go generics adoption survey"}`}}
+	for i := range step.ToolCalls {
+		step.ToolCalls[i].DurationMs = 0
+	}
+	if !slices.Equal(step.ToolCalls, want) {
+		t.Errorf("tool calls = %+v, want %+v", step.ToolCalls, want)
+	}
+}
+
+func TestStepSkipped(t *testing.T) {
+	fixture, err := replay.LoadFixture(filepath.Join(testdata, "research.json"))
+	if err != nil {
+		t.Fatalf("load fixture: %v", err)
+	}
+	// 计划中的步骤类型没有智能体处理，Researcher 的调用不会发生
+	plan := fixture.Chats[1].Output
+	plan.Content = strings.Replace(plan.Content, `"step_type":"research"`, `"step_type":"legal_review"`, 1)
+	fixture.Chats = append(fixture.Chats[:2], fixture.Chats[4])
+	path := filepath.Join(t.TempDir(), "research.json")
+	if err := fixture.Save(path); err != nil {
+		t.Fatalf("save fixture: %v", err)
+	}
+	startReplay(t, path)
+	collector := &eventCollector{}
+	ctx := event.WithEmitter(context.Background(), collector)
+
+	state, _ := runGraphCtx(t, ctx, "How widely are Go generics adopted?", nil)
+	step := state.CurrentPlan.Steps[0]
+	if step.Status != model.StepSkipped || !strings.Contains(step.Error, "legal_review") {
+		t.Errorf("step = %+v, want skipped with the reason", step)
+	}
+	if state.FinalReport == "" {
+		t.Error("final report is empty, want the Reporter to run after skipping")
+	}
+	if !slices.ContainsFunc(collector.events, func(e *model.Event) bool { return e.Type == model.EventStepSkipped }) {
+		t.Error("no step_skipped event")
+	}
+}
+
+// investigationFixture 在 Coordinator 之后插入生成搜索查询的模型调用，并为每个查询录制搜索结果，返回录制文件路径
+func investigationFixture(t *testing.T) string {
+	t.Helper()
+	fixture, err := replay.LoadFixture(filepath.Join(testdata, "research.json"))
+	if err != nil {
+		t.Fatalf("load fixture: %v", err)
+	}
+	queries := replay.ChatExchange{Output: schema.AssistantMessage(
+		"```json\n[\"Go generics adoption survey\", \"go generics adoption survey\", \"Go generics usage statistics\"]\n```", nil)}
+	fixture.Chats = append([]replay.ChatExchange{fixture.Chats[0], queries}, fixture.Chats[1:]...)
+
+	// 搜索结果在 MCP 文本内容中以 JSON 返回，两个查询命中同一篇文章，链接仅有锚点和末尾斜杠的差别
+	result := func(items string) string {
+		data, _ := json.Marshal(map[string]string{"type": "text", "text": items})
+		return string(data)
+	}
+	fixture.ToolCalls = append([]replay.ToolExchange{
+		{
+			Tool:      "web_search",
+			Arguments: `{"query":"Go generics adoption survey"}`,
+			Result: result(`[{"title":"Go Developer Survey 2024","url":"https://go.dev/blog/survey2024-h1","content":"70% of respondents use generics."},` +
+				`{"title":"Generics in Go","url":"https://example.com/generics","content":"An introduction to type parameters."}]`),
+		},
+		{
+			Tool:      "web_search",
+			Arguments: `{"query":"Go generics usage statistics"}`,
+			Result: result(`{"results":[{"title":"Generics usage in open source","url":"https://example.com/stats","snippet":"Type parameters appear in 5% of modules."},` +
+				`{"title":"Go Developer Survey 2024","url":"https://go.dev/blog/survey2024-h1/#generics","content":"70% of respondents use generics."}]}`),
+		},
+	}, fixture.ToolCalls...)
+
+	path := filepath.Join(t.TempDir(), "research.json")
+	if err := fixture.Save(path); err != nil {
+		t.Fatalf("save fixture: %v", err)
+	}
+	return path
+}
+
+// withInvestigationTools 为背景调查配置搜索工具
+func withInvestigationTools(t *testing.T, tools ...string) {
+	t.Helper()
+	old := conf.GetCfg()
+	cfg := *old
+	cfg.Investigation.Tools = tools
+	conf.SetCfg(&cfg)
+	t.Cleanup(func() {
+		conf.SetCfg(old)
+	})
+}
+
+func TestBackgroundInvestigation(t *testing.T) {
+	startReplay(t, investigationFixture(t))
+	state, _ := runGraph(t, "How widely are Go generics adopted?", nil, func(s *model.State) {
+		s.EnableBackgroundInvestigation = true
+	})
+
+	// 两个查询都命中的结果排在最前，其余按在搜索结果中的名次排序
+	want := "Search queries: Go generics adoption survey; Go generics usage statistics\n" +
+		"\n1. [Go Developer Survey 2024](https://go.dev/blog/survey2024-h1)\n   70% of respondents use generics.\n" +
+		"\n2. [Generics usage in open source](https://example.com/stats)\n   Type parameters appear in 5% of modules.\n" +
+		"\n3. [Generics in Go](https://example.com/generics)\n   An introduction to type parameters."
+	if state.BackgroundInvestigationResults != want {
+		t.Errorf("background investigation results:\n%s\nwant:\n%s", state.BackgroundInvestigationResults, want)
+	}
+	if state.FinalReport == "" {
+		t.Error("final report is empty, want the research to continue after the investigation")
+	}
+}
+
+func TestBackgroundInvestigationNoSearchTool(t *testing.T) {
+	withInvestigationTools(t, "arxiv_search")
+	// 没有可用的搜索工具时不生成查询，录制文件中没有对应的模型调用
+	startReplay(t, filepath.Join(testdata, "research.json"))
+	collector := &eventCollector{}
+	ctx := event.WithEmitter(context.Background(), collector)
+
+	state, _ := runGraphCtx(t, ctx, "How widely are Go generics adopted?", nil, func(s *model.State) {
+		s.EnableBackgroundInvestigation = true
+	})
+	if state.BackgroundInvestigationResults != "" {
+		t.Errorf("background investigation results = %q, want empty", state.BackgroundInvestigationResults)
+	}
+	if state.FinalReport == "" {
+		t.Error("final report is empty, want the research to continue without the investigation")
+	}
+	i := slices.IndexFunc(collector.events, func(e *model.Event) bool { return e.Type == model.EventWarning })
+	if i < 0 {
+		t.Fatal("no warning event")
+	}
+	if e := collector.events[i]; e.Agent != consts.BackgroundInvestigator || !strings.Contains(e.Message, "arxiv_search") {
+		t.Errorf("warning event = %+v, want the unavailable tool named", e)
+	}
+}
+
+// TestBudgetStopsAfterStreamingCall Planner 流式调用的用量超出预算后，路由立即结束运行，不再进入下一个智能体
+func TestBudgetStopsAfterStreamingCall(t *testing.T) {
+	fixture, err := replay.LoadFixture(filepath.Join(testdata, "research.json"))
+	if err != nil {
+		t.Fatalf("load fixture: %v", err)
+	}
+	fixture.Chats[1].Output.ResponseMeta = &schema.ResponseMeta{
+		Usage: &schema.TokenUsage{PromptTokens: 900, CompletionTokens: 100, TotalTokens: 1000},
+	}
+	path := filepath.Join(t.TempDir(), "research.json")
+	if err := fixture.Save(path); err != nil {
+		t.Fatalf("save fixture: %v", err)
+	}
+	startReplay(t, path)
+
+	tracker := usage.NewTracker(500, 0)
+	ctx := usage.WithTracker(context.Background(), tracker)
+	state, _ := runGraphCtx(t, ctx, "How widely are Go generics adopted?", nil, func(s *model.State) {
+		s.AutoAcceptedPlan = true
+	})
+	if state.Usage == nil || !state.Usage.BudgetExceeded || state.Usage.Total.TotalTokens != 1000 {
+		t.Fatalf("usage = %+v, want the planner call counted and the budget exceeded", state.Usage)
+	}
+	if state.Goto != compose.END || state.CurrentPlan.Steps[0].Status != model.StepPending {
+		t.Errorf("goto = %q, step = %+v, want the run ended before the step started", state.Goto, state.CurrentPlan.Steps[0])
+	}
+}
+
+// TestBudgetStopsWithinAgent Researcher 的第一次模型调用超出预算后，同一步骤中的下一次调用不再发出，运行以预算错误结束
+func TestBudgetStopsWithinAgent(t *testing.T) {
+	fixture, err := replay.LoadFixture(filepath.Join(testdata, "research.json"))
+	if err != nil {
+		t.Fatalf("load fixture: %v", err)
+	}
+	// 录制文件依次为 Coordinator、Planner、Researcher（两次）和 Reporter 的调用
+	for i, tokens := range []int{100, 100, 500, 500, 500} {
+		fixture.Chats[i].Output.ResponseMeta = &schema.ResponseMeta{
+			Usage: &schema.TokenUsage{PromptTokens: tokens, TotalTokens: tokens},
+		}
+	}
+	path := filepath.Join(t.TempDir(), "research.json")
+	if err := fixture.Save(path); err != nil {
+		t.Fatalf("save fixture: %v", err)
+	}
+	startReplay(t, path)
+	withStepPolicy(t, 2, consts.StepFailureContinue)
+
+	tracker := usage.NewTracker(600, 0)
+	ctx := usage.WithTracker(context.Background(), tracker)
+	graph, err := BuildAgentGraph[string, string](ctx, []*schema.Message{schema.UserMessage("How widely are Go generics adopted?")})
+	if err != nil {
+		t.Fatalf("BuildAgentGraph: %v", err)
+	}
+	_, err = graph.Invoke(ctx, consts.Coordinator,
+		compose.WithCheckPointID(uuid.New().String()),
+		compose.WithCallbacks(&callback.UsageCallback{Tracker: tracker}),
+	)
+	// 超出预算不重试，也不按失败策略继续执行
+	if !errors.Is(err, usage.ErrBudgetExceeded) {
+		t.Fatalf("Invoke err = %v, want ErrBudgetExceeded", err)
+	}
+	sum := tracker.Summary()
+	if sum.Total.Requests != 3 || sum.ByAgent[consts.Researcher].Requests != 1 || !sum.BudgetExceeded {
+		t.Errorf("usage = %+v, researcher = %+v, want the researcher stopped after one call", sum.Total, sum.ByAgent[consts.Researcher])
+	}
+}
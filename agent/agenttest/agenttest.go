@@ -0,0 +1,29 @@
+// Package agenttest 运行图测试共用的录制文件和测试配置
+package agenttest
+
+import (
+	"path/filepath"
+	"runtime"
+
+	"github.com/hildam/deer-flow-go/entity/conf"
+)
+
+// Testdata 录制文件目录，以源文件所在目录定位，不依赖测试的工作目录
+var Testdata = testdata()
+
+func testdata() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "testdata")
+}
+
+// Config 回放录制文件时使用的配置，限制计划迭代和智能体步数，调用方可在返回的副本上修改
+func Config() *conf.AppConfig {
+	return &conf.AppConfig{
+		Setting: conf.SettingConfig{
+			MaxPlanIterations: 1,
+			TotalMaxRound:     3,
+			AgentMaxStep:      10,
+			MaxLimitToken:     50000,
+		},
+	}
+}
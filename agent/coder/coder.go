@@ -8,7 +8,7 @@ import (
 
 	"github.com/HildaM/logs/slog"
 
-	"github.com/cloudwego/eino-ext/components/model/openai"
+	ecmodel "github.com/cloudwego/eino/components/model"
 	"github.com/cloudwego/eino/components/prompt"
 	"github.com/cloudwego/eino/components/tool"
 	"github.com/cloudwego/eino/compose"
@@ -25,7 +25,7 @@ import (
 
 // coderImpl 代码生成者
 type coderImpl[I, O any] struct {
-	llm *openai.ChatModel // llm模型服务
+	llm ecmodel.ToolCallingChatModel // llm模型服务
 }
 
 // NewCoder 创建实例
@@ -40,11 +40,10 @@ func (c *coderImpl[I, O]) NewGraphNode(ctx context.Context) (key string, node co
 	// 创建工作流图
 	graph := compose.NewGraph[I, O]()
 
-	// 获取 mcp 工具
+	// 获取 mcp 工具，获取失败时不使用工具继续运行
 	allTools, err := mcp.GetMCPTools(ctx)
 	if err != nil {
-		slog.Fatal("NewGraphNode failed, get mcp tools failed", "err", err)
-		return "", nil, nil
+		slog.Error("NewGraphNode failed, get mcp tools err = %v", err)
 	}
 
 	// 过滤出python相关的工具，为代码生成任务提供专业工具支持
@@ -56,9 +55,10 @@ func (c *coderImpl[I, O]) NewGraphNode(ctx context.Context) (key string, node co
 			continue
 		}
 
-		// 检查工具名称是否包含python相关关键词
+		// 检查工具名称是否包含python相关关键词，产物工具用于读取之前步骤生成的文件
 		if strings.Contains(strings.ToLower(info.Name), "python") ||
-			strings.Contains(strings.ToLower(info.Desc), "python") {
+			strings.Contains(strings.ToLower(info.Desc), "python") ||
+			strings.Contains(info.Name, "artifact") {
 			codeTools = append(codeTools, t)
 		}
 	}
@@ -78,7 +78,7 @@ func (c *coderImpl[I, O]) NewGraphNode(ctx context.Context) (key string, node co
 	}
 
 	// 将 agent 包装为 lambda 节点
-	agentLambda, err := compose.AnyLambda(reactAgent.Generate, reactAgent.Stream, nil, nil)
+	agentLambda, err := comm.AgentLambda(consts.Coder, reactAgent)
 	if err != nil {
 		slog.Fatal("NewGraphNode failed, create agent lambda failed", "err", err)
 		return "", nil, nil
@@ -99,12 +99,12 @@ func (c *coderImpl[I, O]) NewGraphNode(ctx context.Context) (key string, node co
 }
 
 // loadMsg 消息加载函数
-func loadMsg(ctx context.Context, name string, opts ...any) (output []*schema.Message, err error) {
+func loadMsg(ctx context.Context, _ string, opts ...any) (output []*schema.Message, err error) {
 	err = compose.ProcessState[*model.State](ctx, func(ctx context.Context, state *model.State) error {
 		// 获取 Prompt 模板
-		sysPrompt, err := template.GetPromptTemplate(ctx, name)
+		sysPrompt, err := template.GetPromptTemplate(ctx, consts.Coder, state)
 		if err != nil {
-			slog.Error("loadMsg failed, GetPromptTemplate err = %+v, prompt name = %+v", err, name)
+			slog.Error("loadMsg failed, GetPromptTemplate err = %+v, prompt name = %+v", err, consts.Coder)
 			return err
 		}
 
@@ -114,15 +114,9 @@ func loadMsg(ctx context.Context, name string, opts ...any) (output []*schema.Me
 			schema.MessagesPlaceholder("user_input", true),
 		)
 
-		// 从当前计划中找到第一个未执行的代码生成步骤
-		var curStep *model.Step
-		for i := range state.CurrentPlan.Steps {
-			if state.CurrentPlan.Steps[i].ExecutionRes == nil {
-				curStep = &state.CurrentPlan.Steps[i]
-				slog.Error("loadMsg debug, found coder step, step = %+v", curStep)
-				break
-			}
-		}
+		// 从当前计划中找到第一个未执行的代码生成步骤，工作流跳过 Planner 时以用户问题作为处理步骤
+		curStep := comm.StartStep(ctx, state, consts.Coder, model.Processing)
+		slog.Debug("loadMsg debug, found coder step, step = %+v", curStep)
 
 		// 确保找到了待执行的代码
 		if curStep == nil {
@@ -166,15 +160,8 @@ func routerCoder(ctx context.Context, input *schema.Message, opts ...any) (outpu
 			output = state.Goto
 		}()
 
-		// 将代码生成结果保存到第一个未执行步骤的ExecutionRes字段中
-		for i, step := range state.CurrentPlan.Steps {
-			if step.ExecutionRes == nil {
-				// 克隆代码生成结果内容并保存
-				str := strings.Clone(last.Content)
-				state.CurrentPlan.Steps[i].ExecutionRes = &str
-				break
-			}
-		}
+		// 将代码生成结果保存到执行中的步骤并置为执行成功，智能体重试后仍然失败时置为失败并记录错误
+		comm.CompleteStep(ctx, state, consts.Coder, last)
 		// 记录代码生成任务完成的事件，包含更新后的计划状态
 		slog.Debug("routerCoder debug, plan = %+v", state.CurrentPlan)
 
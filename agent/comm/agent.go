@@ -0,0 +1,73 @@
+package comm
+
+import (
+	"context"
+
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/flow/agent"
+	"github.com/cloudwego/eino/flow/agent/react"
+	"github.com/cloudwego/eino/schema"
+	"github.com/hildam/deer-flow-go/entity/model"
+	"github.com/hildam/deer-flow-go/repo/artifact"
+)
+
+// AgentLambda 将 react 智能体包装为 lambda 节点
+// 执行前将当前计划步骤作为产物生成者写入上下文，工具保存的产物据此记录来源步骤
+// 执行出错时按步骤重试策略重试，重试后仍失败时由 stepFailed 决定结束运行还是将步骤标记为失败
+// 每次执行的工具调用和执行次数记录在当前步骤中
+func AgentLambda(name string, ra *react.Agent) (*compose.Lambda, error) {
+	generate := func(ctx context.Context, input []*schema.Message, opts ...agent.AgentOption) (*schema.Message, error) {
+		ctx = withStepProducer(ctx, name)
+		recorder := &toolCallRecorder{}
+		opts = append(opts, recorder.option())
+		msg, err := withRetry(ctx, name, func() (*schema.Message, error) {
+			msg, err := ra.Generate(ctx, input, opts...)
+			recordAttempt(ctx, recorder.take())
+			return msg, err
+		})
+		if err != nil {
+			return stepFailed(ctx, err)
+		}
+		return msg, nil
+	}
+	stream := func(ctx context.Context, input []*schema.Message, opts ...agent.AgentOption) (*schema.StreamReader[*schema.Message], error) {
+		ctx = withStepProducer(ctx, name)
+		recorder := &toolCallRecorder{}
+		opts = append(opts, recorder.option())
+		// 工具调用在最终回答开始输出之前完成，返回输出流时即可记录
+		// 最终回答在重试内读完，输出中途断开等错误同样重试或将步骤标记为失败；分片已由模型回调实时推送
+		msg, err := withRetry(ctx, name, func() (*schema.Message, error) {
+			sr, err := ra.Stream(ctx, input, opts...)
+			recordAttempt(ctx, recorder.take())
+			if err != nil {
+				return nil, err
+			}
+			return schema.ConcatMessageStream(sr)
+		})
+		if err != nil {
+			if msg, err = stepFailed(ctx, err); err != nil {
+				return nil, err
+			}
+		}
+		return schema.StreamReaderFromArray([]*schema.Message{msg}), nil
+	}
+	return compose.AnyLambda(generate, stream, nil, nil)
+}
+
+// withStepProducer 将第一个未执行的计划步骤写入上下文
+func withStepProducer(ctx context.Context, name string) context.Context {
+	p := artifact.Producer{Agent: name}
+	_ = compose.ProcessState[*model.State](ctx, func(_ context.Context, state *model.State) error {
+		if state.CurrentPlan == nil {
+			return nil
+		}
+		for i, step := range state.CurrentPlan.Steps {
+			if !step.Done() {
+				p.Step, p.StepTitle = i+1, step.Title
+				break
+			}
+		}
+		return nil
+	})
+	return artifact.WithProducer(ctx, p)
+}
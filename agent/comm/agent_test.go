@@ -0,0 +1,75 @@
+package comm
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/flow/agent/react"
+	"github.com/cloudwego/eino/schema"
+
+	"github.com/hildam/deer-flow-go/entity/conf"
+	"github.com/hildam/deer-flow-go/entity/consts"
+	"github.com/hildam/deer-flow-go/repo/replay"
+)
+
+// TestAgentLambdaStreamRetry 最终回答输出到一半断开时重试，不依赖工具调用检测器读完整个输出
+func TestAgentLambdaStreamRetry(t *testing.T) {
+	conf.SetCfg(&conf.AppConfig{Setting: conf.SettingConfig{
+		AgentMaxStep:   10,
+		MaxLimitToken:  50000,
+		StepMaxRetries: 1,
+		StepOnFailure:  consts.StepFailureContinue,
+	}})
+	fixture := &replay.Fixture{Chats: []replay.ChatExchange{
+		{Output: schema.AssistantMessage("Most Go developers", nil), Error: "connection reset by peer"},
+		{Output: schema.AssistantMessage("Most Go developers surveyed have used generics at least once.", nil)},
+	}}
+	path := filepath.Join(t.TempDir(), "fixture.json")
+	if err := fixture.Save(path); err != nil {
+		t.Fatalf("save fixture: %v", err)
+	}
+	if err := replay.Start(replay.ModeReplay, path); err != nil {
+		t.Fatalf("start replay: %v", err)
+	}
+	defer replay.Close()
+
+	ctx := context.Background()
+	// 检测器只读第一个分片即返回，输出流在交还给调用方之后才出错
+	ra, err := react.NewAgent(ctx, &react.AgentConfig{
+		MaxStep:          10,
+		ToolCallingModel: replay.NewChatModel(),
+		StreamToolCallChecker: func(ctx context.Context, sr *schema.StreamReader[*schema.Message]) (bool, error) {
+			defer sr.Close()
+			msg, err := sr.Recv()
+			if err != nil {
+				return false, err
+			}
+			return len(msg.ToolCalls) > 0, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewAgent: %v", err)
+	}
+	lambda, err := AgentLambda(consts.Researcher, ra)
+	if err != nil {
+		t.Fatalf("AgentLambda: %v", err)
+	}
+	runnable, err := compose.NewChain[[]*schema.Message, *schema.Message]().AppendLambda(lambda).Compile(ctx)
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+
+	sr, err := runnable.Stream(ctx, []*schema.Message{schema.UserMessage("How widely are Go generics adopted?")})
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	msg, err := schema.ConcatMessageStream(sr)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	if msg.Content != "Most Go developers surveyed have used generics at least once." {
+		t.Errorf("content = %q, want the retried answer", msg.Content)
+	}
+}
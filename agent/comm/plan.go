@@ -0,0 +1,156 @@
+package comm
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/hildam/deer-flow-go/entity/consts"
+	"github.com/hildam/deer-flow-go/entity/model"
+	"github.com/hildam/deer-flow-go/repo/event"
+)
+
+// summaryLen 步骤事件中执行结果摘要的最大长度
+const summaryLen = 200
+
+// EnsurePlan 返回当前计划，工作流跳过 Planner 时以用户问题生成只有一个 stepType 步骤的计划，并发出 plan_created 事件
+func EnsurePlan(ctx context.Context, state *model.State, stepType model.StepType) *model.Plan {
+	if state.CurrentPlan != nil {
+		return state.CurrentPlan
+	}
+	query := UserQuery(state.Messages)
+	state.CurrentPlan = &model.Plan{
+		Locale:           state.Locale,
+		HasEnoughContext: false,
+		Thought:          query,
+		Title:            query,
+		Steps: []model.Step{{
+			NeedWebSearch: stepType == model.Research,
+			Title:         query,
+			Description:   query,
+			StepType:      stepType,
+			Status:        model.StepPending,
+		}},
+	}
+	EmitPlan(ctx, "", state.CurrentPlan)
+	return state.CurrentPlan
+}
+
+// UserQuery 最近一条用户消息的内容，即本次运行要研究的问题
+// 多轮对话时之前的问题和报告作为上下文，最后一条才是本次提出的问题
+func UserQuery(messages []*schema.Message) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if msg := messages[i]; msg != nil && msg.Role == schema.User {
+			return msg.Content
+		}
+	}
+	return ""
+}
+
+// EmitPlan 发出 plan_created 事件，事件中的计划为副本，不受之后执行结果的影响
+func EmitPlan(ctx context.Context, agent string, plan *model.Plan) {
+	cp := *plan
+	cp.Steps = slices.Clone(plan.Steps)
+	event.Emit(ctx, &model.Event{Type: model.EventPlanCreated, Agent: agent, Plan: &cp})
+}
+
+// ResetSteps 将新计划的步骤置为等待执行，模型生成的计划中的执行记录一律忽略
+func ResetSteps(plan *model.Plan) {
+	for i := range plan.Steps {
+		plan.Steps[i] = model.Step{
+			NeedWebSearch: plan.Steps[i].NeedWebSearch,
+			Title:         plan.Steps[i].Title,
+			Description:   plan.Steps[i].Description,
+			StepType:      plan.Steps[i].StepType,
+			Status:        model.StepPending,
+		}
+	}
+}
+
+// StartStep 将第一个未执行的步骤置为执行中并发出 step_started 事件，没有计划时按 EnsurePlan 生成，全部执行完成时返回 nil
+func StartStep(ctx context.Context, state *model.State, agent string, stepType model.StepType) *model.Step {
+	plan := EnsurePlan(ctx, state, stepType)
+	i := pendingIndex(plan)
+	if i < 0 {
+		return nil
+	}
+	step := &plan.Steps[i]
+	now := time.Now()
+	step.Status = model.StepRunning
+	step.Agent = agent
+	step.StartedAt = &now
+	event.Emit(ctx, &model.Event{Type: model.EventStepStarted, Agent: agent, Step: stepEvent(plan, i)})
+	return step
+}
+
+// CompleteStep 将智能体的输出保存到执行中的步骤，步骤置为执行成功并发出 step_completed 事件
+// 智能体重试后仍然失败时步骤置为失败，记录错误并发出 step_failed 事件，ResearchTeam 随后跳过该步骤
+func CompleteStep(ctx context.Context, state *model.State, agent string, msg *schema.Message) {
+	if state.CurrentPlan == nil {
+		return
+	}
+	i := pendingIndex(state.CurrentPlan)
+	if i < 0 {
+		return
+	}
+	step := &state.CurrentPlan.Steps[i]
+	now := time.Now()
+	step.FinishedAt = &now
+	if errMsg := stepError(msg); errMsg != "" {
+		step.Status = model.StepFailed
+		step.Error = errMsg
+		e := stepEvent(state.CurrentPlan, i)
+		e.Error = errMsg
+		event.Emit(ctx, &model.Event{Type: model.EventStepFailed, Agent: agent, Step: e})
+		return
+	}
+	res := strings.Clone(msg.Content)
+	step.Status = model.StepSucceeded
+	step.ExecutionRes = &res
+
+	e := stepEvent(state.CurrentPlan, i)
+	e.Summary = summarize(res)
+	event.Emit(ctx, &model.Event{Type: model.EventStepCompleted, Agent: agent, Step: e})
+}
+
+// SkipStep 将没有智能体处理的步骤置为跳过，记录原因并发出 step_skipped 事件
+func SkipStep(ctx context.Context, plan *model.Plan, i int, reason string) {
+	step := &plan.Steps[i]
+	now := time.Now()
+	step.Status = model.StepSkipped
+	step.Error = reason
+	step.FinishedAt = &now
+	e := stepEvent(plan, i)
+	e.Error = reason
+	event.Emit(ctx, &model.Event{Type: model.EventStepSkipped, Agent: consts.ResearchTeam, Step: e})
+}
+
+// pendingIndex 第一个未执行步骤的序号，全部执行结束时返回 -1
+func pendingIndex(plan *model.Plan) int {
+	return slices.IndexFunc(plan.Steps, func(s model.Step) bool { return !s.Done() })
+}
+
+// stepEvent 步骤事件中的步骤信息
+func stepEvent(plan *model.Plan, i int) *model.StepEvent {
+	step := plan.Steps[i]
+	return &model.StepEvent{
+		Index:     i,
+		Total:     len(plan.Steps),
+		Title:     step.Title,
+		StepType:  step.StepType,
+		Status:    step.Status,
+		Attempts:  step.Attempts,
+		ToolCalls: len(step.ToolCalls),
+	}
+}
+
+// summarize 截取执行结果的开头作为摘要，多行合并为一行
+func summarize(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > summaryLen {
+		return string(r[:summaryLen]) + "…"
+	}
+	return s
+}
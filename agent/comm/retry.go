@@ -0,0 +1,86 @@
+package comm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/HildaM/logs/slog"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+
+	"github.com/hildam/deer-flow-go/entity/conf"
+	"github.com/hildam/deer-flow-go/entity/consts"
+	"github.com/hildam/deer-flow-go/entity/model"
+	"github.com/hildam/deer-flow-go/repo/event"
+	"github.com/hildam/deer-flow-go/repo/usage"
+)
+
+// stepErrorKey 步骤失败时，智能体输出消息的 Extra 中记录错误信息的 key，由 CompleteStep 将步骤标记为失败
+const stepErrorKey = "deer_step_error"
+
+// withRetry 执行智能体，出错时按配置的重试次数和间隔重试，重试前发出 step_retry 事件
+// 运行取消和中断不重试，直接返回
+func withRetry[T any](ctx context.Context, agent string, run func() (T, error)) (out T, err error) {
+	setting := conf.GetCfg().Setting
+	for attempt := 0; ; attempt++ {
+		if out, err = run(); err == nil || !retryable(ctx, err) || attempt >= setting.StepMaxRetries {
+			return out, err
+		}
+		slog.Error("withRetry failed, agent = %s, attempt = %d/%d, err = %v", agent, attempt+1, setting.StepMaxRetries+1, err)
+		emitRetry(ctx, agent, err)
+		select {
+		case <-ctx.Done():
+			return out, err
+		case <-time.After(time.Duration(setting.StepRetryBackoff*(attempt+1)) * time.Millisecond):
+		}
+	}
+}
+
+// emitRetry 执行中的步骤出错后即将重试时发出 step_retry 事件，客户端据此丢弃本次执行已推送的输出
+func emitRetry(ctx context.Context, agent string, err error) {
+	var e *model.StepEvent
+	_ = compose.ProcessState[*model.State](ctx, func(_ context.Context, state *model.State) error {
+		if state.CurrentPlan == nil {
+			return nil
+		}
+		if i := pendingIndex(state.CurrentPlan); i >= 0 {
+			e = stepEvent(state.CurrentPlan, i)
+			e.Error = err.Error()
+		}
+		return nil
+	})
+	if e != nil {
+		event.Emit(ctx, &model.Event{Type: model.EventStepRetry, Agent: agent, Step: e})
+	}
+}
+
+// retryable 错误是否可以重试或将步骤标记为失败，运行取消、中断和超出预算需要结束整个运行
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, usage.ErrBudgetExceeded) {
+		return false
+	}
+	_, interrupted := compose.ExtractInterruptInfo(err)
+	return !interrupted
+}
+
+// stepFailed 处理重试后仍然失败的步骤
+// 失败策略为 abort 或错误不可恢复时返回错误结束运行，否则返回带有错误标记的消息，由 router 将步骤标记为失败
+func stepFailed(ctx context.Context, err error) (*schema.Message, error) {
+	if !retryable(ctx, err) || conf.GetCfg().Setting.StepOnFailure == consts.StepFailureAbort {
+		return nil, err
+	}
+	msg := schema.AssistantMessage(fmt.Sprintf("step failed: %v", err), nil)
+	msg.Extra = map[string]any{stepErrorKey: err.Error()}
+	return msg, nil
+}
+
+// stepError 消息中记录的步骤错误信息，步骤执行成功时为空
+func stepError(msg *schema.Message) string {
+	if msg == nil {
+		return ""
+	}
+	errMsg, _ := msg.Extra[stepErrorKey].(string)
+	return errMsg
+}
@@ -0,0 +1,121 @@
+package comm
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/flow/agent"
+	"github.com/cloudwego/eino/schema"
+
+	"github.com/hildam/deer-flow-go/entity/model"
+)
+
+// toolCallRecorder 记录 react 智能体一次执行中的工具调用，写入当前执行的计划步骤
+// 工具在 react 智能体内部的图中执行，无法直接读取运行状态，由 AgentLambda 在执行结束后写入
+type toolCallRecorder struct {
+	callbacks.HandlerBuilder
+
+	mu    sync.Mutex
+	calls []model.StepToolCall
+}
+
+// toolCallKey 上下文中存放工具调用序号及开始时间的 key
+type toolCallKey struct{}
+
+type toolCallEntry struct {
+	index int
+	start time.Time
+}
+
+// option 以回调的形式注册到 react 智能体
+func (r *toolCallRecorder) option() agent.AgentOption {
+	return agent.WithComposeOptions(compose.WithCallbacks(r))
+}
+
+// OnStart 工具开始调用时记录名称和参数
+func (r *toolCallRecorder) OnStart(ctx context.Context, info *callbacks.RunInfo, input callbacks.CallbackInput) context.Context {
+	if info == nil || info.Component != components.ComponentOfTool {
+		return ctx
+	}
+	call := model.StepToolCall{Name: info.Name}
+	if in := tool.ConvCallbackInput(input); in != nil {
+		call.Arguments = in.ArgumentsInJSON
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, call)
+	return context.WithValue(ctx, toolCallKey{}, &toolCallEntry{index: len(r.calls) - 1, start: time.Now()})
+}
+
+// OnEnd 工具调用结束时记录耗时
+func (r *toolCallRecorder) OnEnd(ctx context.Context, info *callbacks.RunInfo, output callbacks.CallbackOutput) context.Context {
+	r.end(ctx, nil)
+	return ctx
+}
+
+// OnError 工具调用失败时记录错误
+func (r *toolCallRecorder) OnError(ctx context.Context, info *callbacks.RunInfo, err error) context.Context {
+	r.end(ctx, err)
+	return ctx
+}
+
+// OnStartWithStreamInput 工具不以流式输入调用，忽略
+func (r *toolCallRecorder) OnStartWithStreamInput(ctx context.Context, info *callbacks.RunInfo,
+	input *schema.StreamReader[callbacks.CallbackInput]) context.Context {
+	input.Close()
+	return ctx
+}
+
+// OnEndWithStreamOutput 流式工具输出开始返回即视为调用结束
+func (r *toolCallRecorder) OnEndWithStreamOutput(ctx context.Context, info *callbacks.RunInfo,
+	output *schema.StreamReader[callbacks.CallbackOutput]) context.Context {
+	output.Close()
+	r.end(ctx, nil)
+	return ctx
+}
+
+// end 记录工具调用的耗时和错误，多个工具并发调用时按上下文中的序号区分
+func (r *toolCallRecorder) end(ctx context.Context, err error) {
+	entry, ok := ctx.Value(toolCallKey{}).(*toolCallEntry)
+	if !ok {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	call := &r.calls[entry.index]
+	call.DurationMs = time.Since(entry.start).Milliseconds()
+	if err != nil {
+		call.Error = err.Error()
+	}
+}
+
+// take 取出已记录的工具调用并清空
+func (r *toolCallRecorder) take() []model.StepToolCall {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	calls := slices.Clone(r.calls)
+	r.calls = nil
+	return calls
+}
+
+// recordAttempt 将一次执行的工具调用写入当前执行的步骤并累加执行次数
+func recordAttempt(ctx context.Context, calls []model.StepToolCall) {
+	_ = compose.ProcessState[*model.State](ctx, func(_ context.Context, state *model.State) error {
+		if state.CurrentPlan == nil {
+			return nil
+		}
+		if i := pendingIndex(state.CurrentPlan); i >= 0 {
+			step := &state.CurrentPlan.Steps[i]
+			step.Attempts++
+			step.ToolCalls = append(step.ToolCalls, calls...)
+		}
+		return nil
+	})
+}
@@ -7,7 +7,7 @@ import (
 
 	"github.com/HildaM/logs/slog"
 
-	"github.com/cloudwego/eino-ext/components/model/openai"
+	ecmodel "github.com/cloudwego/eino/components/model"
 	"github.com/cloudwego/eino/components/prompt"
 	"github.com/cloudwego/eino/compose"
 	"github.com/cloudwego/eino/schema"
@@ -19,7 +19,7 @@ import (
 
 // coordinatorImpl 任务协调者
 type coordinatorImpl[I, O any] struct {
-	llm *openai.ChatModel // llm模型服务
+	llm ecmodel.ToolCallingChatModel // llm模型服务
 }
 
 // NewCoordinator 创建实例
@@ -74,10 +74,10 @@ func (c *coordinatorImpl[I, O]) NewGraphNode(ctx context.Context) (key string, n
 }
 
 // loadMsg 加载提示词模板和准备输入数据
-func loadMsg(ctx context.Context, name string, opts ...any) (output []*schema.Message, err error) {
+func loadMsg(ctx context.Context, _ string, opts ...any) (output []*schema.Message, err error) {
 	err = compose.ProcessState[*model.State](ctx, func(ctx context.Context, state *model.State) error {
 		// 从基础设施层获取提示词模板
-		sysPrompt, err := template.GetPromptTemplate(ctx, name)
+		sysPrompt, err := template.GetPromptTemplate(ctx, consts.Coordinator, state)
 		if err != nil {
 			slog.Error("loadMsg failed, get prompt template fail", "err", err)
 			return err
@@ -129,8 +129,10 @@ func router(ctx context.Context, input *schema.Message, opts ...any) (output str
 				return err
 			}
 
-			// 从工具参数中提取并保存用户的语言设置
-			state.Locale = argMap["locale"]
+			// 从工具参数中提取并保存用户的语言设置，运行时已指定语言则以指定的为准
+			if state.Locale == "" {
+				state.Locale = argMap["locale"]
+			}
 
 			// 根据配置决定下一步：是否启用背景调查
 			if state.EnableBackgroundInvestigation {
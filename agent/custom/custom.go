@@ -0,0 +1,197 @@
+package custom
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/HildaM/logs/slog"
+
+	ecmodel "github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/prompt"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/flow/agent/react"
+	"github.com/cloudwego/eino/schema"
+	"github.com/hildam/deer-flow-go/agent/comm"
+	"github.com/hildam/deer-flow-go/entity/conf"
+	"github.com/hildam/deer-flow-go/entity/consts"
+	"github.com/hildam/deer-flow-go/entity/model"
+	"github.com/hildam/deer-flow-go/repo/llm"
+	"github.com/hildam/deer-flow-go/repo/mcp"
+	"github.com/hildam/deer-flow-go/repo/template"
+)
+
+// namePattern 合法的智能体名称和步骤类型，与内置名称的风格一致
+var namePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// customImpl 配置中声明的自定义智能体，执行计划中对应类型的步骤
+type customImpl[I, O any] struct {
+	cfg conf.AgentConfig
+	llm ecmodel.ToolCallingChatModel // llm模型服务
+}
+
+// NewCustom 创建实例
+func NewCustom[I, O any](ctx context.Context, cfg conf.AgentConfig) *customImpl[I, O] {
+	if cfg.Prompt == "" {
+		cfg.Prompt = cfg.Name
+	}
+	return &customImpl[I, O]{
+		cfg: cfg,
+		llm: llm.NewAgentModel(ctx, cfg.Model),
+	}
+}
+
+// Validate 校验自定义智能体配置，名称和步骤类型不能与内置或其他自定义智能体重复
+func Validate(agents []conf.AgentConfig) error {
+	names := map[string]bool{}
+	for _, name := range consts.GetAgentNameList() {
+		names[name] = true
+	}
+	stepTypes := map[string]bool{string(model.Research): true, string(model.Processing): true}
+	for _, a := range agents {
+		if !namePattern.MatchString(a.Name) {
+			return fmt.Errorf("invalid agent name %q", a.Name)
+		}
+		if !namePattern.MatchString(a.StepType) {
+			return fmt.Errorf("agent %s: invalid step type %q", a.Name, a.StepType)
+		}
+		if names[a.Name] {
+			return fmt.Errorf("agent %s: duplicate agent name", a.Name)
+		}
+		if stepTypes[a.StepType] {
+			return fmt.Errorf("agent %s: duplicate step type %s", a.Name, a.StepType)
+		}
+		prompt := a.Prompt
+		if prompt == "" {
+			prompt = a.Name
+		}
+		if r, err := template.Default(); err != nil || !r.HasVariant(prompt, "") {
+			return fmt.Errorf("agent %s: prompt %s not found", a.Name, prompt)
+		}
+		for _, pattern := range a.Tools {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("agent %s: invalid tool pattern %q", a.Name, pattern)
+			}
+		}
+		names[a.Name], stepTypes[a.StepType] = true, true
+	}
+	return nil
+}
+
+// NewGraphNode 创建任务图
+func (c *customImpl[I, O]) NewGraphNode(ctx context.Context) (key string, node compose.AnyGraph, nameOption compose.GraphAddNodeOpt) {
+	// 创建工作流图
+	graph := compose.NewGraph[I, O]()
+
+	// 获取 mcp 工具，获取失败时不使用工具继续运行
+	allTools, err := mcp.GetMCPTools(ctx)
+	if err != nil {
+		slog.Error("NewGraphNode failed, get mcp tools err = %v, agent = %s", err, c.cfg.Name)
+	}
+
+	// 只保留白名单中的工具
+	tools := []tool.BaseTool{}
+	for _, t := range allTools {
+		info, err := t.Info(ctx)
+		if err != nil {
+			slog.Error("NewGraphNode failed, get tool info err = %v, agent = %s", err, c.cfg.Name)
+			continue
+		}
+		if slices.ContainsFunc(c.cfg.Tools, func(pattern string) bool {
+			ok, _ := path.Match(pattern, info.Name)
+			return ok
+		}) {
+			tools = append(tools, t)
+		}
+	}
+	slog.Debug("NewGraphNode debug, agent = %s, tools = %+v", c.cfg.Name, tools)
+
+	// 创建react智能体
+	reactAgent, err := react.NewAgent(ctx, &react.AgentConfig{
+		MaxStep:               conf.GetCfg().Setting.AgentMaxStep,
+		ToolCallingModel:      c.llm,
+		ToolsConfig:           compose.ToolsNodeConfig{Tools: tools},
+		MessageModifier:       comm.ModifyInputFunc, // 消息长度限制处理器
+		StreamToolCallChecker: comm.ToolCallChecker, // 工具调用检测器
+	})
+	if err != nil {
+		slog.Fatal("NewGraphNode failed, create react agent err = %v, agent = %s", err, c.cfg.Name)
+	}
+
+	// 将 agent 包装为 lambda 节点
+	agentLambda, err := comm.AgentLambda(c.cfg.Name, reactAgent)
+	if err != nil {
+		slog.Fatal("NewGraphNode failed, create agent lambda err = %v, agent = %s", err, c.cfg.Name)
+	}
+
+	// 添加工作流节点
+	graph.AddLambdaNode("load", compose.InvokableLambdaWithOption(c.loadMsg))
+	graph.AddLambdaNode("agent", agentLambda)
+	graph.AddLambdaNode("router", compose.InvokableLambdaWithOption(c.router))
+
+	// 构造工作流
+	graph.AddEdge(compose.START, "load")
+	graph.AddEdge("load", "agent")
+	graph.AddEdge("agent", "router")
+	graph.AddEdge("router", compose.END)
+
+	return c.cfg.Name, graph, compose.WithNodeName(c.cfg.Name)
+}
+
+// loadMsg 加载配置的提示词模板和当前步骤的任务信息
+func (c *customImpl[I, O]) loadMsg(ctx context.Context, _ string, opts ...any) (output []*schema.Message, err error) {
+	err = compose.ProcessState[*model.State](ctx, func(ctx context.Context, state *model.State) error {
+		sysPrompt, err := template.GetPromptTemplate(ctx, c.cfg.Prompt, state)
+		if err != nil {
+			slog.Error("loadMsg failed, GetPromptTemplate err = %v, agent = %s, prompt name = %s", err, c.cfg.Name, c.cfg.Prompt)
+			return err
+		}
+
+		// 创建Jinja2模板，包含系统提示词和用户输入占位符
+		promptTemp := prompt.FromMessages(schema.Jinja2,
+			schema.SystemMessage(sysPrompt),
+			schema.MessagesPlaceholder("user_input", true),
+		)
+
+		// 从当前计划中找到第一个未执行的步骤
+		curStep := comm.StartStep(ctx, state, c.cfg.Name, model.StepType(c.cfg.StepType))
+		if curStep == nil {
+			return fmt.Errorf("agent %s: no pending step found", c.cfg.Name)
+		}
+
+		msg := []*schema.Message{
+			schema.UserMessage(fmt.Sprintf(
+				"#Task\n\n##title\n\n %v \n\n##description\n\n %v \n\n##locale\n\n %v",
+				curStep.Title, curStep.Description, state.Locale),
+			),
+		}
+		variables := map[string]any{
+			"locale":              state.Locale,
+			"max_step_num":        state.MaxStepNum,
+			"max_plan_iterations": state.MaxPlanIterations,
+			"CURRENT_TIME":        time.Now().Format("2006-01-02 15:04:05"),
+			"user_input":          msg,
+		}
+		output, err = promptTemp.Format(ctx, variables)
+		return err
+	})
+	return output, err
+}
+
+// router 保存步骤执行结果并返回调度中心
+func (c *customImpl[I, O]) router(ctx context.Context, input *schema.Message, opts ...any) (output string, err error) {
+	err = compose.ProcessState[*model.State](ctx, func(_ context.Context, state *model.State) error {
+		defer func() {
+			output = state.Goto
+		}()
+		comm.CompleteStep(ctx, state, c.cfg.Name, input)
+		// 返回调度中心，由ResearchTeam决定下一步执行哪个智能体
+		state.Goto = consts.ResearchTeam
+		return nil
+	})
+	return output, err
+}
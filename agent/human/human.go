@@ -3,7 +3,7 @@ package human
 import (
 	"context"
 
-	"github.com/cloudwego/eino-ext/components/model/openai"
+	ecmodel "github.com/cloudwego/eino/components/model"
 	"github.com/cloudwego/eino/compose"
 	"github.com/hildam/deer-flow-go/entity/consts"
 	"github.com/hildam/deer-flow-go/entity/model"
@@ -12,7 +12,7 @@ import (
 
 // humanImpl 人工代理
 type humanImpl[I, O any] struct {
-	llm *openai.ChatModel // llm模型服务
+	llm ecmodel.ToolCallingChatModel // llm模型服务
 }
 
 // NewHuman 创建实例
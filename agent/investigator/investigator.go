@@ -3,22 +3,34 @@ package investigator
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/HildaM/logs/slog"
 
-	"github.com/cloudwego/eino-ext/components/model/openai"
+	ecmodel "github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/prompt"
 	"github.com/cloudwego/eino/components/tool"
 	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+	"github.com/hildam/deer-flow-go/agent/comm"
+	"github.com/hildam/deer-flow-go/entity/conf"
 	"github.com/hildam/deer-flow-go/entity/consts"
 	"github.com/hildam/deer-flow-go/entity/model"
+	"github.com/hildam/deer-flow-go/repo/event"
 	"github.com/hildam/deer-flow-go/repo/llm"
 	"github.com/hildam/deer-flow-go/repo/mcp"
+	"github.com/hildam/deer-flow-go/repo/template"
 )
 
 // investigatorImpl 调查者
+// 由模型根据用户问题生成多个搜索查询，并发调用搜索工具，将去重排序后的结果汇总给 Planner
 type investigatorImpl[I, O any] struct {
-	llm *openai.ChatModel // llm模型服务
+	llm ecmodel.ToolCallingChatModel // llm模型服务
 }
 
 // NewInvestigator 创建实例
@@ -32,71 +44,220 @@ func NewInvestigator[I, O any](ctx context.Context) *investigatorImpl[I, O] {
 func (i *investigatorImpl[I, O]) NewGraphNode(ctx context.Context) (key string, node compose.AnyGraph, nameOption compose.GraphAddNodeOpt) {
 	graph := compose.NewGraph[I, O]()
 
+	// 搜索工具在开始时解析一次，供 search 节点使用，每次运行单独构建图，不会被并发的运行共享
+	var tools []namedTool
+
 	// 添加节点
-	graph.AddLambdaNode("search", compose.InvokableLambdaWithOption(search))
+	graph.AddLambdaNode("load", compose.InvokableLambdaWithOption(loadMsg))
+	graph.AddChatModelNode("queries", i.llm)
+	graph.AddLambdaNode("search", compose.InvokableLambda(func(ctx context.Context, input *schema.Message) (string, error) {
+		return search(ctx, tools, input)
+	}))
 	graph.AddLambdaNode("router", compose.InvokableLambdaWithOption(router))
 
-	// 构造工作流
-	graph.AddEdge(compose.START, "search")
+	// 构造工作流，没有可用的搜索工具时跳过调查直接交给 Planner
+	graph.AddBranch(compose.START, compose.NewGraphBranch(func(ctx context.Context, _ I) (string, error) {
+		if tools = searchTools(ctx); len(tools) == 0 {
+			return "router", nil
+		}
+		return "load", nil
+	}, map[string]bool{"load": true, "router": true}))
+	graph.AddEdge("load", "queries")
+	graph.AddEdge("queries", "search")
 	graph.AddEdge("search", "router")
 	graph.AddEdge("router", compose.END)
 
 	return consts.BackgroundInvestigator, graph, compose.WithNodeName(consts.BackgroundInvestigator)
 }
 
-// search 网络搜索节点
-func search(ctx context.Context, name string, opts ...any) (output string, err error) {
-	// 获取网络搜索 mcp 工具
+// options 背景调查配置，未配置的项使用默认值
+func options() conf.InvestigationConfig {
+	opts := conf.GetCfg().Investigation
+	if opts.MaxQueries <= 0 {
+		opts.MaxQueries = 3
+	}
+	if opts.MaxResults <= 0 {
+		opts.MaxResults = 8
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 4
+	}
+	return opts
+}
+
+// namedTool 搜索工具及其名称
+type namedTool struct {
+	name string
+	tool tool.InvokableTool
+}
+
+// searchTools 背景调查使用的搜索工具
+// 配置了 tools 时使用配置的工具，否则使用名称以 search 结尾的全部工具；没有可用工具时发出 warning 事件
+func searchTools(ctx context.Context) []namedTool {
 	toolList, err := mcp.GetMCPTools(ctx)
 	if err != nil {
-		slog.Error("search failed, get mcp tools err = %+v", err)
-		return output, err
+		slog.Error("searchTools failed, get mcp tools err = %+v", err)
 	}
 
-	// 选择网络搜索工具
-	var searchTool tool.InvokableTool
-	for _, mcpTool := range toolList {
-		// 获取工具详情
-		toolInfo, err := mcpTool.Info(ctx)
+	configured := options().Tools
+	var tools []namedTool
+	for _, t := range toolList {
+		info, err := t.Info(ctx)
 		if err != nil {
-			slog.Error("search failed, get tool info err = %+v", err)
+			slog.Error("searchTools failed, get tool info err = %+v", err)
 			continue
 		}
-		if strings.HasSuffix(toolInfo.Name, "search") {
-			searchTool = mcpTool.(tool.InvokableTool)
-			break
+		invokable, ok := t.(tool.InvokableTool)
+		if !ok {
+			continue
+		}
+		if len(configured) > 0 && slices.Contains(configured, info.Name) ||
+			len(configured) == 0 && strings.HasSuffix(info.Name, "search") {
+			tools = append(tools, namedTool{name: info.Name, tool: invokable})
 		}
 	}
-
-	// 调用工具
-	err = compose.ProcessState[*model.State](ctx, func(ctx context.Context, state *model.State) error {
-		// 使用用户最后一条消息作为搜索查询
-		args := map[string]any{
-			"query": state.Messages[len(state.Messages)-1].Content,
+	if len(tools) == 0 {
+		msg := "no search tool available, background investigation skipped"
+		if len(configured) > 0 {
+			msg = fmt.Sprintf("search tools %v not available, background investigation skipped", configured)
 		}
+		slog.Info("searchTools: %s", msg)
+		event.Emit(ctx, &model.Event{Type: model.EventWarning, Agent: consts.BackgroundInvestigator, Message: msg})
+	}
+	return tools
+}
 
-		// 序列化参数
-		argsJSON, err := json.Marshal(args)
+// loadMsg 加载生成搜索查询的提示词
+func loadMsg(ctx context.Context, _ string, opts ...any) (output []*schema.Message, err error) {
+	err = compose.ProcessState[*model.State](ctx, func(ctx context.Context, state *model.State) error {
+		sysPrompt, err := template.GetPromptTemplate(ctx, consts.BackgroundInvestigator, state)
 		if err != nil {
-			slog.Error("search failed, marshal args err = %+v", err)
+			slog.Error("loadMsg failed, GetPromptTemplate err = %+v", err)
 			return err
 		}
 
-		// 调用工具
-		result, err := searchTool.InvokableRun(ctx, string(argsJSON))
-		if err != nil {
-			slog.Error("search failed, invokable run err = %+v", err)
-			return err
+		promptTemp := prompt.FromMessages(schema.Jinja2,
+			schema.SystemMessage(sysPrompt),
+			schema.UserMessage(fmt.Sprintf("Write at most %d search queries for the following request:\n\n%s",
+				options().MaxQueries, comm.UserQuery(state.Messages))),
+		)
+		variables := map[string]any{
+			"locale":       state.Locale,
+			"CURRENT_TIME": time.Now().Format("2006-01-02 15:04:05"),
 		}
-		slog.Debug("search debug, result = %+v, args = %+v", result, args)
+		output, err = promptTemp.Format(ctx, variables)
+		return err
+	})
+	return output, err
+}
 
-		// 将搜索结果保存为背景调研信息，供Planner使用
-		state.BackgroundInvestigationResults = result
+// search 使用搜索工具并发执行搜索查询，将去重排序后的结果汇总保存为背景调查结果，供Planner使用
+// 单次搜索失败只记录日志，不影响其他查询
+func search(ctx context.Context, tools []namedTool, input *schema.Message) (output string, err error) {
+	var query string
+	err = compose.ProcessState[*model.State](ctx, func(ctx context.Context, state *model.State) error {
+		query = comm.UserQuery(state.Messages)
+		return nil
+	})
+	if err != nil {
+		return output, err
+	}
+
+	cfg := options()
+	queries := parseQueries(input.Content, query, cfg.MaxQueries)
+	batches := runSearches(ctx, tools, queries, cfg.Concurrency)
+	summary := summarize(queries, mergeResults(batches), cfg.MaxResults)
+	slog.Debug("search debug, queries = %v, summary = %s", queries, summary)
+
+	err = compose.ProcessState[*model.State](ctx, func(ctx context.Context, state *model.State) error {
+		state.BackgroundInvestigationResults = summary
 		return nil
 	})
 	return output, err
 }
 
+// bulletPattern 列表项前的符号或序号
+var bulletPattern = regexp.MustCompile(`^(?:[-*•]|\d+[.)])\s*`)
+
+// parseQueries 解析模型生成的搜索查询
+// 支持 JSON 字符串数组、{"queries": [...]} 或每行一个查询的文本，去重后最多保留 limit 个，解析不到时使用用户问题
+func parseQueries(content, fallback string, limit int) []string {
+	content = strings.TrimSpace(content)
+	content = strings.TrimPrefix(content, "```json")
+	content = strings.TrimPrefix(content, "```")
+	content = strings.TrimSpace(strings.TrimSuffix(content, "```"))
+
+	var candidates []string
+	var wrapped struct {
+		Queries []string `json:"queries"`
+	}
+	if err := json.Unmarshal([]byte(content), &candidates); err != nil {
+		if err := json.Unmarshal([]byte(content), &wrapped); err == nil {
+			candidates = wrapped.Queries
+		} else {
+			for _, line := range strings.Split(content, "\n") {
+				line = bulletPattern.ReplaceAllString(strings.TrimSpace(line), "")
+				candidates = append(candidates, strings.Trim(line, `"`))
+			}
+		}
+	}
+
+	var queries []string
+	seen := map[string]bool{}
+	for _, q := range candidates {
+		q = strings.TrimSpace(q)
+		key := strings.ToLower(q)
+		if q == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		queries = append(queries, q)
+		if len(queries) == limit {
+			break
+		}
+	}
+	if len(queries) == 0 && strings.TrimSpace(fallback) != "" {
+		queries = []string{strings.TrimSpace(fallback)}
+	}
+	return queries
+}
+
+// batch 一次搜索的查询及结果
+type batch struct {
+	query   string
+	results []searchResult
+}
+
+// runSearches 以最多 concurrency 个并发对每个查询调用每个搜索工具
+// 结果按查询、工具的顺序排列，与完成顺序无关，保证排序结果稳定
+func runSearches(ctx context.Context, tools []namedTool, queries []string, concurrency int) []batch {
+	batches := make([]batch, len(queries)*len(tools))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for qi, q := range queries {
+		for ti, t := range tools {
+			idx := qi*len(tools) + ti
+			batches[idx].query = q
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+
+				args, _ := json.Marshal(map[string]any{"query": q})
+				result, err := t.tool.InvokableRun(ctx, string(args))
+				if err != nil {
+					slog.Error("runSearches failed, tool = %s, query = %s, err = %v", t.name, q, err)
+					return
+				}
+				batches[idx].results = parseResults(result)
+			}()
+		}
+	}
+	wg.Wait()
+	return batches
+}
+
 // router 路由节点
 func router(ctx context.Context, input string, opts ...any) (output string, err error) {
 	err = compose.ProcessState[*model.State](ctx, func(ctx context.Context, state *model.State) error {
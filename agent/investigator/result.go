@@ -0,0 +1,173 @@
+package investigator
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// contentLen 汇总中每条结果内容的最大长度（字符）
+const contentLen = 300
+
+// searchResult 一条搜索结果
+type searchResult struct {
+	Title   string
+	URL     string
+	Content string
+}
+
+// rankedResult 去重后的搜索结果及排序依据
+type rankedResult struct {
+	searchResult
+	queries map[string]bool // 命中该结果的查询
+	best    int             // 在各次搜索结果中的最好名次
+	first   int             // 首次出现的顺序
+}
+
+// parseResults 解析搜索工具的返回
+// 递归查找含有 url / link / href 的对象作为结果，MCP 的文本内容会再按 JSON 解析，无法解析时整段文本作为一条结果
+func parseResults(text string) []searchResult {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+	var v any
+	if err := json.Unmarshal([]byte(text), &v); err != nil {
+		return []searchResult{{Content: text}}
+	}
+	if s, ok := v.(string); ok {
+		return parseResults(s)
+	}
+	var out []searchResult
+	collect(v, &out)
+	return out
+}
+
+// collect 收集 JSON 值中的搜索结果
+func collect(v any, out *[]searchResult) {
+	switch v := v.(type) {
+	case []any:
+		for _, e := range v {
+			collect(e, out)
+		}
+	case map[string]any:
+		// MCP 工具返回的文本内容，内容本身可能是 JSON
+		if text, ok := v["text"].(string); ok && v["type"] == "text" {
+			*out = append(*out, parseResults(text)...)
+			return
+		}
+		if url := firstString(v, "url", "link", "href"); url != "" {
+			*out = append(*out, searchResult{
+				Title:   firstString(v, "title", "name"),
+				URL:     url,
+				Content: firstString(v, "content", "snippet", "description", "text", "body"),
+			})
+			return
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			collect(v[k], out)
+		}
+	}
+}
+
+// firstString 返回第一个存在且非空的字符串字段
+func firstString(m map[string]any, keys ...string) string {
+	for _, k := range keys {
+		if s, ok := m[k].(string); ok && strings.TrimSpace(s) != "" {
+			return strings.TrimSpace(s)
+		}
+	}
+	return ""
+}
+
+// dedupKey 去重使用的 key，有链接时按规范化的链接，否则按规范化的内容
+func dedupKey(r searchResult) string {
+	if r.URL != "" {
+		u := strings.ToLower(r.URL)
+		u, _, _ = strings.Cut(u, "#")
+		return "url:" + strings.TrimSuffix(u, "/")
+	}
+	return "content:" + strings.ToLower(strings.Join(strings.Fields(r.Content), " "))
+}
+
+// mergeResults 合并各次搜索的结果并去重排序
+// 命中的查询越多越靠前，其次按在搜索结果中的最好名次，最后按首次出现的顺序
+func mergeResults(batches []batch) []*rankedResult {
+	index := map[string]*rankedResult{}
+	var ranked []*rankedResult
+	for _, b := range batches {
+		for pos, r := range b.results {
+			key := dedupKey(r)
+			item, ok := index[key]
+			if !ok {
+				item = &rankedResult{searchResult: r, queries: map[string]bool{}, best: pos, first: len(ranked)}
+				index[key] = item
+				ranked = append(ranked, item)
+			}
+			item.queries[b.query] = true
+			item.best = min(item.best, pos)
+			// 保留信息更完整的标题和内容
+			if item.Title == "" {
+				item.Title = r.Title
+			}
+			if len(r.Content) > len(item.Content) {
+				item.Content = r.Content
+			}
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if len(a.queries) != len(b.queries) {
+			return len(a.queries) > len(b.queries)
+		}
+		if a.best != b.best {
+			return a.best < b.best
+		}
+		return a.first < b.first
+	})
+	return ranked
+}
+
+// summarize 生成给 Planner 的背景调查汇总，最多保留 limit 条结果，没有结果时返回空
+func summarize(queries []string, ranked []*rankedResult, limit int) string {
+	if len(ranked) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Search queries: %s\n", strings.Join(queries, "; "))
+	for i, r := range ranked[:min(len(ranked), limit)] {
+		title := r.Title
+		if title == "" {
+			title = r.URL
+		}
+		switch {
+		case r.URL != "":
+			fmt.Fprintf(&sb, "\n%d. [%s](%s)", i+1, title, r.URL)
+		case title != "":
+			fmt.Fprintf(&sb, "\n%d. %s", i+1, title)
+		default:
+			fmt.Fprintf(&sb, "\n%d.", i+1)
+		}
+		if content := truncate(r.Content, contentLen); content != "" {
+			fmt.Fprintf(&sb, "\n   %s", content)
+		}
+		sb.WriteString("\n")
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// truncate 合并空白并截断到 n 个字符
+func truncate(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n]) + "..."
+}
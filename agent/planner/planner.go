@@ -4,14 +4,17 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/HildaM/logs/slog"
 
-	"github.com/cloudwego/eino-ext/components/model/openai"
+	ecmodel "github.com/cloudwego/eino/components/model"
 	"github.com/cloudwego/eino/components/prompt"
 	"github.com/cloudwego/eino/compose"
 	"github.com/cloudwego/eino/schema"
+	"github.com/hildam/deer-flow-go/agent/comm"
+	"github.com/hildam/deer-flow-go/entity/conf"
 	"github.com/hildam/deer-flow-go/entity/consts"
 	"github.com/hildam/deer-flow-go/entity/model"
 	"github.com/hildam/deer-flow-go/repo/llm"
@@ -20,13 +23,19 @@ import (
 
 // plannerImpl 计划者
 type plannerImpl[I, O any] struct {
-	llm *openai.ChatModel // llm模型服务
+	llm    ecmodel.ToolCallingChatModel // llm模型服务
+	agents []conf.AgentConfig           // 自定义智能体，计划中可使用其处理的步骤类型
 }
 
-// NewPlanner 创建实例
-func NewPlanner[I, O any](ctx context.Context) *plannerImpl[I, O] {
+// NewPlanner 创建实例，计划的步骤类型包括内置类型和 agents 处理的自定义类型
+func NewPlanner[I, O any](ctx context.Context, agents []conf.AgentConfig) *plannerImpl[I, O] {
+	stepTypes := []model.StepType{model.Research, model.Processing}
+	for _, a := range agents {
+		stepTypes = append(stepTypes, model.StepType(a.StepType))
+	}
 	return &plannerImpl[I, O]{
-		llm: llm.NewPlanModel(ctx),
+		llm:    llm.NewPlanModel(ctx, stepTypes),
+		agents: agents,
 	}
 }
 
@@ -36,7 +45,7 @@ func (p *plannerImpl[I, O]) NewGraphNode(ctx context.Context) (key string, node
 	graph := compose.NewGraph[I, O]()
 
 	// 添加节点
-	graph.AddLambdaNode("load", compose.InvokableLambdaWithOption(loadMsg))
+	graph.AddLambdaNode("load", compose.InvokableLambdaWithOption(p.loadMsg))
 	graph.AddChatModelNode("agent", p.llm)
 	graph.AddLambdaNode("router", compose.InvokableLambdaWithOption(router))
 
@@ -50,10 +59,10 @@ func (p *plannerImpl[I, O]) NewGraphNode(ctx context.Context) (key string, node
 }
 
 // loadMsg Planner的load节点处理函数，负责加载计划生成的提示词模板
-func loadMsg(ctx context.Context, name string, opts ...any) (output []*schema.Message, err error) {
+func (p *plannerImpl[I, O]) loadMsg(ctx context.Context, _ string, opts ...any) (output []*schema.Message, err error) {
 	err = compose.ProcessState[*model.State](ctx, func(ctx context.Context, state *model.State) error {
 		// 加载模板
-		sysPrompt, err := template.GetPromptTemplate(ctx, name)
+		sysPrompt, err := template.GetPromptTemplate(ctx, consts.Planner, state)
 		if err != nil {
 			slog.Error("loadMsg failed, GetPromptTemplate err = %+v", err)
 			return err
@@ -86,11 +95,29 @@ func loadMsg(ctx context.Context, name string, opts ...any) (output []*schema.Me
 		}
 		// 使用变量格式化提示词模板，生成最终的消息列表
 		output, err = promptTemp.Format(ctx, variables)
-		return err
+		if err != nil {
+			return err
+		}
+		// 说明自定义步骤类型，不经过模板渲染，避免说明中的特殊字符被当作模板语法
+		if len(p.agents) > 0 {
+			output = append(output, schema.SystemMessage(stepTypesMsg(p.agents)))
+		}
+		return nil
 	})
 	return output, err
 }
 
+// stepTypesMsg 自定义步骤类型的说明
+func stepTypesMsg(agents []conf.AgentConfig) string {
+	var sb strings.Builder
+	sb.WriteString("In addition to \"research\" and \"processing\", the following step types are available. ")
+	sb.WriteString("Use one of them as `step_type` when a step matches its description:\n")
+	for _, a := range agents {
+		fmt.Fprintf(&sb, "\n- `%s`: %s", a.StepType, a.Description)
+	}
+	return sb.String()
+}
+
 // router 路由
 func router(ctx context.Context, input *schema.Message, opts ...any) (output string, err error) {
 	err = compose.ProcessState[*model.State](ctx, func(ctx context.Context, state *model.State) error {
@@ -122,6 +149,13 @@ func router(ctx context.Context, input *schema.Message, opts ...any) (output str
 		// 计划生成成功，记录日志并增加迭代计数
 		slog.Debug("router success, input.Content = %+v, state.CurrentPlan = %+v", input.Content, state.CurrentPlan)
 		state.PlanIterations++
+		comm.ResetSteps(state.CurrentPlan)
+		comm.EmitPlan(ctx, consts.Planner, state.CurrentPlan)
+
+		// 仅制定计划时直接结束
+		if state.PlanOnly {
+			return nil
+		}
 
 		// 检查计划是否包含足够的上下文信息
 		if state.CurrentPlan.HasEnoughContext {
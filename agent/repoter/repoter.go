@@ -2,30 +2,39 @@ package repoter
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/HildaM/logs/slog"
 
-	"github.com/cloudwego/eino-ext/components/model/openai"
+	ecmodel "github.com/cloudwego/eino/components/model"
 	"github.com/cloudwego/eino/components/prompt"
 	"github.com/cloudwego/eino/compose"
 	"github.com/cloudwego/eino/schema"
+	"github.com/hildam/deer-flow-go/agent/comm"
 	"github.com/hildam/deer-flow-go/entity/consts"
 	"github.com/hildam/deer-flow-go/entity/model"
+	"github.com/hildam/deer-flow-go/repo/artifact"
+	"github.com/hildam/deer-flow-go/repo/event"
 	"github.com/hildam/deer-flow-go/repo/llm"
+	"github.com/hildam/deer-flow-go/repo/mcp"
 	"github.com/hildam/deer-flow-go/repo/template"
 )
 
 // repoterImpl 报告者
 type repoterImpl[I, O any] struct {
-	llm *openai.ChatModel // llm模型服务
+	llm       ecmodel.ToolCallingChatModel // llm模型服务
+	reportLLM ecmodel.ToolCallingChatModel // 结构化输出模式使用的模型，按 model.Report 输出 JSON
 }
 
 // NewRepoter 创建实例
 func NewRepoter[I, O any](ctx context.Context) *repoterImpl[I, O] {
 	return &repoterImpl[I, O]{
-		llm: llm.NewChatModel(ctx),
+		llm:       llm.NewChatModel(ctx),
+		reportLLM: llm.NewReportModel(ctx),
 	}
 }
 
@@ -37,24 +46,35 @@ func (r *repoterImpl[I, O]) NewGraphNode(ctx context.Context) (key string, node
 	// 添加节点
 	graph.AddLambdaNode("load", compose.InvokableLambdaWithOption(loadMsg))
 	graph.AddChatModelNode("agent", r.llm)
+	graph.AddChatModelNode("structured", r.reportLLM)
 	graph.AddLambdaNode("router", compose.InvokableLambdaWithOption(router))
 
-	// 构造关联
+	// 构造关联，按运行配置选择 Markdown 或结构化输出模式
 	graph.AddEdge(compose.START, "load")
-	graph.AddEdge("load", "agent")
+	graph.AddBranch("load", compose.NewGraphBranch(selectModel, map[string]bool{"agent": true, "structured": true}))
 	graph.AddEdge("agent", "router")
+	graph.AddEdge("structured", "router")
 	graph.AddEdge("router", compose.END)
 
 	return consts.Reporter, graph, compose.WithNodeName(consts.Reporter)
 }
 
 // loadMsg 加载消息，为Reporter智能体加载消息和提示词模板
-func loadMsg(ctx context.Context, name string, opts ...any) (output []*schema.Message, err error) {
+func loadMsg(ctx context.Context, _ string, opts ...any) (output []*schema.Message, err error) {
+	// 获取本次运行生成的产物，获取失败时报告不引用产物
+	var artifacts []*model.Artifact
+	if runID := mcp.SessionID(ctx); runID != "" {
+		var listErr error
+		if artifacts, listErr = artifact.List(ctx, runID); listErr != nil {
+			slog.Error("loadMsg failed, list artifacts err = %v, run_id = %s", listErr, runID)
+		}
+	}
+
 	err = compose.ProcessState[*model.State](ctx, func(_ context.Context, state *model.State) error {
 		// 获取Reporter的系统提示词模板，定义报告生成的格式和要求
-		sysPrompt, err := template.GetPromptTemplate(ctx, name)
+		sysPrompt, err := template.GetPromptTemplate(ctx, consts.Reporter, state)
 		if err != nil {
-			slog.Error("loadMsg failed, GetPromptTemplate err = %+v, template name = %+v", err, name)
+			slog.Error("loadMsg failed, GetPromptTemplate err = %+v, template name = %+v", err, consts.Reporter)
 			return err
 		}
 
@@ -64,19 +84,31 @@ func loadMsg(ctx context.Context, name string, opts ...any) (output []*schema.Me
 			schema.MessagesPlaceholder("user_input", true),
 		)
 
+		// 工作流跳过 Planner 时以用户问题作为研究任务
+		plan := comm.EnsurePlan(ctx, state, model.Research)
+
 		// 构建消息列表，包含研究任务信息和格式指导
 		msg := []*schema.Message{}
 		// 添加研究任务的基本信息（标题和描述）
 		msg = append(msg,
-			schema.UserMessage(fmt.Sprintf("# Research Requirements\n\n## Task\n\n %v \n\n## Description\n\n %v", state.CurrentPlan.Title, state.CurrentPlan.Thought)),
-			// 添加报告格式的详细指导，强调结构化输出和Markdown表格的使用
-			schema.SystemMessage("IMPORTANT: Structure your report according to the format in the prompt. Remember to include:\n\n1. Key Points - A bulleted list of the most important findings\n2. Overview - A brief introduction to the topic\n3. Detailed Analysis - Organized into logical sections\n4. Survey Note (optional) - For more comprehensive reports\n5. Key Citations - List all references at the end\n\nFor citations, DO NOT include inline citations in the text. Instead, place all citations in the 'Key Citations' section at the end using the format: `- [Source Title](URL)`. Include an empty line between each citation for better readability.\n\nPRIORITIZE USING MARKDOWN TABLES for data presentation and comparison. Use tables whenever presenting comparative data, statistics, features, or options. Structure tables with clear headers and aligned columns. Example table format:\n\n| Feature | Description | Pros | Cons |\n|---------|-------------|------|------|\n| Feature 1 | Description 1 | Pros 1 | Cons 1 |\n| Feature 2 | Description 2 | Pros 2 | Cons 2 |"),
+			schema.UserMessage(fmt.Sprintf("# Research Requirements\n\n## Task\n\n %v \n\n## Description\n\n %v", plan.Title, plan.Thought)),
+			formatMsg(state.StructuredReport),
 		)
 
-		// 遍历所有已执行的研究步骤，将执行结果作为观察数据添加到消息中
-		for _, step := range state.CurrentPlan.Steps {
+		// 遍历所有已执行的研究步骤，将执行结果作为观察数据添加到消息中，工作流未执行的步骤跳过
+		for _, step := range plan.Steps {
+			if step.ExecutionRes == nil {
+				continue
+			}
 			msg = append(msg, schema.UserMessage(fmt.Sprintf("Below are some observations for the research task:\n\n %v", *step.ExecutionRes)))
 		}
+		// 失败和跳过的步骤没有观察数据，要求报告中注明
+		if failed := failedStepsMsg(plan); failed != nil {
+			msg = append(msg, failed)
+		}
+		if len(artifacts) > 0 {
+			msg = append(msg, schema.UserMessage(artifactsMsg(artifacts)))
+		}
 		variables := map[string]any{
 			"locale":              state.Locale,
 			"max_step_num":        state.MaxStepNum,
@@ -92,8 +124,59 @@ func loadMsg(ctx context.Context, name string, opts ...any) (output []*schema.Me
 	return output, err
 }
 
+// failedStepsMsg 列出执行失败和跳过的步骤，要求报告在局限性中说明，没有这类步骤时返回 nil
+func failedStepsMsg(plan *model.Plan) *schema.Message {
+	var sb strings.Builder
+	for _, step := range plan.Steps {
+		if step.Status == model.StepFailed || step.Status == model.StepSkipped {
+			fmt.Fprintf(&sb, "- %s (%s: %s)\n", step.Title, step.Status, step.Error)
+		}
+	}
+	if sb.Len() == 0 {
+		return nil
+	}
+	return schema.UserMessage("The following research steps failed or were skipped and produced no observations. " +
+		"Do not make up findings for them, and clearly note in the report which steps failed and that the conclusions may be incomplete:\n\n" + sb.String())
+}
+
+// markdownFormat Markdown 模式的报告格式指导，强调章节结构和Markdown表格的使用
+const markdownFormat = "IMPORTANT: Structure your report according to the format in the prompt. Remember to include:\n\n1. Key Points - A bulleted list of the most important findings\n2. Overview - A brief introduction to the topic\n3. Detailed Analysis - Organized into logical sections\n4. Survey Note (optional) - For more comprehensive reports\n5. Key Citations - List all references at the end\n\nFor citations, DO NOT include inline citations in the text. Instead, place all citations in the 'Key Citations' section at the end using the format: `- [Source Title](URL)`. Include an empty line between each citation for better readability.\n\nPRIORITIZE USING MARKDOWN TABLES for data presentation and comparison. Use tables whenever presenting comparative data, statistics, features, or options. Structure tables with clear headers and aligned columns. Example table format:\n\n| Feature | Description | Pros | Cons |\n|---------|-------------|------|------|\n| Feature 1 | Description 1 | Pros 1 | Cons 1 |\n| Feature 2 | Description 2 | Pros 2 | Cons 2 |"
+
+// structuredFormat 结构化输出模式的报告格式指导，报告按 model.Report 输出 JSON 后由服务端渲染为 Markdown
+const structuredFormat = "IMPORTANT: Respond with a single JSON object describing the report, without any text or code fences around it. The JSON object has the following fields:\n\n" +
+	"- title: the report title\n" +
+	"- key_points: an array of the most important findings, one sentence each\n" +
+	"- overview: a brief introduction to the topic, in Markdown\n" +
+	"- sections: the detailed analysis organized into logical sections, each with a title, Markdown content and optional tables\n" +
+	"- survey_note (optional): a more comprehensive survey note, in Markdown\n" +
+	"- citations: all references, each with a title and url\n\n" +
+	"Each table has an optional caption, headers (an array of column names) and rows (an array of rows, each an array of cell strings with the same length as headers). " +
+	"PRIORITIZE USING TABLES for data presentation and comparison, and put them in the tables field instead of writing Markdown tables in the content. " +
+	"DO NOT include inline citations in the text, list all of them in the citations field."
+
+// formatMsg 构造报告格式指导消息
+func formatMsg(structured bool) *schema.Message {
+	if structured {
+		return schema.SystemMessage(structuredFormat)
+	}
+	return schema.SystemMessage(markdownFormat)
+}
+
+// selectModel 选择报告模型，结构化输出模式使用按 model.Report 输出 JSON 的模型
+func selectModel(ctx context.Context, _ []*schema.Message) (next string, err error) {
+	next = "agent"
+	err = compose.ProcessState[*model.State](ctx, func(_ context.Context, state *model.State) error {
+		if state.StructuredReport {
+			next = "structured"
+		}
+		return nil
+	})
+	return next, err
+}
+
 // router 路由到下一个节点
 func router(ctx context.Context, input *schema.Message, opts ...any) (output string, err error) {
+	var rendered *model.Event
 	err = compose.ProcessState[*model.State](ctx, func(_ context.Context, state *model.State) error {
 		defer func() {
 			output = state.Goto
@@ -101,10 +184,61 @@ func router(ctx context.Context, input *schema.Message, opts ...any) (output str
 
 		// 记录报告生成完成的事件，包含完整的报告内容
 		slog.Debug("router success, input.Content = %+v", input.Content)
+		state.FinalReport = input.Content
+
+		// 结构化输出模式下解析报告并渲染为 Markdown，解析失败时保留模型的原始输出
+		if state.StructuredReport {
+			report, err := parseReport(input.Content)
+			if err != nil {
+				slog.Error("router failed, parseReport err = %v, input.Content = %+v", err, input.Content)
+			} else {
+				state.Report = report
+				state.FinalReport = report.Markdown()
+				rendered = &model.Event{Type: model.EventReport, Agent: consts.Reporter, Report: report, Content: state.FinalReport}
+			}
+		}
 
 		// 设置流程结束标志，整个多智能体研究流程到此完成
 		state.Goto = compose.END
 		return nil
 	})
+	// 流式推送的是模型输出的 JSON，解析后推送渲染好的报告供客户端展示
+	if rendered != nil {
+		event.Emit(ctx, rendered)
+	}
 	return output, nil
 }
+
+// parseReport 解析结构化报告，兼容模型在 JSON 外包裹的代码块标记
+func parseReport(content string) (*model.Report, error) {
+	content = strings.TrimSpace(content)
+	if strings.HasPrefix(content, "```") {
+		content = strings.TrimPrefix(content, "```json")
+		content = strings.TrimPrefix(content, "```")
+		content = strings.TrimSuffix(strings.TrimSpace(content), "```")
+	}
+	report := &model.Report{}
+	if err := json.Unmarshal([]byte(content), report); err != nil {
+		return nil, err
+	}
+	if report.Title == "" && len(report.Sections) == 0 {
+		return nil, errors.New("empty report")
+	}
+	return report, nil
+}
+
+// artifactsMsg 构造产物列表消息，指导报告嵌入图片、链接其他文件
+func artifactsMsg(artifacts []*model.Artifact) string {
+	var sb strings.Builder
+	sb.WriteString("Below are the files (artifacts) produced during the research. " +
+		"Embed relevant images in the report using `![Description](URL)` and link other files using `[Name](URL)`, " +
+		"placing them next to the analysis they support. Use the URLs exactly as given and do not list them in Key Citations.\n")
+	for _, a := range artifacts {
+		fmt.Fprintf(&sb, "\n- %s (%s, %d bytes", a.Name, a.MIMEType, a.Size)
+		if a.StepTitle != "" {
+			fmt.Fprintf(&sb, ", produced by step %d: %s", a.Step, a.StepTitle)
+		}
+		fmt.Fprintf(&sb, "): %s", artifact.URL(a.RunID, a.Name))
+	}
+	return sb.String()
+}
@@ -2,11 +2,14 @@ package researcher
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/HildaM/logs/slog"
 
-	"github.com/cloudwego/eino-ext/components/model/openai"
+	ecmodel "github.com/cloudwego/eino/components/model"
 	"github.com/cloudwego/eino/compose"
+	"github.com/hildam/deer-flow-go/agent/comm"
+	"github.com/hildam/deer-flow-go/entity/conf"
 	"github.com/hildam/deer-flow-go/entity/consts"
 	"github.com/hildam/deer-flow-go/entity/model"
 	"github.com/hildam/deer-flow-go/repo/llm"
@@ -14,13 +17,22 @@ import (
 
 // singleResearcherImpl 研究团队。这是整个多智能体系统的调度中心，负责根据当前状态和计划步骤决定下一个执行的智能体
 type researcherTeamImpl[I, O any] struct {
-	llm *openai.ChatModel // llm模型服务
+	llm    ecmodel.ToolCallingChatModel // llm模型服务
+	routes map[model.StepType]string    // 步骤类型 -> 执行该类型步骤的智能体
 }
 
-// NewResearcherTeam 创建实例
-func NewResearcherTeam[I, O any](ctx context.Context) *researcherTeamImpl[I, O] {
+// NewResearcherTeam 创建实例，agents 为处理自定义步骤类型的智能体
+func NewResearcherTeam[I, O any](ctx context.Context, agents []conf.AgentConfig) *researcherTeamImpl[I, O] {
+	routes := map[model.StepType]string{
+		model.Research:   consts.Researcher,
+		model.Processing: consts.Coder,
+	}
+	for _, a := range agents {
+		routes[model.StepType(a.StepType)] = a.Name
+	}
 	return &researcherTeamImpl[I, O]{
-		llm: llm.NewChatModel(ctx),
+		llm:    llm.NewChatModel(ctx),
+		routes: routes,
 	}
 }
 
@@ -30,7 +42,7 @@ func (r *researcherTeamImpl[I, O]) NewGraphNode(ctx context.Context) (key string
 	graph := compose.NewGraph[I, O]()
 
 	// 添加节点
-	graph.AddLambdaNode("router", compose.InvokableLambdaWithOption(teamRouter))
+	graph.AddLambdaNode("router", compose.InvokableLambdaWithOption(r.teamRouter))
 
 	// 构造关联
 	graph.AddEdge(compose.START, "router")
@@ -40,7 +52,7 @@ func (r *researcherTeamImpl[I, O]) NewGraphNode(ctx context.Context) (key string
 }
 
 // teamRouter 核心路由决策函数. 整个多智能体系统的调度中心，负责根据当前状态和计划步骤决定下一个执行的智能体
-func teamRouter(ctx context.Context, input string, opts ...any) (output string, err error) {
+func (r *researcherTeamImpl[I, O]) teamRouter(ctx context.Context, input string, opts ...any) (output string, err error) {
 	err = compose.ProcessState[*model.State](ctx, func(ctx context.Context, state *model.State) error {
 		defer func() {
 			output = state.Goto
@@ -57,22 +69,21 @@ func teamRouter(ctx context.Context, input string, opts ...any) (output string,
 
 		// 遍历计划中的所有步骤，寻找第一个未执行的步骤
 		for idx, step := range state.CurrentPlan.Steps {
-			// 跳过已经执行完成的步骤
-			if step.ExecutionRes != nil {
+			// 跳过已经执行成功、失败或跳过的步骤
+			if step.Done() {
 				continue
 			}
 
 			slog.Debug("router debug, research team current step: %v, step index: %v", step, idx)
 
-			// 根据计划类型选择响应的节点
-			switch step.StepType {
-			case model.Research:
-				state.Goto = consts.Researcher
-				return nil
-			case model.Processing:
-				state.Goto = consts.Coder
-				return nil
+			// 根据计划类型选择响应的节点，没有智能体处理的步骤标记为跳过
+			agent, ok := r.routes[step.StepType]
+			if !ok {
+				comm.SkipStep(ctx, state.CurrentPlan, idx, fmt.Sprintf("no agent for step type %q", step.StepType))
+				continue
 			}
+			state.Goto = agent
+			return nil
 		}
 
 		// 所有步骤都已执行完成，检查是否需要生成最终报告
@@ -3,12 +3,11 @@ package researcher
 import (
 	"context"
 	"fmt"
-	"strings"
 	"time"
 
 	"github.com/HildaM/logs/slog"
 
-	"github.com/cloudwego/eino-ext/components/model/openai"
+	ecmodel "github.com/cloudwego/eino/components/model"
 	"github.com/cloudwego/eino/components/prompt"
 	"github.com/cloudwego/eino/components/tool"
 	"github.com/cloudwego/eino/compose"
@@ -25,7 +24,7 @@ import (
 
 // singleResearcherImpl 单个研究者
 type singleResearcherImpl[I, O any] struct {
-	llm *openai.ChatModel // llm模型服务
+	llm ecmodel.ToolCallingChatModel // llm模型服务
 }
 
 // NewSingleResearcher 创建实例
@@ -62,7 +61,7 @@ func (r *singleResearcherImpl[I, O]) NewGraphNode(ctx context.Context) (key stri
 	}
 
 	// 封装为 lambda 节点
-	agentLambda, err := compose.AnyLambda(reactAgent.Generate, reactAgent.Stream, nil, nil)
+	agentLambda, err := comm.AgentLambda(consts.Researcher, reactAgent)
 	if err != nil {
 		slog.Fatal("NewGraphNode failed, create lambda node err = %+v", err)
 	}
@@ -82,12 +81,12 @@ func (r *singleResearcherImpl[I, O]) NewGraphNode(ctx context.Context) (key stri
 }
 
 // loadMsg 为Researcher智能体加载消息和提示词模板
-func loadMsg(ctx context.Context, name string, opts ...any) (output []*schema.Message, err error) {
+func loadMsg(ctx context.Context, _ string, opts ...any) (output []*schema.Message, err error) {
 	err = compose.ProcessState[*model.State](ctx, func(_ context.Context, state *model.State) error {
 		// 获取Researcher的系统提示词模板，定义研究任务的执行方式
-		sysPrompt, err := template.GetPromptTemplate(ctx, name)
+		sysPrompt, err := template.GetPromptTemplate(ctx, consts.Researcher, state)
 		if err != nil {
-			slog.Error("loadMsg failed, GetPromptTemplate err = %+v, prompt name = %+v", err, name)
+			slog.Error("loadMsg failed, GetPromptTemplate err = %+v, prompt name = %+v", err, consts.Researcher)
 			return err
 		}
 
@@ -97,14 +96,8 @@ func loadMsg(ctx context.Context, name string, opts ...any) (output []*schema.Me
 			schema.MessagesPlaceholder("user_input", true),
 		)
 
-		// 从当前计划中找到第一个未执行的研究步骤
-		var curStep *model.Step
-		for i := range state.CurrentPlan.Steps {
-			if state.CurrentPlan.Steps[i].ExecutionRes == nil {
-				curStep = &state.CurrentPlan.Steps[i]
-				break
-			}
-		}
+		// 从当前计划中找到第一个未执行的研究步骤，工作流跳过 Planner 时以用户问题作为研究步骤
+		curStep := comm.StartStep(ctx, state, consts.Researcher, model.Research)
 
 		// 确保找到了待执行的步骤
 		if curStep == nil {
@@ -140,15 +133,8 @@ func singleRouter(ctx context.Context, input *schema.Message, opts ...any) (outp
 		defer func() {
 			output = state.Goto
 		}()
-		// 将研究结果保存到第一个未执行步骤的ExecutionRes字段中
-		for i, step := range state.CurrentPlan.Steps {
-			if step.ExecutionRes == nil {
-				// 克隆研究结果内容并保存
-				str := strings.Clone(last.Content)
-				state.CurrentPlan.Steps[i].ExecutionRes = &str
-				break
-			}
-		}
+		// 将研究结果保存到执行中的步骤并置为执行成功，智能体重试后仍然失败时置为失败并记录错误
+		comm.CompleteStep(ctx, state, consts.Researcher, last)
 		// 记录研究任务完成的事件，包含更新后的计划状态
 		slog.Debug("routerResearcher debug, researcher_end, plan = %+v", state.CurrentPlan)
 
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hildam/deer-flow-go/agent"
+	"github.com/hildam/deer-flow-go/biz/service"
+	"github.com/hildam/deer-flow-go/repo/template"
+)
+
+// runBatch 批量研究文件中的问题
+func runBatch(args []string) error {
+	var f runFlags
+	fs := flag.NewFlagSet("batch", flag.ExitOnError)
+	fs.StringVar(&configPath, "config", configPath, "配置文件路径")
+	input := fs.String("input", "", "问题文件，JSONL 或带表头的 CSV（id,query,locale）")
+	outputDir := fs.String("output-dir", "output", "输出目录，已完成的问题再次运行时跳过")
+	workers := fs.Int("workers", 2, "并发运行的问题数")
+	fs.BoolVar(&f.quiet, "quiet", false, "不在标准错误输出进度")
+	fs.IntVar(&f.maxPlanIterations, "max-plan-iterations", 0, "最大计划迭代次数，默认使用配置")
+	fs.IntVar(&f.maxStepNum, "max-step-num", 0, "计划最大步骤数，默认使用配置")
+	fs.BoolVar(&f.backgroundInvestigation, "background-investigation", false, "制定计划前先进行背景调查")
+	fs.BoolVar(&f.structuredReport, "structured-report", false, "Reporter 以结构化输出生成报告，默认使用配置")
+	fs.Var(&f.promptVariants, "prompt-variant", "智能体使用的提示词变体，如 planner=concise，可重复指定")
+	fs.StringVar(&f.workflow, "workflow", "", "使用的工作流，如 quick_answer，默认使用配置")
+	f.parse(fs, args)
+
+	if *input == "" {
+		return errors.New("batch failed, --input is required")
+	}
+	items, err := service.LoadBatchItems(*input)
+	if err != nil {
+		return fmt.Errorf("batch failed, err: %w", err)
+	}
+	defer closeDeps()
+	if err := initDeps(); err != nil {
+		return err
+	}
+	if err := template.ValidateVariants(f.promptVariants); err != nil {
+		return fmt.Errorf("batch failed, err: %w", err)
+	}
+
+	// 收到中断信号后不再开始新的问题，运行中的问题取消后记为失败，下次运行时重试
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var mu sync.Mutex
+	progress := func(format string, a ...any) {
+		if f.quiet {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintf(os.Stderr, format, a...)
+	}
+	summary, err := service.RunBatch(ctx, items, service.BatchOption{
+		Workers:      *workers,
+		OutputDir:    *outputDir,
+		Workflow:     f.workflow,
+		StateOptions: []agent.StateOption{f.stateOption()},
+		OnStart: func(item *service.BatchItem) {
+			progress("[%s] 开始：%s\n", item.ID, truncate(item.Query, 60))
+		},
+		OnDone: func(item *service.BatchItem, r *service.BatchResult) {
+			if !r.Completed() {
+				progress("[%s] 失败：%s\n", item.ID, r.Error)
+				return
+			}
+			progress("[%s] 完成，耗时 %s\n", item.ID, r.Record.FinishedAt.Sub(r.Record.StartedAt).Round(time.Second))
+		},
+	})
+	if summary == nil {
+		return fmt.Errorf("batch failed, err: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "共 %d 个问题：完成 %d，失败 %d，跳过 %d\n",
+		summary.Total, summary.Completed, summary.Failed, summary.Skipped)
+	if err != nil || summary.Failed > 0 || summary.Completed+summary.Skipped < summary.Total {
+		return exitWith(exitFailed, nil)
+	}
+	return nil
+}
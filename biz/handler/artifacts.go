@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/HildaM/logs/slog"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/utils"
+
+	"github.com/hildam/deer-flow-go/entity/model"
+	"github.com/hildam/deer-flow-go/repo/artifact"
+)
+
+// artifactView 产物元信息及下载地址
+type artifactView struct {
+	*model.Artifact
+	URL string `json:"url"`
+}
+
+// ListArtifacts 列出运行生成的产物
+func ListArtifacts(ctx context.Context, c *app.RequestContext) {
+	items, err := artifact.List(ctx, c.Param("thread_id"))
+	if errors.Is(err, artifact.ErrInvalidName) {
+		c.JSON(http.StatusBadRequest, utils.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		slog.Error("ListArtifacts failed, err = %v", err)
+		c.JSON(http.StatusInternalServerError, utils.H{"error": err.Error()})
+		return
+	}
+	views := make([]artifactView, 0, len(items))
+	for _, a := range items {
+		views = append(views, artifactView{Artifact: a, URL: artifact.URL(a.RunID, a.Name)})
+	}
+	c.JSON(http.StatusOK, utils.H{"artifacts": views})
+}
+
+// inlineTypes 可在浏览器中直接展示的产物类型，仅限位图和纯文本
+// HTML、SVG、XML 等可执行脚本的类型一律作为附件下载，避免产物中的脚本在服务的域名下运行
+var inlineTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+	"image/bmp":  true,
+	"text/plain": true,
+}
+
+// DownloadArtifact 下载产物，位图和纯文本在浏览器中直接展示，其他文件作为附件下载
+func DownloadArtifact(ctx context.Context, c *app.RequestContext) {
+	a, rc, err := artifact.Open(ctx, c.Param("thread_id"), c.Param("name"))
+	switch {
+	case errors.Is(err, artifact.ErrInvalidName):
+		c.JSON(http.StatusBadRequest, utils.H{"error": err.Error()})
+		return
+	case errors.Is(err, artifact.ErrNotFound):
+		c.JSON(http.StatusNotFound, utils.H{"error": err.Error()})
+		return
+	case err != nil:
+		slog.Error("DownloadArtifact failed, err = %v", err)
+		c.JSON(http.StatusInternalServerError, utils.H{"error": err.Error()})
+		return
+	}
+
+	disposition := "attachment"
+	if t, _, _ := mime.ParseMediaType(a.MIMEType); inlineTypes[t] {
+		disposition = "inline"
+	}
+	c.Header("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": path.Base(a.Name)}))
+	c.Header("Content-Length", strconv.FormatInt(a.Size, 10))
+	// 产物内容由工具生成，禁止浏览器猜测类型，并在沙箱中展示
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Content-Security-Policy", "sandbox")
+	c.SetContentType(a.MIMEType)
+	// 响应写完后由框架关闭 rc
+	c.SetBodyStream(rc, int(a.Size))
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/common/config"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+	"github.com/cloudwego/hertz/pkg/route"
+
+	"github.com/hildam/deer-flow-go/repo/artifact"
+)
+
+// TestDownloadArtifact 仅位图和纯文本内联展示，其他类型作为附件下载，均禁止类型猜测并在沙箱中展示
+func TestDownloadArtifact(t *testing.T) {
+	artifact.SetStore(artifact.NewLocalStore(t.TempDir()))
+	ctx := context.Background()
+	const runID = "download-run"
+
+	r := route.NewEngine(config.NewOptions(nil))
+	r.GET("/api/runs/:thread_id/artifacts/*name", DownloadArtifact)
+
+	tests := []struct {
+		name        string
+		mimeType    string
+		disposition string
+	}{
+		{"chart.png", "image/png", "inline"},
+		{"notes.txt", "text/plain; charset=utf-8", "inline"},
+		{"page.html", "text/html; charset=utf-8", "attachment"},
+		{"icon.svg", "image/svg+xml", "attachment"},
+		{"data.xml", "application/xml", "attachment"},
+		{"report.md", "text/markdown; charset=utf-8", "attachment"},
+	}
+	for _, tt := range tests {
+		a := artifact.New(ctx, runID, tt.name, "test")
+		a.MIMEType = tt.mimeType
+		if err := artifact.Put(ctx, a, strings.NewReader("<script>alert(1)</script>")); err != nil {
+			t.Fatalf("Put %s: %v", tt.name, err)
+		}
+
+		resp := ut.PerformRequest(r, "GET", artifact.URL(runID, tt.name), nil).Result()
+		if resp.StatusCode() != 200 {
+			t.Errorf("%s: status = %d", tt.name, resp.StatusCode())
+			continue
+		}
+		if got := string(resp.Header.Peek("Content-Disposition")); !strings.HasPrefix(got, tt.disposition+";") {
+			t.Errorf("%s: Content-Disposition = %q, want %s", tt.name, got, tt.disposition)
+		}
+		if got := string(resp.Header.Peek("X-Content-Type-Options")); got != "nosniff" {
+			t.Errorf("%s: X-Content-Type-Options = %q, want nosniff", tt.name, got)
+		}
+		if got := string(resp.Header.Peek("Content-Security-Policy")); got != "sandbox" {
+			t.Errorf("%s: Content-Security-Policy = %q, want sandbox", tt.name, got)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/HildaM/logs/slog"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/utils"
+	"github.com/cloudwego/hertz/pkg/protocol/sse"
+	"github.com/google/uuid"
+
+	"github.com/hildam/deer-flow-go/agent"
+	"github.com/hildam/deer-flow-go/biz/service"
+	"github.com/hildam/deer-flow-go/entity/model"
+	"github.com/hildam/deer-flow-go/repo/callback"
+	"github.com/hildam/deer-flow-go/repo/template"
+	"github.com/hildam/deer-flow-go/repo/workflow"
+)
+
+// ChatStream 流式对话接口
+// 以SSE的形式推送整个研究流程的输出，遇到计划确认中断时推送interrupt事件并结束本次请求，
+// 客户端携带相同的thread_id和interrupt_feedback再次请求即可从检查点恢复
+func ChatStream(ctx context.Context, c *app.RequestContext) {
+	var req model.ChatRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		slog.Error("ChatStream failed, bind request err = %v", err)
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := template.ValidateVariants(req.PromptVariants); err != nil {
+		slog.Error("ChatStream failed, validate prompt variants err = %v", err)
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := workflow.Check(req.Workflow); req.Workflow != "" && err != nil {
+		slog.Error("ChatStream failed, check workflow err = %v", err)
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.ThreadID == "" || req.ThreadID == "__default__" {
+		req.ThreadID = uuid.New().String()
+	}
+	// 写入响应头之前占用线程，同一线程的并发请求中只有一个能开始运行，其余返回 409
+	ctx, release, err := service.Claim(ctx, req.ThreadID)
+	if err != nil {
+		slog.Error("ChatStream failed, claim thread err = %v", err)
+		c.String(http.StatusConflict, err.Error())
+		return
+	}
+	defer release()
+
+	// 设置SSE响应头
+	c.SetStatusCode(http.StatusOK)
+	c.Response.Header.Set("Content-Type", "text/event-stream")
+	c.Response.Header.Set("Cache-Control", "no-cache")
+	c.Response.Header.Set("Connection", "keep-alive")
+	w := sse.NewWriter(c)
+	defer w.Close()
+
+	logger := &callback.LoggerCallback{ID: req.ThreadID, SSE: w}
+	rec, err := service.Run(ctx, &service.RunRequest{
+		ThreadID:          req.ThreadID,
+		Messages:          req.Messages,
+		InterruptFeedback: req.InterruptFeedback,
+		Workflow:          req.Workflow,
+		StateOptions:      []agent.StateOption{requestStateOption(&req)},
+		Logger:            logger,
+	})
+	if err != nil {
+		slog.Error("ChatStream failed, run err = %v, thread_id = %s", err, req.ThreadID)
+	}
+	// 运行开始前失败时没有运行记录，也没有推送 run_completed，响应头已写入，以事件告知客户端
+	if rec == nil && err != nil {
+		logger.Emit(ctx, &model.Event{Type: model.EventRunCompleted, Status: model.RunFailed, Error: err.Error()})
+	}
+}
+
+// CancelChat 取消线程正在执行的运行
+// 支持参数：partial_report 为 true 时以已完成的步骤生成部分报告，以 report 事件推送并保存到运行历史
+func CancelChat(ctx context.Context, c *app.RequestContext) {
+	threadID := c.Param("thread_id")
+	partial, _ := strconv.ParseBool(c.Query("partial_report"))
+	if !service.Cancel(threadID, partial) {
+		c.JSON(http.StatusNotFound, utils.H{"error": "thread is not running"})
+		return
+	}
+	c.JSON(http.StatusAccepted, utils.H{"thread_id": threadID})
+}
+
+// requestStateOption 使用请求中的运行参数初始化状态
+func requestStateOption(req *model.ChatRequest) agent.StateOption {
+	return func(state *model.State) {
+		if req.MaxPlanIterations > 0 {
+			state.MaxPlanIterations = req.MaxPlanIterations
+		}
+		if req.MaxStepNum > 0 {
+			state.MaxStepNum = req.MaxStepNum
+		}
+		state.AutoAcceptedPlan = req.AutoAcceptedPlan
+		state.EnableBackgroundInvestigation = req.EnableBackgroundInvestigation
+		if req.StructuredReport {
+			state.StructuredReport = true
+		}
+		state.PromptVariants = req.PromptVariants
+	}
+}
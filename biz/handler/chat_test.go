@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/common/config"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+	"github.com/cloudwego/hertz/pkg/route"
+
+	"github.com/hildam/deer-flow-go/biz/service"
+)
+
+// TestChatStreamConflict 线程已被占用时在写入 SSE 响应头之前返回 409
+func TestChatStreamConflict(t *testing.T) {
+	const threadID = "busy-thread"
+	_, release, err := service.Claim(context.Background(), threadID)
+	if err != nil {
+		t.Fatalf("Claim: %v", err)
+	}
+	defer release()
+
+	r := route.NewEngine(config.NewOptions(nil))
+	r.POST("/api/chat/stream", ChatStream)
+	body := `{"thread_id":"` + threadID + `","messages":[{"role":"user","content":"hello"}]}`
+	resp := ut.PerformRequest(r, "POST", "/api/chat/stream",
+		&ut.Body{Body: strings.NewReader(body), Len: len(body)},
+		ut.Header{Key: "Content-Type", Value: "application/json"}).Result()
+	if resp.StatusCode() != http.StatusConflict {
+		t.Errorf("status = %d, want %d", resp.StatusCode(), http.StatusConflict)
+	}
+	if got := string(resp.Header.Peek("Content-Type")); got == "text/event-stream" {
+		t.Errorf("Content-Type = %q, want no event stream", got)
+	}
+}
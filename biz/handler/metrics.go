@@ -0,0 +1,9 @@
+package handler
+
+import (
+	"github.com/cloudwego/hertz/pkg/common/adaptor"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics Prometheus 指标接口
+var Metrics = adaptor.HertzHandler(promhttp.Handler())
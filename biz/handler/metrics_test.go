@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/cloudwego/hertz/pkg/app/server"
+
+	"github.com/hildam/deer-flow-go/agent"
+	"github.com/hildam/deer-flow-go/agent/agenttest"
+	"github.com/hildam/deer-flow-go/biz/service"
+	"github.com/hildam/deer-flow-go/entity/conf"
+	"github.com/hildam/deer-flow-go/entity/model"
+	"github.com/hildam/deer-flow-go/repo/callback"
+	"github.com/hildam/deer-flow-go/repo/mcp"
+	"github.com/hildam/deer-flow-go/repo/replay"
+)
+
+var testdata = agenttest.Testdata // 录制文件目录
+
+func TestMain(m *testing.M) {
+	// 模型请求的指标以模型名称为标签
+	cfg := agenttest.Config()
+	cfg.Model.DefaultModel.ModelID = "gpt-4o"
+	conf.SetCfg(cfg)
+	os.Exit(m.Run())
+}
+
+// TestMetrics 一次运行后 /metrics 包含运行、各智能体耗时、模型请求和工具调用的指标
+func TestMetrics(t *testing.T) {
+	if err := replay.Start(replay.ModeReplay, filepath.Join(testdata, "research.json")); err != nil {
+		t.Fatalf("start replay: %v", err)
+	}
+	defer replay.Close()
+
+	const threadID = "metrics-run"
+	ctx := context.Background()
+	_, err := service.Run(ctx, &service.RunRequest{
+		ThreadID: threadID,
+		Messages: []*schema.Message{schema.UserMessage("How widely are Go generics adopted?")},
+		StateOptions: []agent.StateOption{func(state *model.State) {
+			state.AutoAcceptedPlan = true
+		}},
+		Logger: &callback.LoggerCallback{ID: threadID},
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	// 回放的工具不经过 MCP 层，调用一次内置工具产生工具指标
+	_, _ = (&mcp.ListArtifactsTool{}).InvokableRun(mcp.WithSession(ctx, threadID), "{}")
+
+	body := scrape(t)
+	for _, series := range []string{
+		`deer_flow_runs_started_total`,
+		`deer_flow_runs_finished_total{status="completed",terminal_agent="reporter"}`,
+		`deer_flow_agent_duration_seconds_count{agent="planner"}`,
+		`deer_flow_agent_duration_seconds_count{agent="researcher"}`,
+		`deer_flow_llm_requests_total{model="gpt-4o"}`,
+		`deer_flow_mcp_tool_calls_total{server="builtin",tool="list_artifacts"}`,
+		`deer_flow_mcp_tool_duration_seconds_count{server="builtin",tool="list_artifacts"}`,
+		`deer_flow_checkpoint_entries`,
+		`deer_flow_interrupted_threads`,
+	} {
+		if !strings.Contains(body, series) {
+			t.Errorf("/metrics missing %s", series)
+		}
+	}
+}
+
+// scrape 启动 HTTP 服务并请求 /metrics，返回响应内容
+// 指标内容较大时以 chunked 方式写出，需要真实的连接，不能使用 ut.PerformRequest
+func scrape(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close()
+
+	h := server.New(server.WithHostPorts(addr), server.WithExitWaitTime(0))
+	h.GET("/metrics", Metrics)
+	go func() {
+		_ = h.Run()
+	}()
+	defer func() {
+		_ = h.Shutdown(context.Background())
+	}()
+
+	var resp *http.Response
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+		if resp, err = http.Get("http://" + addr + "/metrics"); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		t.Fatalf("GET /metrics: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /metrics status = %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read /metrics: %v", err)
+	}
+	return string(body)
+}
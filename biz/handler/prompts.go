@@ -0,0 +1,23 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/HildaM/logs/slog"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/utils"
+
+	"github.com/hildam/deer-flow-go/repo/template"
+)
+
+// ListPrompts 列出已加载的提示词模板，包含变体、语言、版本、来源和引用的未定义变量
+func ListPrompts(ctx context.Context, c *app.RequestContext) {
+	r, err := template.Default()
+	if err != nil {
+		slog.Error("ListPrompts failed, err = %v", err)
+		c.JSON(http.StatusInternalServerError, utils.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, utils.H{"prompts": r.List()})
+}
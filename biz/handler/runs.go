@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/HildaM/logs/slog"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/utils"
+
+	"github.com/hildam/deer-flow-go/biz/service"
+	"github.com/hildam/deer-flow-go/repo/artifact"
+	"github.com/hildam/deer-flow-go/repo/export"
+	"github.com/hildam/deer-flow-go/repo/history"
+)
+
+// ListRuns 查询运行历史
+// 支持参数：q 关键词，status 运行状态，since/until 开始时间范围（RFC3339），offset/limit 分页
+func ListRuns(ctx context.Context, c *app.RequestContext) {
+	opt, err := parseListOption(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{"error": err.Error()})
+		return
+	}
+
+	records, err := history.List(opt)
+	if err != nil {
+		slog.Error("ListRuns failed, err = %v", err)
+		c.JSON(http.StatusInternalServerError, utils.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, utils.H{"runs": records})
+}
+
+// GetRun 获取单次运行的完整记录
+func GetRun(ctx context.Context, c *app.RequestContext) {
+	rec, err := history.Get(c.Param("thread_id"))
+	if errors.Is(err, history.ErrNotFound) {
+		c.JSON(http.StatusNotFound, utils.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		slog.Error("GetRun failed, err = %v", err)
+		c.JSON(http.StatusInternalServerError, utils.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, rec)
+}
+
+// DeleteRun 删除运行记录，同时丢弃运行保留的检查点，线程有运行在执行时返回 409
+func DeleteRun(ctx context.Context, c *app.RequestContext) {
+	threadID := c.Param("thread_id")
+	if err := service.Discard(threadID); err != nil {
+		c.JSON(http.StatusConflict, utils.H{"error": err.Error()})
+		return
+	}
+	err := history.Delete(threadID)
+	if errors.Is(err, history.ErrNotFound) {
+		c.JSON(http.StatusNotFound, utils.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		slog.Error("DeleteRun failed, err = %v", err)
+		c.JSON(http.StatusInternalServerError, utils.H{"error": err.Error()})
+		return
+	}
+	// 产物删除失败不影响运行记录的删除
+	if err := artifact.DeleteRun(ctx, threadID); err != nil {
+		slog.Error("DeleteRun failed, delete artifacts err = %v, thread_id = %s", err, threadID)
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ExportRun 导出运行的最终报告
+// 支持参数：format 导出格式（html/pdf/docx/json/md，默认 html），download 为 true 时作为附件下载
+func ExportRun(ctx context.Context, c *app.RequestContext) {
+	rec, err := history.Get(c.Param("thread_id"))
+	if errors.Is(err, history.ErrNotFound) {
+		c.JSON(http.StatusNotFound, utils.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		slog.Error("ExportRun failed, get run err = %v", err)
+		c.JSON(http.StatusInternalServerError, utils.H{"error": err.Error()})
+		return
+	}
+
+	// 报告中的产物链接转换为当前服务的绝对地址
+	opt := export.Options{
+		Format:  c.DefaultQuery("format", export.FormatHTML),
+		BaseURL: string(c.URI().Scheme()) + "://" + string(c.Host()),
+	}
+	var buf bytes.Buffer
+	err = export.Export(ctx, rec, opt, &buf)
+	switch {
+	case errors.Is(err, export.ErrUnsupportedFormat):
+		c.JSON(http.StatusBadRequest, utils.H{"error": err.Error()})
+		return
+	case errors.Is(err, export.ErrNoReport):
+		c.JSON(http.StatusConflict, utils.H{"error": err.Error()})
+		return
+	case err != nil:
+		slog.Error("ExportRun failed, export err = %v, thread_id = %s", err, rec.ID)
+		c.JSON(http.StatusInternalServerError, utils.H{"error": err.Error()})
+		return
+	}
+
+	disposition := "inline"
+	if opt.Format == export.FormatDOCX || c.Query("download") == "true" {
+		disposition = "attachment"
+	}
+	c.Header("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": export.FileName(rec.ID, opt.Format)}))
+	c.Data(http.StatusOK, export.ContentType(opt.Format), buf.Bytes())
+}
+
+// parseListOption 解析查询参数
+func parseListOption(c *app.RequestContext) (history.ListOption, error) {
+	opt := history.ListOption{
+		Keyword: c.Query("q"),
+		Status:  c.Query("status"),
+		Limit:   20,
+	}
+	var err error
+	if v := c.Query("since"); v != "" {
+		if opt.Since, err = time.Parse(time.RFC3339, v); err != nil {
+			return opt, err
+		}
+	}
+	if v := c.Query("until"); v != "" {
+		if opt.Until, err = time.Parse(time.RFC3339, v); err != nil {
+			return opt, err
+		}
+	}
+	if v := c.Query("offset"); v != "" {
+		if opt.Offset, err = strconv.Atoi(v); err != nil {
+			return opt, err
+		}
+	}
+	if v := c.Query("limit"); v != "" {
+		if opt.Limit, err = strconv.Atoi(v); err != nil {
+			return opt, err
+		}
+	}
+	if opt.Offset < 0 || opt.Limit < 0 {
+		return opt, errors.New("offset and limit must not be negative")
+	}
+	return opt, nil
+}
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/HildaM/logs/slog"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/utils"
+
+	"github.com/hildam/deer-flow-go/entity/conf"
+	"github.com/hildam/deer-flow-go/repo/workflow"
+)
+
+// ListWorkflows 列出已加载的工作流
+func ListWorkflows(ctx context.Context, c *app.RequestContext) {
+	workflows, err := workflow.List()
+	if err != nil {
+		slog.Error("ListWorkflows failed, err = %v", err)
+		c.JSON(http.StatusInternalServerError, utils.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, utils.H{"workflows": workflows})
+}
+
+// GetWorkflow 获取工作流及其 Mermaid 流程图
+func GetWorkflow(ctx context.Context, c *app.RequestContext) {
+	name := c.Param("name")
+	wf, err := workflow.Get(name, conf.GetCfg().Agents)
+	if err != nil {
+		slog.Error("GetWorkflow failed, err = %v, name = %s", err, name)
+		c.JSON(http.StatusNotFound, utils.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, utils.H{"workflow": wf, "mermaid": wf.Mermaid()})
+}
+
+// GetTransitions 获取完整的流转表及其 Mermaid 流程图
+func GetTransitions(ctx context.Context, c *app.RequestContext) {
+	agents := conf.GetCfg().Agents
+	c.JSON(http.StatusOK, utils.H{
+		"transitions": workflow.Transitions(agents),
+		"mermaid":     workflow.TransitionsMermaid(agents),
+	})
+}
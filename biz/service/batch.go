@@ -0,0 +1,302 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/HildaM/logs/slog"
+	"github.com/cloudwego/eino/schema"
+	"github.com/google/uuid"
+
+	"github.com/hildam/deer-flow-go/agent"
+	"github.com/hildam/deer-flow-go/entity/model"
+	"github.com/hildam/deer-flow-go/repo/callback"
+)
+
+// BatchItem 批量研究中的单个问题
+type BatchItem struct {
+	ID     string `json:"id,omitempty"`     // 问题标识，作为输出文件名，为空时根据问题内容生成
+	Query  string `json:"query"`            // 研究问题
+	Locale string `json:"locale,omitempty"` // 报告语言，为空时自动检测
+}
+
+// BatchResult 单个问题的运行结果，与报告一同写入输出目录
+type BatchResult struct {
+	Item   *BatchItem       `json:"item"`
+	Record *model.RunRecord `json:"record,omitempty"`
+	Error  string           `json:"error,omitempty"`
+}
+
+// Completed 问题是否已完成，报告写入失败同样视为未完成
+func (r *BatchResult) Completed() bool {
+	return r.Error == "" && r.Record != nil && r.Record.Status == model.RunCompleted
+}
+
+// BatchOption 批量运行参数
+type BatchOption struct {
+	Workers      int                                   // 并发数
+	OutputDir    string                                // 输出目录
+	Workflow     string                                // 使用的工作流，为空时使用默认工作流
+	StateOptions []agent.StateOption                   // 每次运行的初始状态定制
+	OnStart      func(item *BatchItem)                 // 单个问题开始运行时回调
+	OnDone       func(item *BatchItem, r *BatchResult) // 单个问题运行结束时回调
+}
+
+// BatchSummary 批量运行汇总
+type BatchSummary struct {
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+	Skipped   int `json:"skipped"` // 之前已完成而跳过的问题
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^\w.-]+`)
+
+// LoadBatchItems 读取问题列表，支持 JSONL 和带表头的 CSV（按扩展名区分）
+// CSV 需包含 query 列，id 和 locale 列可选
+func LoadBatchItems(path string) ([]*BatchItem, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("LoadBatchItems failed, open err: %w", err)
+	}
+	defer f.Close()
+
+	var items []*BatchItem
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		items, err = readCSVItems(f)
+	} else {
+		items, err = readJSONLItems(f)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("LoadBatchItems failed, %w", err)
+	}
+
+	// 补全并校验问题标识，标识用作文件名且必须唯一
+	seen := make(map[string]bool, len(items))
+	for _, item := range items {
+		if item.ID == "" {
+			sum := sha1.Sum([]byte(item.Query))
+			item.ID = hex.EncodeToString(sum[:])[:12]
+		}
+		item.ID = unsafeFileChars.ReplaceAllString(item.ID, "_")
+		if seen[item.ID] {
+			return nil, fmt.Errorf("LoadBatchItems failed, duplicate id: %s", item.ID)
+		}
+		seen[item.ID] = true
+	}
+	return items, nil
+}
+
+// readJSONLItems 逐行解析 JSONL，忽略空行
+func readJSONLItems(r io.Reader) ([]*BatchItem, error) {
+	var items []*BatchItem
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		item := &BatchItem{}
+		if err := json.Unmarshal([]byte(text), item); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if strings.TrimSpace(item.Query) == "" {
+			return nil, fmt.Errorf("line %d: empty query", line)
+		}
+		items = append(items, item)
+	}
+	return items, scanner.Err()
+}
+
+// readCSVItems 按表头解析 CSV
+func readCSVItems(r io.Reader) ([]*BatchItem, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	cols := map[string]int{}
+	for i, name := range rows[0] {
+		cols[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	queryCol, ok := cols["query"]
+	if !ok {
+		return nil, errors.New("csv header has no query column")
+	}
+	field := func(row []string, name string) string {
+		if i, ok := cols[name]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	var items []*BatchItem
+	for i, row := range rows[1:] {
+		if queryCol >= len(row) || strings.TrimSpace(row[queryCol]) == "" {
+			return nil, fmt.Errorf("row %d: empty query", i+2)
+		}
+		items = append(items, &BatchItem{
+			ID:     field(row, "id"),
+			Query:  strings.TrimSpace(row[queryCol]),
+			Locale: field(row, "locale"),
+		})
+	}
+	return items, nil
+}
+
+// RunBatch 使用固定大小的协程池依次运行全部问题
+// 每个问题的报告写入 <id>.md，运行记录写入 <id>.json，运行过程写入 <id>.log；
+// 已有完成记录的问题直接跳过，因此中断后重新执行即可继续。ctx 取消后不再开始新的问题
+func RunBatch(ctx context.Context, items []*BatchItem, opt BatchOption) (*BatchSummary, error) {
+	if err := os.MkdirAll(opt.OutputDir, 0o755); err != nil {
+		return nil, fmt.Errorf("RunBatch failed, mkdir err: %w", err)
+	}
+	workers := opt.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+
+	summary := &BatchSummary{Total: len(items)}
+	var mu sync.Mutex
+	pending := make(chan *BatchItem)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range pending {
+				if opt.OnStart != nil {
+					opt.OnStart(item)
+				}
+				res := runBatchItem(ctx, item, opt)
+
+				mu.Lock()
+				if res.Completed() {
+					summary.Completed++
+				} else {
+					summary.Failed++
+				}
+				mu.Unlock()
+				if opt.OnDone != nil {
+					opt.OnDone(item, res)
+				}
+			}
+		}()
+	}
+
+dispatch:
+	for _, item := range items {
+		if completed(opt.OutputDir, item.ID) {
+			mu.Lock()
+			summary.Skipped++
+			mu.Unlock()
+			continue
+		}
+		select {
+		case pending <- item:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(pending)
+	wg.Wait()
+	return summary, ctx.Err()
+}
+
+// runBatchItem 运行单个问题并写入结果
+func runBatchItem(ctx context.Context, item *BatchItem, opt BatchOption) *BatchResult {
+	res := &BatchResult{Item: item}
+	base := filepath.Join(opt.OutputDir, item.ID)
+
+	// 运行过程写入日志文件
+	logFile, err := os.Create(base + ".log")
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	defer logFile.Close()
+	outChan := make(chan string)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for out := range outChan {
+			_, _ = logFile.WriteString(out)
+		}
+	}()
+
+	opts := append([]agent.StateOption{}, opt.StateOptions...)
+	opts = append(opts, func(state *model.State) {
+		// 批量运行无人值守，计划自动接受
+		state.AutoAcceptedPlan = true
+		if item.Locale != "" {
+			state.Locale = item.Locale
+		}
+	})
+	threadID := uuid.New().String()
+	rec, err := Run(ctx, &RunRequest{
+		ThreadID:     threadID,
+		Messages:     []*schema.Message{schema.UserMessage(item.Query)},
+		Workflow:     opt.Workflow,
+		StateOptions: opts,
+		Logger:       &callback.LoggerCallback{ID: threadID, Out: outChan},
+	})
+	close(outChan)
+	<-done
+
+	res.Record = rec
+	switch {
+	case err != nil:
+		res.Error = err.Error()
+	case rec.Status != model.RunCompleted:
+		res.Error = fmt.Sprintf("run %s", rec.Status)
+	}
+	if rec != nil && rec.Report != "" {
+		if err := writeFileAtomic(base+".md", []byte(rec.Report)); err != nil {
+			slog.Error("runBatchItem failed, write report err = %v, id = %s", err, item.ID)
+			res.Error = err.Error()
+		}
+	}
+	// 运行记录最后写入，作为完成标记
+	data, _ := json.MarshalIndent(res, "", "  ")
+	if err := writeFileAtomic(base+".json", data); err != nil {
+		slog.Error("runBatchItem failed, write result err = %v, id = %s", err, item.ID)
+	}
+	return res
+}
+
+// completed 判断问题是否已有完成的运行记录
+func completed(dir, id string) bool {
+	data, err := os.ReadFile(filepath.Join(dir, id+".json"))
+	if err != nil {
+		return false
+	}
+	res := &BatchResult{}
+	if err := json.Unmarshal(data, res); err != nil {
+		return false
+	}
+	return res.Completed()
+}
+
+// writeFileAtomic 先写临时文件再重命名，避免崩溃时留下不完整的文件
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
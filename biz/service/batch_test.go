@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hildam/deer-flow-go/agent/agenttest"
+	"github.com/hildam/deer-flow-go/entity/conf"
+	"github.com/hildam/deer-flow-go/entity/consts"
+	"github.com/hildam/deer-flow-go/repo/checkpoint"
+	"github.com/hildam/deer-flow-go/repo/replay"
+)
+
+var testdata = agenttest.Testdata // 录制文件目录
+
+func TestMain(m *testing.M) {
+	conf.SetCfg(agenttest.Config())
+	os.Exit(m.Run())
+}
+
+// TestRunBatchConcurrent 并发运行多个问题，再次运行时跳过已完成的问题，需配合 -race 运行
+func TestRunBatchConcurrent(t *testing.T) {
+	const n = 6
+	fixture, err := replay.LoadFixture(filepath.Join(testdata, "greeting.json"))
+	if err != nil {
+		t.Fatalf("load fixture: %v", err)
+	}
+	// 每个问题各消耗一次相同的模型响应
+	greeting := fixture.Chats[0]
+	fixture.Chats = nil
+	items := make([]*BatchItem, 0, n)
+	for i := 0; i < n; i++ {
+		fixture.Chats = append(fixture.Chats, greeting)
+		items = append(items, &BatchItem{ID: fmt.Sprintf("q%d", i), Query: fmt.Sprintf("hello #%d", i)})
+	}
+	path := filepath.Join(t.TempDir(), "greeting.json")
+	if err := fixture.Save(path); err != nil {
+		t.Fatalf("save fixture: %v", err)
+	}
+	if err := replay.Start(replay.ModeReplay, path); err != nil {
+		t.Fatalf("start replay: %v", err)
+	}
+	defer replay.Close()
+
+	dir := t.TempDir()
+	summary, err := RunBatch(context.Background(), items, BatchOption{Workers: 3, OutputDir: dir})
+	if err != nil {
+		t.Fatalf("RunBatch: %v", err)
+	}
+	if summary.Completed != n || summary.Failed != 0 {
+		t.Fatalf("summary = %+v, want %d completed", summary, n)
+	}
+	for _, item := range items {
+		if !completed(dir, item.ID) {
+			t.Errorf("item %s: no completed result", item.ID)
+		}
+	}
+	// 运行记录保存执行路径，问候只经过 Coordinator
+	data, err := os.ReadFile(filepath.Join(dir, "q0.json"))
+	if err != nil {
+		t.Fatalf("read result: %v", err)
+	}
+	res := &BatchResult{}
+	if err := json.Unmarshal(data, res); err != nil {
+		t.Fatalf("unmarshal result: %v", err)
+	}
+	if path := res.Record.Path; len(path) != 1 || path[0].Node != consts.Coordinator || path[0].StartedAt.IsZero() {
+		t.Errorf("path = %+v, want [coordinator]", path)
+	}
+	// 运行正常结束后释放检查点
+	if entries, _ := checkpoint.Size(); entries != 0 {
+		t.Errorf("checkpoint entries = %d, want 0", entries)
+	}
+
+	summary, err = RunBatch(context.Background(), items, BatchOption{Workers: 3, OutputDir: dir})
+	if err != nil {
+		t.Fatalf("RunBatch resume: %v", err)
+	}
+	if summary.Skipped != n || summary.Completed != 0 {
+		t.Errorf("resume summary = %+v, want %d skipped", summary, n)
+	}
+}
+
+func TestLoadBatchItems(t *testing.T) {
+	dir := t.TempDir()
+	jsonl := filepath.Join(dir, "q.jsonl")
+	_ = os.WriteFile(jsonl, []byte(`{"id":"a/b","query":"first"}`+"\n\n"+`{"query":"second","locale":"en-US"}`+"\n"), 0o644)
+	items, err := LoadBatchItems(jsonl)
+	if err != nil {
+		t.Fatalf("LoadBatchItems jsonl: %v", err)
+	}
+	if len(items) != 2 || items[0].ID != "a_b" || items[1].ID == "" || items[1].Locale != "en-US" {
+		t.Errorf("jsonl items = %+v", items)
+	}
+
+	csvPath := filepath.Join(dir, "q.csv")
+	_ = os.WriteFile(csvPath, []byte("query,id\nfirst,x\nsecond,x\n"), 0o644)
+	if _, err := LoadBatchItems(csvPath); err == nil {
+		t.Error("LoadBatchItems csv with duplicate ids: want error")
+	}
+}
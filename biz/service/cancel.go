@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/hildam/deer-flow-go/entity/model"
+	"github.com/hildam/deer-flow-go/repo/checkpoint"
+)
+
+var (
+	// ErrCanceled 运行被取消接口或控制台 Ctrl-C 取消
+	ErrCanceled = errors.New("run canceled")
+	// ErrRunning 同一线程已有运行在执行
+	ErrRunning = errors.New("thread is already running")
+)
+
+// cancelEntry 运行中线程的取消函数及取消参数
+type cancelEntry struct {
+	cancel        context.CancelCauseFunc
+	partialReport bool // 取消后是否以已完成的步骤生成部分报告
+}
+
+// registry 运行中线程的取消注册表，以线程ID索引
+var registry = struct {
+	mu   sync.Mutex
+	runs map[string]*cancelEntry
+}{runs: make(map[string]*cancelEntry)}
+
+// claimKey 上下文中存放已占用线程ID的 key
+type claimKey struct{}
+
+// Claim 占用线程，供需要在运行前确认线程可用的调用方使用，如写入 SSE 响应头之前的接口
+// 返回的上下文可被 Cancel 取消，传给 Run 时不再重复注册；同一线程已有运行时返回 ErrRunning
+// 运行结束后调用 release 释放线程
+func Claim(ctx context.Context, threadID string) (runCtx context.Context, release func(), err error) {
+	return register(ctx, threadID)
+}
+
+// claimed 上下文是否已由 Claim 占用了线程
+func claimed(ctx context.Context, threadID string) bool {
+	id, ok := ctx.Value(claimKey{}).(string)
+	return ok && id == threadID
+}
+
+// register 注册运行中的线程，返回可被 Cancel 取消的上下文，运行结束后调用 release 注销
+func register(ctx context.Context, threadID string) (runCtx context.Context, release func(), err error) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	if _, ok := registry.runs[threadID]; ok {
+		return nil, nil, fmt.Errorf("%w: %s", ErrRunning, threadID)
+	}
+	runCtx, cancel := context.WithCancelCause(ctx)
+	runCtx = context.WithValue(runCtx, claimKey{}, threadID)
+	entry := &cancelEntry{cancel: cancel}
+	registry.runs[threadID] = entry
+	return runCtx, func() {
+		registry.mu.Lock()
+		defer registry.mu.Unlock()
+		if registry.runs[threadID] == entry {
+			delete(registry.runs, threadID)
+		}
+		cancel(nil)
+	}, nil
+}
+
+// Running 线程是否有运行在执行
+func Running(threadID string) bool {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	_, ok := registry.runs[threadID]
+	return ok
+}
+
+// Cancel 取消线程正在执行的运行，partialReport 为 true 时以已完成的步骤生成部分报告
+// 线程没有运行在执行时返回 false
+func Cancel(threadID string, partialReport bool) bool {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	entry, ok := registry.runs[threadID]
+	if !ok {
+		return false
+	}
+	entry.partialReport = entry.partialReport || partialReport
+	entry.cancel(ErrCanceled)
+	return true
+}
+
+// Discard 丢弃线程在中断或取消时保留的检查点，之后以相同线程ID请求时重新开始运行
+// 线程有运行在执行时返回 ErrRunning
+func Discard(threadID string) error {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	if _, ok := registry.runs[threadID]; ok {
+		return fmt.Errorf("%w: %s", ErrRunning, threadID)
+	}
+	checkpoint.Delete(threadID)
+	return nil
+}
+
+// wantPartialReport 取消时是否要求生成部分报告
+func wantPartialReport(threadID string) bool {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	entry, ok := registry.runs[threadID]
+	return ok && entry.partialReport
+}
+
+// resumeState 返回取消时写入检查点的状态，执行中的步骤恢复为等待执行，恢复运行时重新执行
+// 计划为副本，记录中的计划仍由 abortSteps 将执行中的步骤标记为失败
+func resumeState(state *model.State) *model.State {
+	st := *state
+	st.InterruptFeedback = ""
+	if state.CurrentPlan != nil {
+		plan := *state.CurrentPlan
+		plan.Steps = slices.Clone(plan.Steps)
+		for i := range plan.Steps {
+			if step := &plan.Steps[i]; step.Status == model.StepRunning {
+				step.Status = model.StepPending
+				step.StartedAt = nil
+			}
+		}
+		st.CurrentPlan = &plan
+	}
+	return &st
+}
+
+// partialReport 以执行成功步骤的结果生成部分报告，没有执行成功的步骤时返回空
+// 失败和跳过的步骤连同原因列在未完成的步骤中
+func partialReport(plan *model.Plan, locale string) string {
+	if plan == nil {
+		return ""
+	}
+	text := partialText["en-US"]
+	if strings.HasPrefix(locale, "zh") {
+		text = partialText["zh-CN"]
+	}
+
+	var done, pending strings.Builder
+	for _, step := range plan.Steps {
+		switch step.Status {
+		case model.StepSucceeded:
+			res := ""
+			if step.ExecutionRes != nil {
+				res = strings.TrimSpace(*step.ExecutionRes)
+			}
+			fmt.Fprintf(&done, "## %s\n\n%s\n\n", step.Title, res)
+		case model.StepFailed:
+			fmt.Fprintf(&pending, "- %s (%s: %s)\n", step.Title, text.failed, step.Error)
+		case model.StepSkipped:
+			fmt.Fprintf(&pending, "- %s (%s: %s)\n", step.Title, text.skipped, step.Error)
+		default:
+			fmt.Fprintf(&pending, "- %s\n", step.Title)
+		}
+	}
+	if done.Len() == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n> %s\n\n", plan.Title, text.note)
+	b.WriteString(done.String())
+	if pending.Len() > 0 {
+		fmt.Fprintf(&b, "## %s\n\n%s", text.pending, pending.String())
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// partialText 部分报告中的提示文字，按语言区分
+var partialText = map[string]struct{ note, pending, failed, skipped string }{
+	"en-US": {note: "The research was canceled. This partial report contains the results of the completed steps only.", pending: "Unfinished Steps", failed: "failed", skipped: "skipped"},
+	"zh-CN": {note: "研究已取消，本报告仅包含已完成步骤的结果。", pending: "未完成的步骤", failed: "失败", skipped: "跳过"},
+}
@@ -0,0 +1,289 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+
+	"github.com/hildam/deer-flow-go/agent"
+	"github.com/hildam/deer-flow-go/entity/consts"
+	"github.com/hildam/deer-flow-go/entity/model"
+	"github.com/hildam/deer-flow-go/repo/callback"
+	"github.com/hildam/deer-flow-go/repo/checkpoint"
+	"github.com/hildam/deer-flow-go/repo/replay"
+)
+
+// TestRunCancel 步骤完成后取消运行，记录为已取消并以完成的步骤生成部分报告，恢复后跳过已完成的步骤
+func TestRunCancel(t *testing.T) {
+	if err := replay.Start(replay.ModeReplay, filepath.Join(testdata, "research.json")); err != nil {
+		t.Fatalf("start replay: %v", err)
+	}
+	defer replay.Close()
+
+	const threadID = "cancel-run"
+	defer checkpoint.Delete(threadID)
+	outChan := make(chan string)
+	done := make(chan struct{})
+	var sb strings.Builder
+	go func() {
+		defer close(done)
+		canceled := false
+		for out := range outChan {
+			sb.WriteString(out)
+			// 输出通道无缓冲，取消完成前运行阻塞在下一次输出上，不会进入下一个智能体
+			if !canceled && strings.Contains(out, string(model.EventStepCompleted)) {
+				canceled = Cancel(threadID, true)
+				if !canceled {
+					t.Errorf("Cancel(%s) = false, want true for a running thread", threadID)
+				}
+			}
+		}
+	}()
+
+	rec, err := Run(context.Background(), &RunRequest{
+		ThreadID: threadID,
+		Messages: []*schema.Message{schema.UserMessage("How widely are Go generics adopted?")},
+		StateOptions: []agent.StateOption{func(state *model.State) {
+			state.AutoAcceptedPlan = true
+		}},
+		Logger: &callback.LoggerCallback{ID: threadID, Out: outChan},
+	})
+	close(outChan)
+	<-done
+
+	if !errors.Is(err, ErrCanceled) {
+		t.Fatalf("Run err = %v, want ErrCanceled", err)
+	}
+	if rec.Status != model.RunCancelled {
+		t.Errorf("status = %q, want %q", rec.Status, model.RunCancelled)
+	}
+	if rec.Plan == nil || rec.Plan.Steps[0].ExecutionRes == nil {
+		t.Fatalf("plan = %+v, want the completed step kept", rec.Plan)
+	}
+	want := "# Go generics adoption\n\n> The research was canceled. This partial report contains the results of the completed steps only.\n\n" +
+		"## " + rec.Plan.Steps[0].Title + "\n\nMost Go developers surveyed have used generics at least once."
+	if rec.Report != want {
+		t.Errorf("report = %q, want %q", rec.Report, want)
+	}
+	if Running(threadID) || Cancel(threadID, false) {
+		t.Errorf("thread %s still registered after Run returned", threadID)
+	}
+	out := sb.String()
+	report, completed := strings.Index(out, "[report]"), strings.Index(out, "[run_completed]")
+	if report < 0 || completed < report || !strings.Contains(out[report:], want) {
+		t.Errorf("output missing the partial report before run_completed:\n%s", out)
+	}
+
+	// 录制文件中剩下 Reporter 的调用，恢复后不再执行已完成的步骤
+	rec, err = Run(context.Background(), &RunRequest{
+		ThreadID:          threadID,
+		InterruptFeedback: consts.AcceptPlan,
+		Logger:            &callback.LoggerCallback{ID: threadID},
+	})
+	if err != nil || rec.Status != model.RunCompleted {
+		t.Fatalf("resume = %v, %v, want completed", rec, err)
+	}
+	if rec.Plan == nil || rec.Plan.Steps[0].Status != model.StepSucceeded || rec.Plan.Steps[0].Attempts != 1 {
+		t.Errorf("plan = %+v, want the step from before the cancel", rec.Plan)
+	}
+	if !strings.Contains(rec.Report, "Most Go developers surveyed have used generics at least once.") {
+		t.Errorf("report = %q, want the reporter's report", rec.Report)
+	}
+}
+
+// TestRunCancelResumeWorkflow 非默认工作流的运行取消后，以相同线程ID的普通请求恢复，沿用首次运行的工作流和问题
+func TestRunCancelResumeWorkflow(t *testing.T) {
+	fixture, err := replay.LoadFixture(filepath.Join(testdata, "research.json"))
+	if err != nil {
+		t.Fatalf("load fixture: %v", err)
+	}
+	// 快速回答跳过 Planner，去掉计划的模型调用
+	fixture.Chats = append(fixture.Chats[:1], fixture.Chats[2:]...)
+	path := filepath.Join(t.TempDir(), "research.json")
+	if err := fixture.Save(path); err != nil {
+		t.Fatalf("save fixture: %v", err)
+	}
+	if err := replay.Start(replay.ModeReplay, path); err != nil {
+		t.Fatalf("start replay: %v", err)
+	}
+	defer replay.Close()
+
+	const (
+		threadID = "cancel-quick-answer"
+		query    = "How widely are Go generics adopted?"
+	)
+	defer checkpoint.Delete(threadID)
+	outChan := make(chan string)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		canceled := false
+		for out := range outChan {
+			if !canceled && strings.Contains(out, string(model.EventStepCompleted)) {
+				canceled = Cancel(threadID, false)
+			}
+		}
+	}()
+	rec, err := Run(context.Background(), &RunRequest{
+		ThreadID: threadID,
+		Messages: []*schema.Message{schema.UserMessage(query)},
+		Workflow: "quick_answer",
+		Logger:   &callback.LoggerCallback{ID: threadID, Out: outChan},
+	})
+	close(outChan)
+	<-done
+	if !errors.Is(err, ErrCanceled) || rec.Workflow != "quick_answer" {
+		t.Fatalf("Run = %v, %v, want a cancelled quick_answer run", rec, err)
+	}
+	if wf, ok := checkpoint.Workflow(threadID); !ok || wf != "quick_answer" {
+		t.Fatalf("checkpoint workflow = %q, %v, want quick_answer", wf, ok)
+	}
+
+	// 不指定工作流和人工反馈，新的输入不替换记录的问题
+	rec, err = Run(context.Background(), &RunRequest{
+		ThreadID: threadID,
+		Messages: []*schema.Message{schema.UserMessage("Please continue.")},
+		Logger:   &callback.LoggerCallback{ID: threadID},
+	})
+	if err != nil || rec.Status != model.RunCompleted {
+		t.Fatalf("resume = %v, %v, want completed", rec, err)
+	}
+	if rec.Workflow != "quick_answer" || rec.Query != "" {
+		t.Errorf("workflow = %q, query = %q, want quick_answer and the query kept from the first run", rec.Workflow, rec.Query)
+	}
+	if slices.ContainsFunc(rec.Path, func(v model.NodeVisit) bool {
+		return v.Node == consts.ResearchTeam || v.Node == consts.Planner
+	}) {
+		t.Errorf("path = %v, want the quick_answer graph", rec.Path)
+	}
+	if rec.Plan == nil || rec.Plan.Steps[0].Attempts != 1 || !strings.HasPrefix(rec.Report, "# Go generics adoption") {
+		t.Errorf("plan = %+v, report = %q, want the step from before the cancel and the reporter's report", rec.Plan, rec.Report)
+	}
+}
+
+// TestDiscard 丢弃检查点后以相同线程ID请求时重新开始运行，执行中的线程不能丢弃
+func TestDiscard(t *testing.T) {
+	const threadID = "discard"
+	ctx := context.Background()
+	_ = checkpoint.NewCheckPoint().Set(ctx, threadID, []byte("state"))
+	defer checkpoint.Delete(threadID)
+
+	_, release, err := register(ctx, threadID)
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if err := Discard(threadID); !errors.Is(err, ErrRunning) {
+		t.Errorf("Discard running thread err = %v, want ErrRunning", err)
+	}
+	release()
+	if err := Discard(threadID); err != nil {
+		t.Fatalf("Discard: %v", err)
+	}
+	if _, ok := checkpoint.Workflow(threadID); ok {
+		t.Error("checkpoint kept after Discard")
+	}
+}
+
+// TestRunCancelKeepsCheckpoint 从计划确认恢复的运行被取消后仍有检查点，可再次恢复
+func TestRunCancelKeepsCheckpoint(t *testing.T) {
+	if err := replay.Start(replay.ModeReplay, filepath.Join(testdata, "research.json")); err != nil {
+		t.Fatalf("start replay: %v", err)
+	}
+	defer replay.Close()
+
+	const threadID = "cancel-resumed-run"
+	defer checkpoint.Delete(threadID)
+	rec, err := Run(context.Background(), &RunRequest{
+		ThreadID: threadID,
+		Messages: []*schema.Message{schema.UserMessage("How widely are Go generics adopted?")},
+		StateOptions: []agent.StateOption{func(state *model.State) {
+			state.AutoAcceptedPlan = false
+		}},
+		Logger: &callback.LoggerCallback{ID: threadID},
+	})
+	if err != nil || rec.Status != model.RunInterrupted {
+		t.Fatalf("Run = %v, %v, want interrupted", rec, err)
+	}
+
+	outChan := make(chan string)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		canceled := false
+		for out := range outChan {
+			if !canceled && strings.Contains(out, string(model.EventStepCompleted)) {
+				canceled = Cancel(threadID, false)
+			}
+		}
+	}()
+	rec, err = Run(context.Background(), &RunRequest{
+		ThreadID:          threadID,
+		InterruptFeedback: consts.AcceptPlan,
+		Logger:            &callback.LoggerCallback{ID: threadID, Out: outChan},
+	})
+	close(outChan)
+	<-done
+
+	if !errors.Is(err, ErrCanceled) || rec.Status != model.RunCancelled {
+		t.Fatalf("resume = %v, %v, want cancelled", rec.Status, err)
+	}
+	if _, ok, _ := checkpoint.NewCheckPoint().Get(context.Background(), threadID); !ok {
+		t.Errorf("checkpoint of %s deleted after cancel, want kept", threadID)
+	}
+}
+
+func TestRegisterRunning(t *testing.T) {
+	_, release, err := register(context.Background(), "dup")
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if _, _, err := register(context.Background(), "dup"); !errors.Is(err, ErrRunning) {
+		t.Errorf("register again err = %v, want ErrRunning", err)
+	}
+	release()
+	if _, release, err := register(context.Background(), "dup"); err != nil {
+		t.Errorf("register after release: %v", err)
+	} else {
+		release()
+	}
+}
+
+// TestClaim 占用线程后 Run 使用占用时的上下文运行，不再重复注册
+func TestClaim(t *testing.T) {
+	ctx, release, err := Claim(context.Background(), "claimed")
+	if err != nil {
+		t.Fatalf("Claim: %v", err)
+	}
+	defer release()
+	if !claimed(ctx, "claimed") || claimed(ctx, "other") || claimed(context.Background(), "claimed") {
+		t.Error("claimed does not match the claimed thread only")
+	}
+	if _, _, err := Claim(context.Background(), "claimed"); !errors.Is(err, ErrRunning) {
+		t.Errorf("Claim again err = %v, want ErrRunning", err)
+	}
+}
+
+func TestPartialReport(t *testing.T) {
+	res := "结果"
+	plan := &model.Plan{Title: "标题", Steps: []model.Step{
+		{Title: "步骤一", Status: model.StepSucceeded, ExecutionRes: &res},
+		{Title: "步骤二", Status: model.StepFailed, Error: "超时"},
+		{Title: "步骤三", Status: model.StepSkipped, Error: "没有处理该类型的智能体"},
+		{Title: "步骤四", Status: model.StepRunning},
+		{Title: "步骤五", Status: model.StepPending},
+	}}
+	want := "# 标题\n\n> 研究已取消，本报告仅包含已完成步骤的结果。\n\n## 步骤一\n\n结果\n\n## 未完成的步骤\n\n" +
+		"- 步骤二 (失败: 超时)\n- 步骤三 (跳过: 没有处理该类型的智能体)\n- 步骤四\n- 步骤五"
+	if got := partialReport(plan, "zh-CN"); got != want {
+		t.Errorf("partialReport = %q, want %q", got, want)
+	}
+	plan.Steps[0].Status = model.StepFailed
+	if got := partialReport(plan, "zh-CN"); got != "" {
+		t.Errorf("partialReport without succeeded steps = %q, want empty", got)
+	}
+}
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/HildaM/logs/slog"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+
+	"github.com/hildam/deer-flow-go/agent"
+	"github.com/hildam/deer-flow-go/agent/comm"
+	"github.com/hildam/deer-flow-go/entity/conf"
+	"github.com/hildam/deer-flow-go/entity/consts"
+	"github.com/hildam/deer-flow-go/entity/model"
+	"github.com/hildam/deer-flow-go/repo/callback"
+	"github.com/hildam/deer-flow-go/repo/checkpoint"
+	"github.com/hildam/deer-flow-go/repo/event"
+	"github.com/hildam/deer-flow-go/repo/history"
+	"github.com/hildam/deer-flow-go/repo/mcp"
+	"github.com/hildam/deer-flow-go/repo/metrics"
+	"github.com/hildam/deer-flow-go/repo/usage"
+)
+
+// RunRequest 单次研究运行的参数
+type RunRequest struct {
+	ThreadID          string                   // 线程ID，中断后使用相同的线程ID从检查点恢复
+	Messages          []*schema.Message        // 用户输入，从检查点恢复时除接受计划外追加到对话中
+	InterruptFeedback string                   // 人工反馈，从检查点恢复时写入状态
+	Workflow          string                   // 使用的工作流，为空时使用默认工作流，从检查点恢复时沿用首次运行的工作流
+	StateOptions      []agent.StateOption      // 初始状态定制
+	Logger            *callback.LoggerCallback // 输出回调，负责推送SSE和控制台输出
+}
+
+// Run 执行一次研究运行，结束后将问题、计划、报告和用量归档到运行历史
+// 等待人工反馈的中断不视为错误，通过返回记录的状态区分
+// 运行期间可通过 Cancel 取消，取消后返回 ErrCanceled，已完成的步骤保留在记录的计划和检查点中
+// 线程保留了中断或取消时的检查点时从检查点恢复，不需要恢复时先调用 Discard 丢弃检查点
+func Run(ctx context.Context, req *RunRequest) (*model.RunRecord, error) {
+	// 注册到取消注册表，同一线程同时只能有一个运行，调用方已通过 Claim 占用线程时不再注册
+	release := func() {}
+	if !claimed(ctx, req.ThreadID) {
+		var err error
+		if ctx, release, err = register(ctx, req.ThreadID); err != nil {
+			slog.Error("Run failed, register err = %v", err)
+			return nil, err
+		}
+	}
+	defer release()
+
+	// 记录本次运行使用的状态，新建运行时由 StateOption 获取，从检查点恢复时由 StateModifier 获取
+	var state *model.State
+	opts := append([]agent.StateOption{}, req.StateOptions...)
+	opts = append(opts, func(s *model.State) {
+		state = s
+	})
+
+	// 从检查点恢复时检查点中的状态属于首次运行的工作流，图结构需要保持一致
+	wf := req.Workflow
+	prevWf, resume := checkpoint.Workflow(req.ThreadID)
+	if resume {
+		if prevWf == "" {
+			if prev, err := history.Get(req.ThreadID); err == nil {
+				prevWf = prev.Workflow
+			}
+		}
+		if prevWf != "" {
+			wf = prevWf
+		}
+	}
+	graph, err := agent.BuildWorkflowGraph[string, string](ctx, wf, req.Messages, opts...)
+	if err != nil {
+		slog.Error("Run failed, BuildWorkflowGraph err = %v", err)
+		return nil, err
+	}
+
+	// 用量统计
+	setting := conf.GetCfg().Setting
+	tracker := usage.NewTracker(setting.MaxRunTokens, setting.MaxRunCost)
+	ctx = usage.WithTracker(ctx, tracker)
+	metricsCb := &callback.MetricsCallback{}
+	// 以线程ID作为有状态工具的会话ID，隔离并发运行的 Python 变量等数据
+	ctx = mcp.WithSession(ctx, req.ThreadID)
+	// 各智能体的 router 发出的工作流事件由输出回调推送
+	ctx = event.WithEmitter(ctx, req.Logger)
+
+	rec := &model.RunRecord{
+		ID:        req.ThreadID,
+		Status:    model.RunRunning,
+		StartedAt: time.Now(),
+	}
+	// 从检查点恢复时沿用首次运行记录的问题
+	if !resume {
+		rec.Query = comm.UserQuery(req.Messages)
+	}
+	metrics.RunStarted(req.ThreadID)
+
+	_, err = graph.Stream(ctx, consts.Coordinator,
+		compose.WithCheckPointID(req.ThreadID),
+		compose.WithStateModifier(func(ctx context.Context, path compose.NodePath, s any) error {
+			st, ok := s.(*model.State)
+			if !ok {
+				return nil
+			}
+			state = st
+			// 用量和预算按整个运行计算，接续中断前的用量
+			tracker.Restore(st.Usage)
+			st.InterruptFeedback = req.InterruptFeedback
+			// 修改计划的意见和恢复取消的运行时的新输入追加到对话中，接受计划时输入即为反馈本身
+			if req.InterruptFeedback != consts.AcceptPlan {
+				st.Messages = append(st.Messages, req.Messages...)
+			}
+			return nil
+		}),
+		compose.WithCallbacks(req.Logger, metricsCb,
+			&callback.UsageCallback{Tracker: tracker},
+			&callback.TraceCallback{ID: req.ThreadID},
+		),
+	)
+	req.Logger.Wait()
+	tracker.Wait()
+
+	// 归档运行结果
+	rec.FinishedAt = time.Now()
+	rec.Usage = tracker.Summary()
+	rec.Path = metricsCb.Path()
+	rec.Status = model.RunCompleted
+	_, interrupted := compose.ExtractInterruptInfo(err)
+	cancelled := err != nil && ctx.Err() != nil
+	switch {
+	case interrupted:
+		rec.Status = model.RunInterrupted
+	case cancelled:
+		// 取消的原因为 Cancel 传入的 ErrCanceled，上层直接取消上下文时为 context.Canceled
+		err = context.Cause(ctx)
+		rec.Status = model.RunCancelled
+		rec.Error = err.Error()
+		slog.Info("Run canceled, thread_id = %s", req.ThreadID)
+	case err != nil:
+		rec.Status = model.RunFailed
+		rec.Error = err.Error()
+		slog.Error("Run failed, Stream err = %v, thread_id = %s", err, req.ThreadID)
+	}
+	if state != nil {
+		rec.Locale = state.Locale
+		rec.Plan = state.CurrentPlan
+		rec.Report = state.FinalReport
+		rec.Structured = state.Report
+		rec.Prompts = state.Prompts
+		rec.Workflow = state.Workflow
+		// 取消的运行以当前状态写入检查点，恢复时跳过已完成的步骤，需在 abortSteps 修改计划之前写入
+		if cancelled {
+			if err := agent.SaveCheckpoint(context.WithoutCancel(ctx), req.ThreadID, resumeState(state)); err != nil {
+				slog.Error("Run failed, save checkpoint err = %v, thread_id = %s", err, req.ThreadID)
+			}
+		}
+		if interrupted || cancelled {
+			checkpoint.SetWorkflow(req.ThreadID, state.Workflow)
+		}
+		if rec.Plan != nil && rec.Error != "" {
+			abortSteps(rec.Plan, rec.Error)
+		}
+		if cancelled && rec.Report == "" && wantPartialReport(req.ThreadID) {
+			rec.Report = partialReport(state.CurrentPlan, state.Locale)
+			if rec.Report != "" {
+				event.Emit(ctx, &model.Event{Type: model.EventReport, Content: rec.Report})
+			}
+		}
+	}
+	metrics.RunFinished(req.ThreadID, rec.Status, metricsCb.LastAgent())
+
+	// 等待人工反馈的运行保留检查点和工具会话，以便从中断处恢复
+	// 取消的运行只释放工具会话，保留上面写入的检查点，以相同的线程ID重新请求时从取消处继续执行
+	// 其余运行结束后释放检查点和工具会话
+	if interrupted {
+		event.Emit(ctx, &model.Event{
+			Type:    model.EventInterrupt,
+			Agent:   consts.Human,
+			Message: "Please Review the Plan.",
+			Options: []model.InterruptOption{
+				{Text: "Edit plan", Value: consts.EditPlan},
+				{Text: "Start research", Value: consts.AcceptPlan},
+			},
+		})
+	} else {
+		var routeErr *model.RouteError
+		if errors.As(err, &routeErr) {
+			_ = req.Logger.PushRouteError(ctx, routeErr)
+		}
+		if !cancelled {
+			checkpoint.Delete(req.ThreadID)
+		}
+		mcp.CloseSession(context.WithoutCancel(ctx), req.ThreadID)
+	}
+	_ = req.Logger.PushUsage(ctx, rec.Usage)
+	if !interrupted {
+		event.Emit(ctx, &model.Event{Type: model.EventRunCompleted, Status: rec.Status, Error: rec.Error, Usage: rec.Usage})
+	}
+
+	if err := history.Save(rec); err != nil {
+		slog.Error("Run failed, save history err = %v, thread_id = %s", err, req.ThreadID)
+	}
+	if interrupted {
+		return rec, nil
+	}
+	return rec, err
+}
+
+// abortSteps 运行取消或失败时，将执行中的步骤置为失败并记录原因
+func abortSteps(plan *model.Plan, reason string) {
+	now := time.Now()
+	for i := range plan.Steps {
+		if step := &plan.Steps[i]; step.Status == model.StepRunning {
+			step.Status = model.StepFailed
+			step.Error = reason
+			step.FinishedAt = &now
+		}
+	}
+}
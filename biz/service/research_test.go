@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+
+	"github.com/hildam/deer-flow-go/agent"
+	"github.com/hildam/deer-flow-go/entity/conf"
+	"github.com/hildam/deer-flow-go/entity/consts"
+	"github.com/hildam/deer-flow-go/entity/model"
+	"github.com/hildam/deer-flow-go/repo/callback"
+	"github.com/hildam/deer-flow-go/repo/checkpoint"
+	"github.com/hildam/deer-flow-go/repo/replay"
+)
+
+// TestRunInterrupt 未自动接受计划时运行停在计划确认，推送 interrupt 事件而非 run_completed
+func TestRunInterrupt(t *testing.T) {
+	if err := replay.Start(replay.ModeReplay, filepath.Join(testdata, "research.json")); err != nil {
+		t.Fatalf("start replay: %v", err)
+	}
+	defer replay.Close()
+
+	const threadID = "interrupt-run"
+	defer checkpoint.Delete(threadID)
+	outChan := make(chan string)
+	done := make(chan struct{})
+	var sb strings.Builder
+	go func() {
+		defer close(done)
+		for out := range outChan {
+			sb.WriteString(out)
+		}
+	}()
+
+	rec, err := Run(context.Background(), &RunRequest{
+		ThreadID: threadID,
+		Messages: []*schema.Message{schema.UserMessage("How widely are Go generics adopted?")},
+		StateOptions: []agent.StateOption{func(state *model.State) {
+			state.AutoAcceptedPlan = false
+		}},
+		Logger: &callback.LoggerCallback{ID: threadID, Out: outChan},
+	})
+	close(outChan)
+	<-done
+
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if rec.Status != model.RunInterrupted {
+		t.Errorf("status = %q, want %q", rec.Status, model.RunInterrupted)
+	}
+	out := sb.String()
+	if !strings.Contains(out, "[interrupt] Please Review the Plan.") {
+		t.Errorf("output missing interrupt event:\n%s", out)
+	}
+	if strings.Contains(out, string(model.EventRunCompleted)) {
+		t.Errorf("output has run_completed for an interrupted run:\n%s", out)
+	}
+}
+
+// TestRunResumeUsage 从计划确认恢复的运行接续中断前的用量，预算按整个运行计算
+func TestRunResumeUsage(t *testing.T) {
+	fixture, err := replay.LoadFixture(filepath.Join(testdata, "research.json"))
+	if err != nil {
+		t.Fatalf("load fixture: %v", err)
+	}
+	// 录制文件依次为 Coordinator、Planner、Researcher（两次）和 Reporter 的调用
+	for _, i := range []int{0, 1, 2, 3} {
+		fixture.Chats[i].Output.ResponseMeta = &schema.ResponseMeta{
+			Usage: &schema.TokenUsage{PromptTokens: 200, CompletionTokens: 100, TotalTokens: 300},
+		}
+	}
+	path := filepath.Join(t.TempDir(), "research.json")
+	if err := fixture.Save(path); err != nil {
+		t.Fatalf("save fixture: %v", err)
+	}
+	if err := replay.Start(replay.ModeReplay, path); err != nil {
+		t.Fatalf("start replay: %v", err)
+	}
+	defer replay.Close()
+
+	// 中断前用量 600，单独计算恢复后的 600 未超出预算，合计 1200 超出
+	old := conf.GetCfg()
+	cfg := *old
+	cfg.Setting.MaxRunTokens = 1000
+	conf.SetCfg(&cfg)
+	defer conf.SetCfg(old)
+
+	const threadID = "resume-usage-run"
+	defer checkpoint.Delete(threadID)
+	rec, err := Run(context.Background(), &RunRequest{
+		ThreadID: threadID,
+		Messages: []*schema.Message{schema.UserMessage("How widely are Go generics adopted?")},
+		StateOptions: []agent.StateOption{func(state *model.State) {
+			state.AutoAcceptedPlan = false
+		}},
+		Logger: &callback.LoggerCallback{ID: threadID},
+	})
+	if err != nil || rec.Status != model.RunInterrupted {
+		t.Fatalf("Run = %v, %v, want interrupted", rec, err)
+	}
+	if got := rec.Usage.Total.TotalTokens; got != 600 {
+		t.Fatalf("interrupted usage = %d tokens, want 600", got)
+	}
+
+	rec, err = Run(context.Background(), &RunRequest{
+		ThreadID:          threadID,
+		InterruptFeedback: consts.AcceptPlan,
+		Logger:            &callback.LoggerCallback{ID: threadID},
+	})
+	if err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	sum := rec.Usage
+	if sum.Total.TotalTokens != 1200 || sum.Total.Requests != 4 || !sum.BudgetExceeded {
+		t.Errorf("usage = %+v, want 1200 tokens over 4 requests with the budget exceeded", sum.Total)
+	}
+	if sum.ByAgent[consts.Planner] == nil || sum.ByAgent[consts.Researcher] == nil {
+		t.Errorf("usage by agent = %v, want planner and researcher", sum.ByAgent)
+	}
+	// 超出预算后不再进入 Reporter
+	if rec.Report != "" {
+		t.Errorf("report = %q, want the run stopped before the reporter", rec.Report)
+	}
+}
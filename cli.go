@@ -0,0 +1,509 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/HildaM/logs/slog"
+	"github.com/cloudwego/eino/schema"
+	"github.com/google/uuid"
+
+	"github.com/hildam/deer-flow-go/agent"
+	"github.com/hildam/deer-flow-go/biz/service"
+	"github.com/hildam/deer-flow-go/entity/conf"
+	"github.com/hildam/deer-flow-go/entity/model"
+	"github.com/hildam/deer-flow-go/repo/callback"
+	"github.com/hildam/deer-flow-go/repo/mcp"
+	"github.com/hildam/deer-flow-go/repo/replay"
+	"github.com/hildam/deer-flow-go/repo/template"
+	"github.com/hildam/deer-flow-go/repo/workflow"
+)
+
+// 输出格式
+const (
+	formatText     = "text"
+	formatMarkdown = "markdown"
+	formatJSON     = "json"
+)
+
+// 退出码
+const (
+	exitFailed      = 1 // 运行失败
+	exitUsage       = 2 // 参数错误
+	exitInterrupted = 3 // 计划等待确认
+)
+
+// runFlags research 和 plan 命令共用的运行参数
+type runFlags struct {
+	query     string
+	queryFile string
+	output    string
+	format    string
+	quiet     bool
+
+	locale                  string
+	maxPlanIterations       int
+	maxStepNum              int
+	autoAccept              bool
+	backgroundInvestigation bool
+	structuredReport        bool
+	structuredReportSet     bool // 是否显式指定了 --structured-report，未指定时使用配置
+	promptVariants          variantFlag
+	workflow                string
+}
+
+// variantFlag 可重复的 --prompt-variant 参数，格式为 智能体=变体
+type variantFlag map[string]string
+
+// String 实现 flag.Value
+func (v variantFlag) String() string {
+	pairs := make([]string, 0, len(v))
+	for name, variant := range v {
+		pairs = append(pairs, name+"="+variant)
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Set 实现 flag.Value
+func (v *variantFlag) Set(s string) error {
+	name, variant, ok := strings.Cut(s, "=")
+	if !ok || name == "" || variant == "" {
+		return fmt.Errorf("invalid prompt variant %q, want agent=variant", s)
+	}
+	if *v == nil {
+		*v = variantFlag{}
+	}
+	(*v)[name] = variant
+	return nil
+}
+
+// register 注册运行参数
+func (f *runFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&configPath, "config", configPath, "配置文件路径")
+	fs.StringVar(&f.query, "query", "", "研究问题")
+	fs.StringVar(&f.queryFile, "query-file", "", "从文件读取研究问题，- 表示标准输入")
+	fs.StringVar(&f.output, "output", "", "输出文件，默认输出到标准输出")
+	fs.StringVar(&f.format, "format", formatMarkdown, "输出格式：markdown 或 json")
+	fs.BoolVar(&f.quiet, "quiet", false, "不在标准错误输出运行过程")
+	fs.StringVar(&f.locale, "locale", "", "报告语言，如 zh-CN、en-US，默认根据问题自动检测")
+	fs.IntVar(&f.maxPlanIterations, "max-plan-iterations", 0, "最大计划迭代次数，默认使用配置")
+	fs.IntVar(&f.maxStepNum, "max-step-num", 0, "计划最大步骤数，默认使用配置")
+	fs.BoolVar(&f.autoAccept, "auto-accept", true, "自动接受计划，关闭后输出计划并以退出码 3 结束")
+	fs.BoolVar(&f.backgroundInvestigation, "background-investigation", false, "制定计划前先进行背景调查")
+	fs.BoolVar(&f.structuredReport, "structured-report", false, "Reporter 以结构化输出生成报告，默认使用配置")
+	fs.Var(&f.promptVariants, "prompt-variant", "智能体使用的提示词变体，如 planner=concise，可重复指定")
+	fs.StringVar(&f.workflow, "workflow", "", "使用的工作流，如 quick_answer，默认使用配置")
+}
+
+// parse 解析命令行参数，记录显式指定的开关参数
+func (f *runFlags) parse(fs *flag.FlagSet, args []string) {
+	_ = fs.Parse(args)
+	fs.Visit(func(fl *flag.Flag) {
+		if fl.Name == "structured-report" {
+			f.structuredReportSet = true
+		}
+	})
+}
+
+// readQuery 读取研究问题
+func (f *runFlags) readQuery() (string, error) {
+	if f.queryFile == "" {
+		return strings.TrimSpace(f.query), nil
+	}
+	var (
+		data []byte
+		err  error
+	)
+	if f.queryFile == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(f.queryFile)
+	}
+	if err != nil {
+		return "", fmt.Errorf("read query file failed: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// stateOption 使用命令行参数初始化状态
+func (f *runFlags) stateOption() agent.StateOption {
+	return func(state *model.State) {
+		if f.locale != "" {
+			state.Locale = f.locale
+		}
+		if f.maxPlanIterations > 0 {
+			state.MaxPlanIterations = f.maxPlanIterations
+		}
+		if f.maxStepNum > 0 {
+			state.MaxStepNum = f.maxStepNum
+		}
+		state.AutoAcceptedPlan = f.autoAccept
+		state.EnableBackgroundInvestigation = f.backgroundInvestigation
+		if f.structuredReportSet {
+			state.StructuredReport = f.structuredReport
+		}
+		if len(f.promptVariants) > 0 {
+			state.PromptVariants = f.promptVariants
+		}
+	}
+}
+
+// request 根据参数构造运行请求
+func (f *runFlags) request(opts ...agent.StateOption) (*service.RunRequest, error) {
+	if f.format != formatMarkdown && f.format != formatJSON {
+		return nil, exitWith(exitUsage, fmt.Errorf("unknown format: %s", f.format))
+	}
+	query, err := f.readQuery()
+	if err != nil {
+		return nil, err
+	}
+	if query == "" {
+		return nil, exitWith(exitUsage, errors.New("--query or --query-file is required"))
+	}
+	return &service.RunRequest{
+		ThreadID:     uuid.New().String(),
+		Messages:     []*schema.Message{schema.UserMessage(query)},
+		Workflow:     f.workflow,
+		StateOptions: append([]agent.StateOption{f.stateOption()}, opts...),
+	}, nil
+}
+
+// execute 执行一次运行，运行过程输出到标准错误
+func (f *runFlags) execute(req *service.RunRequest) (*model.RunRecord, error) {
+	if err := template.ValidateVariants(f.promptVariants); err != nil {
+		return nil, err
+	}
+	outChan := make(chan string)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for out := range outChan {
+			if !f.quiet {
+				fmt.Fprint(os.Stderr, out)
+			}
+		}
+	}()
+
+	req.Logger = &callback.LoggerCallback{ID: req.ThreadID, Out: outChan}
+	rec, err := service.Run(context.Background(), req)
+	close(outChan)
+	<-done
+	if !f.quiet {
+		fmt.Fprintln(os.Stderr)
+	}
+	return rec, err
+}
+
+// runResearch 执行一次研究并输出报告
+func runResearch(args []string) error {
+	var f runFlags
+	fs := flag.NewFlagSet("research", flag.ExitOnError)
+	f.register(fs)
+	f.parse(fs, args)
+
+	req, err := f.request()
+	if err != nil {
+		return fmt.Errorf("research failed, err: %w", err)
+	}
+	defer closeDeps()
+	if err := initDeps(); err != nil {
+		return err
+	}
+
+	rec, err := f.execute(req)
+	if err != nil {
+		return fmt.Errorf("research failed, thread_id = %s, err: %w", req.ThreadID, err)
+	}
+
+	switch {
+	case f.format == formatJSON:
+		err = writeJSON(f.output, rec)
+	case rec.Status == model.RunInterrupted:
+		err = writeOutput(f.output, []byte(formatPlan(rec.Plan)))
+	default:
+		err = writeOutput(f.output, []byte(rec.Report))
+	}
+	if err != nil {
+		return fmt.Errorf("research failed, write output err: %w", err)
+	}
+
+	// 未自动接受计划时运行停在计划确认，输出计划后以单独的退出码结束
+	if rec.Status == model.RunInterrupted {
+		fmt.Fprintf(os.Stderr, "计划等待确认，研究未执行，thread_id = %s\n", rec.ID)
+		return exitWith(exitInterrupted, nil)
+	}
+	return nil
+}
+
+// runPlan 仅制定研究计划
+func runPlan(args []string) error {
+	var f runFlags
+	fs := flag.NewFlagSet("plan", flag.ExitOnError)
+	f.register(fs)
+	f.parse(fs, args)
+
+	req, err := f.request(func(state *model.State) {
+		state.PlanOnly = true
+	})
+	if err != nil {
+		return fmt.Errorf("plan failed, err: %w", err)
+	}
+	defer closeDeps()
+	if err := initDeps(); err != nil {
+		return err
+	}
+
+	rec, err := f.execute(req)
+	if err != nil {
+		return fmt.Errorf("plan failed, thread_id = %s, err: %w", req.ThreadID, err)
+	}
+	if rec.Plan == nil {
+		return fmt.Errorf("plan failed, no plan generated, thread_id = %s", req.ThreadID)
+	}
+
+	if f.format == formatJSON {
+		err = writeJSON(f.output, rec.Plan)
+	} else {
+		err = writeOutput(f.output, []byte(formatPlan(rec.Plan)))
+	}
+	if err != nil {
+		return fmt.Errorf("plan failed, write output err: %w", err)
+	}
+	return nil
+}
+
+// runTools 列出可用的MCP工具
+func runTools(args []string) error {
+	if len(args) == 0 || args[0] != "list" {
+		fmt.Fprintln(os.Stderr, "用法：deer-flow-go tools list [--format text|json]")
+		return exitWith(exitUsage, nil)
+	}
+	fs := flag.NewFlagSet("tools list", flag.ExitOnError)
+	fs.StringVar(&configPath, "config", configPath, "配置文件路径")
+	format := fs.String("format", formatText, "输出格式：text 或 json")
+	_ = fs.Parse(args[1:])
+
+	// 列出工具只需要配置、录制回放和MCP服务
+	defer func() {
+		_ = replay.Close()
+	}()
+	if err := initAll(initConf, replay.Init, mcp.InitMcpServer); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	tools, err := mcp.GetMCPTools(ctx)
+	if err != nil {
+		return fmt.Errorf("tools list failed, err: %w", err)
+	}
+	infos := make([]*schema.ToolInfo, 0, len(tools))
+	for _, t := range tools {
+		info, err := t.Info(ctx)
+		if err != nil {
+			slog.Error("tools list failed, get tool info err = %v", err)
+			continue
+		}
+		infos = append(infos, info)
+	}
+
+	if *format == formatJSON {
+		type toolItem struct {
+			Name string `json:"name"`
+			Desc string `json:"desc"`
+		}
+		items := make([]toolItem, 0, len(infos))
+		for _, info := range infos {
+			items = append(items, toolItem{Name: info.Name, Desc: info.Desc})
+		}
+		if err := writeJSON("", items); err != nil {
+			return fmt.Errorf("tools list failed, err: %w", err)
+		}
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tDESCRIPTION")
+	for _, info := range infos {
+		fmt.Fprintf(w, "%s\t%s\n", info.Name, truncate(strings.ReplaceAll(info.Desc, "\n", " "), 80))
+	}
+	_ = w.Flush()
+	return nil
+}
+
+// runMcp 查看MCP服务连接状态
+func runMcp(args []string) error {
+	if len(args) == 0 || args[0] != "status" {
+		fmt.Fprintln(os.Stderr, "用法：deer-flow-go mcp status [--format text|json]")
+		return exitWith(exitUsage, nil)
+	}
+	fs := flag.NewFlagSet("mcp status", flag.ExitOnError)
+	fs.StringVar(&configPath, "config", configPath, "配置文件路径")
+	format := fs.String("format", formatText, "输出格式：text 或 json")
+	_ = fs.Parse(args[1:])
+
+	if err := initConf(); err != nil {
+		return err
+	}
+	// 任一服务启动失败时不会保留任何连接，此时所有服务均显示为未连接
+	initErr := mcp.InitMcpServer()
+	statuses := mcp.Status(context.Background())
+	if initErr != nil {
+		for i := range statuses {
+			statuses[i].Error = initErr.Error()
+		}
+	}
+
+	if *format == formatJSON {
+		if err := writeJSON("", statuses); err != nil {
+			return fmt.Errorf("mcp status failed, err: %w", err)
+		}
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SERVER\tCONNECTED\tTOOLS\tERROR")
+		for _, s := range statuses {
+			fmt.Fprintf(w, "%s\t%v\t%d\t%s\n", s.Name, s.Connected, len(s.Tools), s.Error)
+		}
+		_ = w.Flush()
+	}
+
+	for _, s := range statuses {
+		if !s.Connected {
+			return exitWith(exitFailed, nil)
+		}
+	}
+	return nil
+}
+
+// runPrompts 列出提示词模板及校验结果
+func runPrompts(args []string) error {
+	if len(args) == 0 || args[0] != "list" {
+		fmt.Fprintln(os.Stderr, "用法：deer-flow-go prompts list [--format text|json]")
+		return exitWith(exitUsage, nil)
+	}
+	fs := flag.NewFlagSet("prompts list", flag.ExitOnError)
+	fs.StringVar(&configPath, "config", configPath, "配置文件路径")
+	format := fs.String("format", formatText, "输出格式：text 或 json")
+	_ = fs.Parse(args[1:])
+
+	if err := initConf(); err != nil {
+		return err
+	}
+	r, err := template.Default()
+	if err != nil {
+		return fmt.Errorf("prompts list failed, err: %w", err)
+	}
+	prompts := r.List()
+
+	if *format == formatJSON {
+		if err := writeJSON("", prompts); err != nil {
+			return fmt.Errorf("prompts list failed, err: %w", err)
+		}
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PATH\tVARIANT\tSOURCE\tVERSION\tUNDEFINED")
+	for _, p := range prompts {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", p.Path, p.Variant, p.Source, p.Version, strings.Join(p.Undefined, ","))
+	}
+	_ = w.Flush()
+	return nil
+}
+
+// runWorkflows 查看工作流及流转表
+func runWorkflows(args []string) error {
+	if len(args) == 0 || (args[0] != "list" && args[0] != "mermaid") {
+		fmt.Fprintln(os.Stderr, "用法：deer-flow-go workflows list [--format text|json]\n      deer-flow-go workflows mermaid [工作流名称]")
+		return exitWith(exitUsage, nil)
+	}
+	fs := flag.NewFlagSet("workflows "+args[0], flag.ExitOnError)
+	fs.StringVar(&configPath, "config", configPath, "配置文件路径")
+	format := fs.String("format", formatText, "输出格式：text 或 json")
+	_ = fs.Parse(args[1:])
+
+	if err := initConf(); err != nil {
+		return err
+	}
+	agents := conf.GetCfg().Agents
+
+	// 未指定工作流时输出完整的流转表
+	if args[0] == "mermaid" {
+		if fs.NArg() == 0 {
+			fmt.Print(workflow.TransitionsMermaid(agents))
+			return nil
+		}
+		wf, err := workflow.Get(fs.Arg(0), agents)
+		if err != nil {
+			return fmt.Errorf("workflows mermaid failed, err: %w", err)
+		}
+		fmt.Print(wf.Mermaid())
+		return nil
+	}
+
+	workflows, err := workflow.List()
+	if err != nil {
+		return fmt.Errorf("workflows list failed, err: %w", err)
+	}
+	if *format == formatJSON {
+		if err := writeJSON("", workflows); err != nil {
+			return fmt.Errorf("workflows list failed, err: %w", err)
+		}
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tENTRY\tNODES\tSOURCE\tDESCRIPTION")
+	for _, wf := range workflows {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", wf.Name, wf.Entry, strings.Join(wf.NodeNames(), ","), wf.Source, wf.Description)
+	}
+	_ = w.Flush()
+	return nil
+}
+
+// stepStatusText 步骤状态的中文说明
+var stepStatusText = map[model.StepStatus]string{
+	model.StepPending:   "待执行",
+	model.StepRunning:   "执行中",
+	model.StepSucceeded: "已完成",
+	model.StepFailed:    "失败",
+	model.StepSkipped:   "已跳过",
+}
+
+// formatPlan 将计划格式化为 Markdown
+func formatPlan(plan *model.Plan) string {
+	if plan == nil {
+		return ""
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s\n\n%s\n\n", plan.Title, plan.Thought)
+	for i, step := range plan.Steps {
+		status := stepStatusText[step.Status]
+		if status == "" {
+			status = stepStatusText[model.StepPending]
+		}
+		fmt.Fprintf(&sb, "%d. [%s][%s] %s\n   %s\n", i+1, step.StepType, status, step.Title, step.Description)
+	}
+	return sb.String()
+}
+
+// writeJSON 以缩进格式输出 JSON
+func writeJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeOutput(path, append(data, '\n'))
+}
+
+// writeOutput 写入输出文件，未指定文件时输出到标准输出
+func writeOutput(path string, data []byte) error {
+	if path == "" || path == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
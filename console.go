@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+
+	"github.com/HildaM/logs/slog"
+	"github.com/cloudwego/eino/schema"
+	"github.com/google/uuid"
+
+	"github.com/hildam/deer-flow-go/agent"
+	"github.com/hildam/deer-flow-go/biz/service"
+	"github.com/hildam/deer-flow-go/entity/consts"
+	"github.com/hildam/deer-flow-go/entity/model"
+	"github.com/hildam/deer-flow-go/repo/callback"
+)
+
+const consoleHelp = `命令：
+  /plan                查看当前计划
+  /accept              接受计划并开始研究
+  /edit <修改意见>     按意见重新制定计划
+  /auto [on|off]       自动接受计划，默认关闭
+  /history             查看本次会话的对话记录
+  /report              查看最近一次的研究报告
+  /report save <文件>  保存最近一次的研究报告
+  /new                 开始新的会话
+  /help                查看帮助
+  /exit                退出
+直接输入问题开始研究，研究完成后继续输入可在之前的结论上追问。
+`
+
+// console 控制台会话
+// 同一会话内的问题和研究报告依次保存在 messages 中，作为后续追问的上下文
+type console struct {
+	ctx        context.Context
+	reader     *bufio.Reader
+	messages   []*schema.Message // 会话上下文
+	last       *model.RunRecord  // 最近一次运行的记录
+	autoAccept bool              // 是否自动接受计划
+}
+
+// runConsule 运行控制台
+func runConsule() error {
+	// 初始化配置
+	defer closeDeps()
+	if err := initDeps(); err != nil {
+		return err
+	}
+
+	c := &console{
+		ctx:    context.Background(),
+		reader: bufio.NewReader(os.Stdin),
+	}
+	fmt.Print(consoleHelp)
+	for {
+		fmt.Print("\n> ")
+		line, err := c.reader.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if line != "" && !c.handle(line) {
+			return nil
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			slog.Error("runConsule failed, read stdin err: %v", err)
+			return nil
+		}
+	}
+}
+
+// handle 处理一行输入，返回 false 表示退出
+func (c *console) handle(line string) bool {
+	if !strings.HasPrefix(line, "/") {
+		// 计划等待确认时，直接输入视为修改意见
+		if c.interrupted() {
+			c.edit(line)
+			return true
+		}
+		c.ask(line)
+		return true
+	}
+
+	cmd, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+	switch cmd {
+	case "/plan":
+		c.printPlan()
+	case "/accept":
+		if !c.interrupted() {
+			fmt.Println("当前没有等待确认的计划")
+			break
+		}
+		c.run(&service.RunRequest{ThreadID: c.last.ID, InterruptFeedback: consts.AcceptPlan})
+	case "/edit":
+		if arg == "" {
+			fmt.Println("用法：/edit <修改意见>")
+			break
+		}
+		c.edit(arg)
+	case "/auto":
+		c.autoAccept = arg != "off"
+		fmt.Printf("自动接受计划：%v\n", c.autoAccept)
+	case "/history":
+		c.printHistory()
+	case "/report":
+		c.report(arg)
+	case "/new":
+		c.discard()
+		c.messages, c.last = nil, nil
+		fmt.Println("已开始新的会话")
+	case "/help":
+		fmt.Print(consoleHelp)
+	case "/exit", "/quit":
+		return false
+	default:
+		fmt.Printf("未知命令 %s，输入 /help 查看帮助\n", cmd)
+	}
+	return true
+}
+
+// ask 提出新问题，之前的问题和报告一并作为上下文
+func (c *console) ask(question string) {
+	c.discard()
+	c.messages = append(c.messages, schema.UserMessage(question))
+	autoAccept := c.autoAccept
+	c.run(&service.RunRequest{
+		ThreadID: uuid.New().String(),
+		Messages: slices.Clone(c.messages),
+		StateOptions: []agent.StateOption{func(state *model.State) {
+			state.AutoAcceptedPlan = autoAccept
+		}},
+	})
+}
+
+// edit 按修改意见重新制定计划
+func (c *console) edit(feedback string) {
+	if !c.interrupted() {
+		fmt.Println("当前没有等待确认的计划")
+		return
+	}
+	msg := schema.UserMessage(feedback)
+	c.messages = append(c.messages, msg)
+	c.run(&service.RunRequest{
+		ThreadID:          c.last.ID,
+		Messages:          []*schema.Message{msg},
+		InterruptFeedback: consts.EditPlan,
+	})
+}
+
+// run 执行一次运行并等待输出完毕，运行期间按 Ctrl-C 取消运行并以已完成的步骤生成部分报告
+func (c *console) run(req *service.RunRequest) {
+	outChan := make(chan string)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for out := range outChan {
+			fmt.Print(out)
+		}
+	}()
+
+	// 只在运行期间接管中断信号，等待输入时 Ctrl-C 仍然直接退出
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	finished := make(chan struct{})
+	go func() {
+		select {
+		case <-sig:
+			service.Cancel(req.ThreadID, true)
+		case <-finished:
+		}
+	}()
+
+	req.Logger = &callback.LoggerCallback{ID: req.ThreadID, Out: outChan}
+	rec, err := service.Run(c.ctx, req)
+	close(finished)
+	signal.Stop(sig)
+	close(outChan)
+	<-done
+
+	switch {
+	case errors.Is(err, service.ErrCanceled):
+	case err != nil:
+		fmt.Printf("\n运行失败：%v\n", err)
+	}
+	if rec == nil {
+		return
+	}
+	c.last = rec
+
+	switch rec.Status {
+	case model.RunInterrupted:
+		fmt.Println("\n计划等待确认：/plan 查看计划，/accept 开始研究，/edit <修改意见> 修改计划")
+	case model.RunCancelled:
+		if rec.Report != "" {
+			fmt.Println("\n研究已取消，已根据完成的步骤生成部分报告：/report 查看")
+		} else {
+			fmt.Println("\n研究已取消")
+		}
+	case model.RunCompleted:
+		if rec.Report != "" {
+			c.messages = append(c.messages, schema.AssistantMessage(rec.Report, nil))
+		}
+	}
+}
+
+// discard 开始新的问题前丢弃最近一次运行在中断或取消时保留的检查点，控制台不会再恢复该运行
+func (c *console) discard() {
+	if c.last != nil && (c.last.Status == model.RunInterrupted || c.last.Status == model.RunCancelled) {
+		_ = service.Discard(c.last.ID)
+	}
+}
+
+// interrupted 最近一次运行是否在等待计划确认
+func (c *console) interrupted() bool {
+	return c.last != nil && c.last.Status == model.RunInterrupted
+}
+
+// printPlan 输出当前计划
+func (c *console) printPlan() {
+	if c.last == nil || c.last.Plan == nil {
+		fmt.Println("当前没有计划")
+		return
+	}
+	fmt.Print(formatPlan(c.last.Plan))
+}
+
+// printHistory 输出本次会话的对话记录
+func (c *console) printHistory() {
+	if len(c.messages) == 0 {
+		fmt.Println("当前会话没有对话记录")
+		return
+	}
+	for i, msg := range c.messages {
+		fmt.Printf("%d. [%s] %s\n", i+1, msg.Role, truncate(strings.ReplaceAll(msg.Content, "\n", " "), 100))
+	}
+}
+
+// report 查看或保存最近一次的研究报告
+func (c *console) report(arg string) {
+	if c.last == nil || c.last.Report == "" {
+		fmt.Println("当前没有研究报告")
+		return
+	}
+	if arg == "" {
+		fmt.Println(c.last.Report)
+		return
+	}
+
+	sub, file, _ := strings.Cut(arg, " ")
+	file = strings.TrimSpace(file)
+	if sub != "save" || file == "" {
+		fmt.Println("用法：/report save <文件>")
+		return
+	}
+	if err := os.WriteFile(file, []byte(c.last.Report), 0o644); err != nil {
+		fmt.Printf("保存失败：%v\n", err)
+		return
+	}
+	fmt.Printf("已保存到 %s\n", file)
+}
@@ -20,8 +20,17 @@ var (
 	f *file.File
 	// 缓存的配置实例
 	appConf *AppConfig
+	// 配置文件路径
+	configPath = "config.yaml"
 )
 
+// SetPath 设置配置文件路径，需在 Init 之前调用
+func SetPath(path string) {
+	if path != "" {
+		configPath = path
+	}
+}
+
 // Init 初始化配置
 func Init() error {
 	// 加载配置
@@ -48,9 +57,9 @@ func loadConfig() error {
 	defer configMu.Unlock()
 
 	// 创建文件提供者
-	f = file.Provider("config.yaml")
+	f = file.Provider(configPath)
 
-	// 从根目录加载配置文件
+	// 加载配置文件
 	if err := k.Load(f, yaml.Parser()); err != nil {
 		return fmt.Errorf("failed to load config file: %w", err)
 	}
@@ -66,11 +75,21 @@ func loadConfig() error {
 	return nil
 }
 
-// GetCfg 获取配置（无锁读取）
+// GetCfg 获取配置
+// 配置热加载时整体替换实例而不修改旧实例，调用方拿到的配置在整个使用期间保持一致
 func GetCfg() *AppConfig {
+	configMu.RLock()
+	defer configMu.RUnlock()
 	return appConf
 }
 
+// SetCfg 直接设置配置，用于测试等不读取配置文件的场景
+func SetCfg(cfg *AppConfig) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	appConf = cfg
+}
+
 // startConfigWatch 启动配置文件监听
 func startConfigWatch() {
 	if f == nil {
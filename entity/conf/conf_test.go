@@ -0,0 +1,29 @@
+package conf
+
+import (
+	"sync"
+	"testing"
+)
+
+// TestGetCfgConcurrent 配置热加载与读取并发进行，需配合 -race 运行
+func TestGetCfgConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				SetCfg(&AppConfig{Setting: SettingConfig{MaxPlanIterations: i}})
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if cfg := GetCfg(); cfg != nil {
+					_ = cfg.Setting.MaxPlanIterations
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
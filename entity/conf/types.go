@@ -19,22 +19,120 @@ type Model struct {
 	APIKey  string `yaml:"api_key" mapstructure:"api_key"`   // 模型服务的API密钥
 }
 
+// ModelPrice 单个模型的计费价格，单位为每百万 token 的价格
+type ModelPrice struct {
+	Model       string  `yaml:"model" mapstructure:"model"`               // 模型ID
+	Input       float64 `yaml:"input" mapstructure:"input"`               // 输入 token 单价
+	Output      float64 `yaml:"output" mapstructure:"output"`             // 输出 token 单价
+	CachedInput float64 `yaml:"cached_input" mapstructure:"cached_input"` // 命中缓存的输入 token 单价，为 0 时按输入单价计费
+}
+
 // ModelConfig 模型配置
 type ModelConfig struct {
-	DefaultModel Model `yaml:"default_model" mapstructure:"default_model"` // 默认使用的模型名称
+	DefaultModel Model        `yaml:"default_model" mapstructure:"default_model"`         // 默认使用的模型名称
+	Pricing      []ModelPrice `yaml:"pricing,omitempty" mapstructure:"pricing,omitempty"` // 模型价格表
 }
 
 // SettingConfig 应用运行配置
 type SettingConfig struct {
-	MaxPlanIterations int `yaml:"max_plan_iterations" mapstructure:"max_plan_iterations"` // 最大计划迭代次数
-	TotalMaxRound     int `yaml:"total_max_round" mapstructure:"total_max_round"`         // 全局 agent 最大执行轮数
-	AgentMaxStep      int `yaml:"agent_max_step" mapstructure:"agent_max_step"`           // 每个 agent 最大执行步骤数
-	MaxLimitToken     int `yaml:"max_limit_token" mapstructure:"max_limit_token"`         // 最大限制token数
+	MaxPlanIterations int     `yaml:"max_plan_iterations" mapstructure:"max_plan_iterations"` // 最大计划迭代次数
+	TotalMaxRound     int     `yaml:"total_max_round" mapstructure:"total_max_round"`         // 全局 agent 最大执行轮数
+	AgentMaxStep      int     `yaml:"agent_max_step" mapstructure:"agent_max_step"`           // 每个 agent 最大执行步骤数
+	MaxLimitToken     int     `yaml:"max_limit_token" mapstructure:"max_limit_token"`         // 最大限制token数
+	MaxRunTokens      int     `yaml:"max_run_tokens" mapstructure:"max_run_tokens"`           // 单次运行 token 预算上限，0 表示不限制
+	MaxRunCost        float64 `yaml:"max_run_cost" mapstructure:"max_run_cost"`               // 单次运行费用预算上限，0 表示不限制
+	StructuredReport  bool    `yaml:"structured_report" mapstructure:"structured_report"`     // Reporter 是否以结构化输出生成报告
+	StepMaxRetries    int     `yaml:"step_max_retries" mapstructure:"step_max_retries"`       // 步骤执行出错后的重试次数，0 表示不重试
+	StepRetryBackoff  int     `yaml:"step_retry_backoff" mapstructure:"step_retry_backoff"`   // 重试间隔（毫秒），第 n 次重试等待 n 倍间隔
+	StepOnFailure     string  `yaml:"step_on_failure" mapstructure:"step_on_failure"`         // 重试后仍失败时的处理方式：continue（默认）或 abort
+}
+
+// TraceConfig 链路追踪配置
+type TraceConfig struct {
+	Enable      bool   `yaml:"enable" mapstructure:"enable"`             // 是否启用链路追踪
+	Exporter    string `yaml:"exporter" mapstructure:"exporter"`         // 导出方式：otlp（OTLP HTTP）或 file（本地JSON文件）
+	Endpoint    string `yaml:"endpoint" mapstructure:"endpoint"`         // OTLP HTTP 接收端地址，如 localhost:4318
+	Insecure    bool   `yaml:"insecure" mapstructure:"insecure"`         // OTLP 是否使用非加密连接
+	FilePath    string `yaml:"file_path" mapstructure:"file_path"`       // 本地文件导出路径
+	ServiceName string `yaml:"service_name" mapstructure:"service_name"` // 上报的服务名称
+}
+
+// ServerConfig HTTP服务配置
+type ServerConfig struct {
+	Addr string `yaml:"addr" mapstructure:"addr"` // 监听地址，如 :8888
+}
+
+// HistoryConfig 运行历史配置
+type HistoryConfig struct {
+	Path string `yaml:"path" mapstructure:"path"` // 运行历史数据库文件路径
+}
+
+// ReplayConfig 录制回放配置
+type ReplayConfig struct {
+	Mode    string `yaml:"mode" mapstructure:"mode"`       // 运行模式：record 录制模型及工具交互，replay 使用录制结果替代真实调用，为空表示关闭
+	Fixture string `yaml:"fixture" mapstructure:"fixture"` // 录制文件路径
+}
+
+// SandboxConfig 内置代码执行沙箱配置
+type SandboxConfig struct {
+	Disable    bool   `yaml:"disable" mapstructure:"disable"`         // 是否禁用内置代码执行工具
+	Python     string `yaml:"python" mapstructure:"python"`           // Python 解释器，默认 python3
+	Shell      bool   `yaml:"shell" mapstructure:"shell"`             // 是否允许执行 shell 脚本
+	Timeout    int    `yaml:"timeout" mapstructure:"timeout"`         // 单次执行超时秒数，默认 60
+	CPUSeconds int    `yaml:"cpu_seconds" mapstructure:"cpu_seconds"` // CPU 时间上限秒数，默认与超时相同
+	MemoryMB   int    `yaml:"memory_mb" mapstructure:"memory_mb"`     // 虚拟内存上限，默认 1024
+	Network    bool   `yaml:"network" mapstructure:"network"`         // 是否允许访问网络，默认禁止
+}
+
+// ArtifactConfig 产物存储配置
+type ArtifactConfig struct {
+	Backend string `yaml:"backend" mapstructure:"backend"` // 存储后端，目前支持 local（本地文件系统）
+	Dir     string `yaml:"dir" mapstructure:"dir"`         // local 后端的存储目录
+}
+
+// PromptConfig 提示词模板配置
+type PromptConfig struct {
+	Dir string `yaml:"dir" mapstructure:"dir"` // 覆盖内置模板的目录，目录结构与内置模板相同，默认 prompts
+}
+
+// WorkflowConfig 工作流配置
+type WorkflowConfig struct {
+	Dir     string `yaml:"dir" mapstructure:"dir"`         // 工作流定义目录，其中的同名定义覆盖内置工作流，默认 workflows
+	Default string `yaml:"default" mapstructure:"default"` // 未指定工作流时使用的工作流，默认 deep_research
+}
+
+// InvestigationConfig 背景调查配置
+type InvestigationConfig struct {
+	Tools       []string `yaml:"tools" mapstructure:"tools"`             // 使用的搜索工具名称，为空时使用名称以 search 结尾的全部工具
+	MaxQueries  int      `yaml:"max_queries" mapstructure:"max_queries"` // 由模型生成的搜索查询数上限，默认 3
+	MaxResults  int      `yaml:"max_results" mapstructure:"max_results"` // 去重排序后保留在汇总中的结果数，默认 8
+	Concurrency int      `yaml:"concurrency" mapstructure:"concurrency"` // 同时执行的搜索数，默认 4
+}
+
+// AgentConfig 自定义智能体配置，以 ReAct 方式执行计划中指定类型的步骤
+type AgentConfig struct {
+	Name        string   `yaml:"name" mapstructure:"name"`               // 智能体名称，即图节点名称，不能与内置智能体重名
+	StepType    string   `yaml:"step_type" mapstructure:"step_type"`     // 处理的计划步骤类型，如 legal_review
+	Description string   `yaml:"description" mapstructure:"description"` // 步骤类型说明，Planner 据此决定何时使用该类型的步骤
+	Prompt      string   `yaml:"prompt" mapstructure:"prompt"`           // 提示词模板名称，对应提示词目录中的 <prompt>.md，默认与智能体名称相同
+	Model       Model    `yaml:"model" mapstructure:"model"`             // 使用的模型，未配置的字段使用默认模型的配置
+	Tools       []string `yaml:"tools" mapstructure:"tools"`             // 可使用的工具名称，支持 * 通配符，为空时不使用工具
 }
 
 // AppConfig 应用配置
 type AppConfig struct {
-	MCP     MCPConfig     `yaml:"mcp" mapstructure:"mcp"`         // MCP服务相关配置
-	Model   ModelConfig   `yaml:"model" mapstructure:"model"`     // 大语言模型相关配置
-	Setting SettingConfig `yaml:"setting" mapstructure:"setting"` // 应用运行时配置参数
+	MCP      MCPConfig      `yaml:"mcp" mapstructure:"mcp"`           // MCP服务相关配置
+	Model    ModelConfig    `yaml:"model" mapstructure:"model"`       // 大语言模型相关配置
+	Setting  SettingConfig  `yaml:"setting" mapstructure:"setting"`   // 应用运行时配置参数
+	Trace    TraceConfig    `yaml:"trace" mapstructure:"trace"`       // 链路追踪配置
+	Server   ServerConfig   `yaml:"server" mapstructure:"server"`     // HTTP服务配置
+	History  HistoryConfig  `yaml:"history" mapstructure:"history"`   // 运行历史配置
+	Replay   ReplayConfig   `yaml:"replay" mapstructure:"replay"`     // 录制回放配置
+	Sandbox  SandboxConfig  `yaml:"sandbox" mapstructure:"sandbox"`   // 内置代码执行沙箱配置
+	Artifact ArtifactConfig `yaml:"artifact" mapstructure:"artifact"` // 产物存储配置
+	Prompt   PromptConfig   `yaml:"prompt" mapstructure:"prompt"`     // 提示词模板配置
+	Agents   []AgentConfig  `yaml:"agents" mapstructure:"agents"`     // 自定义智能体
+	Workflow WorkflowConfig `yaml:"workflow" mapstructure:"workflow"` // 工作流配置
+
+	Investigation InvestigationConfig `yaml:"investigation" mapstructure:"investigation"` // 背景调查配置
 }
@@ -30,6 +30,12 @@ func GetAgentNameList() []string {
 	}
 }
 
+// 步骤失败策略，步骤重试后仍然失败时的处理方式
+const (
+	StepFailureContinue = "continue" // 将步骤标记为失败，继续执行后续步骤，报告中注明失败的步骤
+	StepFailureAbort    = "abort"    // 结束整个运行
+)
+
 // 人类选项
 const (
 	EditPlan   = "edit_plan" // 编辑计划选项，用户选择修改当前计划
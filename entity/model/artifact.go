@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// Artifact 运行过程中步骤或工具生成的文件，如 Coder 生成的 CSV、图表和工具返回的文档
+type Artifact struct {
+	RunID     string    `json:"run_id"`               // 所属运行的线程ID
+	Name      string    `json:"name"`                 // 运行内唯一的文件名，可包含子目录
+	MIMEType  string    `json:"mime_type"`            // MIME 类型
+	Size      int64     `json:"size"`                 // 文件大小
+	Agent     string    `json:"agent,omitempty"`      // 生成文件的智能体
+	Step      int       `json:"step,omitempty"`       // 生成文件的计划步骤序号，从 1 开始，0 表示不属于任何步骤
+	StepTitle string    `json:"step_title,omitempty"` // 生成文件的计划步骤标题
+	Tool      string    `json:"tool,omitempty"`       // 生成文件的工具
+	CreatedAt time.Time `json:"created_at"`           // 保存时间
+}
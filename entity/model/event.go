@@ -0,0 +1,58 @@
+package model
+
+import "time"
+
+// EventType 工作流事件类型，即 SSE 的事件名
+type EventType string
+
+const (
+	EventAgentStart    EventType = "agent_start"    // 智能体开始执行
+	EventAgentEnd      EventType = "agent_end"      // 智能体执行结束，Next 为流转到的智能体
+	EventPlanCreated   EventType = "plan_created"   // 生成了新的计划
+	EventStepStarted   EventType = "step_started"   // 计划步骤开始执行
+	EventStepCompleted EventType = "step_completed" // 计划步骤执行完成
+	EventStepRetry     EventType = "step_retry"     // 计划步骤执行出错，即将重试，之前推送的本次执行的输出应丢弃
+	EventStepFailed    EventType = "step_failed"    // 计划步骤重试后仍然失败
+	EventStepSkipped   EventType = "step_skipped"   // 计划步骤没有智能体处理，跳过
+	EventRunCompleted  EventType = "run_completed"  // 运行结束，等待人工反馈时推送 interrupt 事件
+	EventInterrupt     EventType = "interrupt"      // 计划等待人工确认，客户端选择 Options 之一后携带 interrupt_feedback 重新请求
+	EventWarning       EventType = "warning"        // 智能体跳过了部分工作但运行继续，如没有可用的搜索工具
+	EventReport        EventType = "report"         // 结构化报告解析完成或取消时生成了部分报告，包含 Markdown 报告
+)
+
+// Event 工作流事件，由各智能体的 router 和运行服务发出，通过 SSE 和控制台推送
+type Event struct {
+	Type     EventType         `json:"type"`
+	ThreadID string            `json:"thread_id"`
+	Agent    string            `json:"agent,omitempty"`   // 发出事件的智能体
+	Next     string            `json:"next,omitempty"`    // agent_end：流转到的智能体
+	Plan     *Plan             `json:"plan,omitempty"`    // plan_created：完整的计划
+	Step     *StepEvent        `json:"step,omitempty"`    // step_*：步骤信息
+	Status   RunStatus         `json:"status,omitempty"`  // run_completed：运行状态
+	Error    string            `json:"error,omitempty"`   // run_completed：失败原因
+	Usage    *UsageSummary     `json:"usage,omitempty"`   // run_completed：用量汇总
+	Message  string            `json:"message,omitempty"` // warning / interrupt：提示信息
+	Report   *Report           `json:"report,omitempty"`  // report：结构化报告，部分报告时为空
+	Content  string            `json:"content,omitempty"` // report：渲染后的 Markdown 报告
+	Options  []InterruptOption `json:"options,omitempty"` // interrupt：可选的人工反馈
+	Time     time.Time         `json:"time"`
+}
+
+// InterruptOption 中断事件中可选的人工反馈
+type InterruptOption struct {
+	Text  string `json:"text"`
+	Value string `json:"value"` // 重新请求时作为 interrupt_feedback
+}
+
+// StepEvent 步骤事件中的步骤信息
+type StepEvent struct {
+	Index     int        `json:"index"` // 步骤序号，从 0 开始
+	Total     int        `json:"total"` // 计划的步骤总数
+	Title     string     `json:"title"`
+	StepType  StepType   `json:"step_type"`
+	Status    StepStatus `json:"status"`
+	Attempts  int        `json:"attempts,omitempty"`   // 执行次数，包括重试；step_retry 时为已失败的次数
+	ToolCalls int        `json:"tool_calls,omitempty"` // 工具调用次数
+	Summary   string     `json:"summary,omitempty"`    // step_completed：执行结果摘要
+	Error     string     `json:"error,omitempty"`      // step_retry / step_failed / step_skipped：出错、失败或跳过的原因
+}
@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 // StepType 定义步骤类型的枚举
 type StepType string
 
@@ -8,6 +10,17 @@ const (
 	Processing StepType = "processing"
 )
 
+// StepStatus 定义步骤执行状态
+type StepStatus string
+
+const (
+	StepPending   StepStatus = "pending"   // 等待执行
+	StepRunning   StepStatus = "running"   // 执行中
+	StepSucceeded StepStatus = "succeeded" // 执行成功
+	StepFailed    StepStatus = "failed"    // 重试后仍然失败
+	StepSkipped   StepStatus = "skipped"   // 当前工作流中没有处理该步骤类型的智能体，未执行
+)
+
 // Plan 定义计划的结构体
 type Plan struct {
 	Locale           string `json:"locale" validate:"required"`
@@ -24,4 +37,36 @@ type Step struct {
 	Description   string   `json:"description" validate:"required"`
 	StepType      StepType `json:"step_type" validate:"required"`
 	ExecutionRes  *string  `json:"execution_res,omitempty"`
+
+	// 以下字段在执行时记录，不属于计划内容
+	Status     StepStatus     `json:"status,omitempty"`
+	Agent      string         `json:"agent,omitempty"`       // 执行步骤的智能体
+	StartedAt  *time.Time     `json:"started_at,omitempty"`  // 开始执行的时间
+	FinishedAt *time.Time     `json:"finished_at,omitempty"` // 执行结束的时间
+	Attempts   int            `json:"attempts,omitempty"`    // 执行次数，包括重试
+	ToolCalls  []StepToolCall `json:"tool_calls,omitempty"`  // 执行中的工具调用，包括失败的尝试
+	Error      string         `json:"error,omitempty"`       // 失败或跳过的原因
+}
+
+// StepToolCall 步骤执行中的一次工具调用
+type StepToolCall struct {
+	Name       string `json:"name"`
+	Arguments  string `json:"arguments,omitempty"` // JSON格式的调用参数
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// StepRuntimeFields 执行时记录的步骤字段的 JSON 名称，生成计划的 JSON Schema 中不包含这些字段
+var StepRuntimeFields = []string{"execution_res", "status", "agent", "started_at", "finished_at", "attempts", "tool_calls", "error"}
+
+// Done 步骤是否已执行结束，失败和跳过的步骤也视为结束，不再执行
+func (s *Step) Done() bool {
+	switch s.Status {
+	case StepSucceeded, StepFailed, StepSkipped:
+		return true
+	case "":
+		// 兼容没有状态的计划，有执行结果即视为结束
+		return s.ExecutionRes != nil
+	}
+	return false
 }
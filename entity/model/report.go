@@ -0,0 +1,107 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Report 定义结构化报告，Reporter 以结构化输出模式生成，服务端渲染为 Markdown
+type Report struct {
+	Title      string           `json:"title" validate:"required"`
+	KeyPoints  []string         `json:"key_points" validate:"required"` // 最重要的发现
+	Overview   string           `json:"overview" validate:"required"`   // 主题简介
+	Sections   []ReportSection  `json:"sections" validate:"required"`   // 详细分析
+	SurveyNote string           `json:"survey_note,omitempty"`          // 更全面的调研笔记，可选
+	Citations  []ReportCitation `json:"citations"`                      // 引用来源
+}
+
+// ReportSection 报告章节
+type ReportSection struct {
+	Title   string        `json:"title" validate:"required"`
+	Content string        `json:"content" validate:"required"` // 章节正文，Markdown 格式，不含表格
+	Tables  []ReportTable `json:"tables,omitempty"`            // 章节中的数据表格，渲染在正文之后
+}
+
+// ReportTable 报告表格
+type ReportTable struct {
+	Caption string     `json:"caption,omitempty"`
+	Headers []string   `json:"headers" validate:"required"`
+	Rows    [][]string `json:"rows" validate:"required"`
+}
+
+// ReportCitation 引用来源
+type ReportCitation struct {
+	Title string `json:"title" validate:"required"`
+	URL   string `json:"url" validate:"required"`
+}
+
+// Markdown 将结构化报告渲染为 Markdown，章节结构与 Markdown 模式下的报告一致
+func (r *Report) Markdown() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s\n", r.Title)
+	if len(r.KeyPoints) > 0 {
+		sb.WriteString("\n## Key Points\n\n")
+		for _, p := range r.KeyPoints {
+			fmt.Fprintf(&sb, "- %s\n", p)
+		}
+	}
+	if r.Overview != "" {
+		fmt.Fprintf(&sb, "\n## Overview\n\n%s\n", strings.TrimSpace(r.Overview))
+	}
+	if len(r.Sections) > 0 {
+		sb.WriteString("\n## Detailed Analysis\n")
+		for _, s := range r.Sections {
+			fmt.Fprintf(&sb, "\n### %s\n\n", s.Title)
+			if content := strings.TrimSpace(s.Content); content != "" {
+				sb.WriteString(content + "\n")
+			}
+			for _, t := range s.Tables {
+				sb.WriteString("\n" + t.Markdown())
+			}
+		}
+	}
+	if r.SurveyNote != "" {
+		fmt.Fprintf(&sb, "\n## Survey Note\n\n%s\n", strings.TrimSpace(r.SurveyNote))
+	}
+	if len(r.Citations) > 0 {
+		sb.WriteString("\n## Key Citations\n")
+		for _, c := range r.Citations {
+			fmt.Fprintf(&sb, "\n- [%s](%s)\n", c.Title, c.URL)
+		}
+	}
+	return sb.String()
+}
+
+// Markdown 将表格渲染为 Markdown 表格，行的列数与表头不一致时补齐或截断
+func (t *ReportTable) Markdown() string {
+	if len(t.Headers) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	if t.Caption != "" {
+		fmt.Fprintf(&sb, "**%s**\n\n", t.Caption)
+	}
+	writeRow := func(cells []string) {
+		sb.WriteString("|")
+		for i := range t.Headers {
+			cell := ""
+			if i < len(cells) {
+				cell = cells[i]
+			}
+			fmt.Fprintf(&sb, " %s |", tableCell(cell))
+		}
+		sb.WriteString("\n")
+	}
+	writeRow(t.Headers)
+	sb.WriteString("|" + strings.Repeat("---|", len(t.Headers)) + "\n")
+	for _, row := range t.Rows {
+		writeRow(row)
+	}
+	return sb.String()
+}
+
+// tableCell 转义表格单元格中的竖线和换行
+func tableCell(s string) string {
+	s = strings.ReplaceAll(strings.TrimSpace(s), "|", `\|`)
+	return strings.Join(strings.Fields(strings.ReplaceAll(s, "\n", " ")), " ")
+}
@@ -0,0 +1,60 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RouteError 智能体设置的 Goto 不在流转表中，运行时校验流转失败时返回
+type RouteError struct {
+	Workflow string      `json:"workflow"`        // 运行的工作流
+	Agent    string      `json:"agent"`           // 设置 Goto 的智能体
+	Goto     string      `json:"goto"`            // 不允许的目标
+	Allowed  []string    `json:"allowed"`         // 允许流转到的目标
+	State    *RouteState `json:"state,omitempty"` // 流转时的状态摘要
+}
+
+// Error 实现 error 接口
+func (e *RouteError) Error() string {
+	msg := fmt.Sprintf("workflow %s: illegal transition from %s to %q, allowed: [%s]",
+		e.Workflow, e.Agent, e.Goto, strings.Join(e.Allowed, ", "))
+	if e.State != nil {
+		msg += fmt.Sprintf(", state: %+v", *e.State)
+	}
+	return msg
+}
+
+// RouteState 流转时的状态摘要，用于定位设置错误 Goto 的原因
+type RouteState struct {
+	PlanIterations    int    `json:"plan_iterations"`              // 已制定计划的次数
+	HasPlan           bool   `json:"has_plan"`                     // 是否已有计划
+	HasEnoughContext  bool   `json:"has_enough_context"`           // 计划是否认为上下文足够
+	TotalSteps        int    `json:"total_steps"`                  // 计划的步骤数
+	CompletedSteps    int    `json:"completed_steps"`              // 已执行的步骤数
+	FailedSteps       int    `json:"failed_steps"`                 // 重试后仍失败或跳过的步骤数
+	InterruptFeedback string `json:"interrupt_feedback,omitempty"` // 人工反馈
+	AutoAcceptedPlan  bool   `json:"auto_accepted_plan"`           // 是否自动接受计划
+}
+
+// NewRouteState 根据运行状态生成状态摘要
+func NewRouteState(state *State) *RouteState {
+	rs := &RouteState{
+		PlanIterations:    state.PlanIterations,
+		InterruptFeedback: state.InterruptFeedback,
+		AutoAcceptedPlan:  state.AutoAcceptedPlan,
+	}
+	if plan := state.CurrentPlan; plan != nil {
+		rs.HasPlan = true
+		rs.HasEnoughContext = plan.HasEnoughContext
+		rs.TotalSteps = len(plan.Steps)
+		for _, step := range plan.Steps {
+			switch step.Status {
+			case StepSucceeded:
+				rs.CompletedSteps++
+			case StepFailed, StepSkipped:
+				rs.FailedSteps++
+			}
+		}
+	}
+	return rs
+}
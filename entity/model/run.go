@@ -0,0 +1,56 @@
+package model
+
+import "time"
+
+// RunStatus 定义运行状态
+type RunStatus string
+
+const (
+	RunRunning     RunStatus = "running"     // 运行中
+	RunCompleted   RunStatus = "completed"   // 正常结束
+	RunFailed      RunStatus = "failed"      // 执行出错
+	RunInterrupted RunStatus = "interrupted" // 等待人工反馈
+	RunCancelled   RunStatus = "cancelled"   // 被取消，计划和已完成步骤的结果保留在记录中
+)
+
+// RunRecord 定义一次研究运行的归档记录
+type RunRecord struct {
+	ID         string               `json:"id"`
+	Query      string               `json:"query"`
+	Locale     string               `json:"locale,omitempty"`
+	Plan       *Plan                `json:"plan,omitempty"`
+	Report     string               `json:"report,omitempty"`
+	Structured *Report              `json:"structured_report,omitempty"` // 结构化输出模式下的结构化报告
+	Prompts    map[string]PromptRef `json:"prompts,omitempty"`           // 各智能体使用的提示词模板版本，用于对比不同模板的效果
+	Workflow   string               `json:"workflow,omitempty"`          // 使用的工作流
+	Status     RunStatus            `json:"status"`
+	Error      string               `json:"error,omitempty"`
+	Usage      *UsageSummary        `json:"usage,omitempty"`
+	Path       []NodeVisit          `json:"path,omitempty"` // 依次执行的智能体，用于在流程图上还原运行路径
+	StartedAt  time.Time            `json:"started_at"`
+	FinishedAt time.Time            `json:"finished_at,omitempty"`
+}
+
+// NodeVisit 一次智能体执行
+type NodeVisit struct {
+	Node       string    `json:"node"`            // 智能体名称
+	StartedAt  time.Time `json:"started_at"`      // 开始时间
+	DurationMs int64     `json:"duration_ms"`     // 耗时，单位毫秒
+	Error      string    `json:"error,omitempty"` // 执行出错时的错误信息
+}
+
+// PromptRef 运行使用的提示词模板版本
+type PromptRef struct {
+	Variant string `json:"variant,omitempty"` // 变体名称，默认模板为空
+	Locale  string `json:"locale,omitempty"`  // 模板语言，默认模板为空
+	Version string `json:"version"`           // 模板内容摘要
+	Source  string `json:"source"`            // 模板来源：embedded 或 override
+}
+
+// Duration 返回运行耗时
+func (r *RunRecord) Duration() time.Duration {
+	if r.FinishedAt.IsZero() {
+		return 0
+	}
+	return r.FinishedAt.Sub(r.StartedAt)
+}
@@ -12,6 +12,9 @@ type ChatRequest struct {
 	InterruptFeedback             string                 `json:"interrupt_feedback,omitempty" form:"interrupt_feedback"`
 	MCPSettings                   map[string]interface{} `json:"mcp_settings,omitempty" form:"mcp_settings"`
 	EnableBackgroundInvestigation bool                   `json:"enable_background_investigation,omitempty" form:"enable_background_investigation"`
+	StructuredReport              bool                   `json:"structured_report,omitempty" form:"structured_report"`
+	PromptVariants                map[string]string      `json:"prompt_variants,omitempty" form:"prompt_variants"`
+	Workflow                      string                 `json:"workflow,omitempty" form:"workflow"`
 }
 
 type ToolResp struct {
@@ -39,4 +42,6 @@ type ChatResp struct {
 	ToolCalls      []ToolResp               `json:"tool_calls,omitempty" form:"tool_calls"`
 	ToolCallChunks []ToolChunkResp          `json:"tool_call_chunks,omitempty" form:"tool_call_chunks"`
 	MessageChunks  any                      `json:"message_chunks,omitempty" form:"message_chunks"`
+	Usage          *UsageSummary            `json:"usage,omitempty" form:"usage"`
+	RouteError     *RouteError              `json:"route_error,omitempty" form:"route_error"`
 }
@@ -9,16 +9,24 @@ type State struct {
 	Messages []*schema.Message `json:"messages,omitempty"`
 
 	// 子图共享变量
-	Goto                           string `json:"goto,omitempty"`
-	CurrentPlan                    *Plan  `json:"current_plan,omitempty"`
-	Locale                         string `json:"locale,omitempty"`
-	PlanIterations                 int    `json:"plan_iterations,omitempty"`
-	BackgroundInvestigationResults string `json:"background_investigation_results"`
-	InterruptFeedback              string `json:"interrupt_feedback,omitempty"`
+	Goto                           string               `json:"goto,omitempty"`
+	CurrentPlan                    *Plan                `json:"current_plan,omitempty"`
+	Locale                         string               `json:"locale,omitempty"`
+	PlanIterations                 int                  `json:"plan_iterations,omitempty"`
+	BackgroundInvestigationResults string               `json:"background_investigation_results"`
+	InterruptFeedback              string               `json:"interrupt_feedback,omitempty"`
+	Usage                          *UsageSummary        `json:"usage,omitempty"`
+	FinalReport                    string               `json:"final_report,omitempty"`
+	Report                         *Report              `json:"report,omitempty"`  // 结构化输出模式下生成的结构化报告
+	Prompts                        map[string]PromptRef `json:"prompts,omitempty"` // 各智能体使用的提示词模板版本
 
 	// 全局配置变量
-	MaxPlanIterations             int  `json:"max_plan_iterations,omitempty"`
-	MaxStepNum                    int  `json:"max_step_num,omitempty"`
-	AutoAcceptedPlan              bool `json:"auto_accepted_plan"`
-	EnableBackgroundInvestigation bool `json:"enable_background_investigation"`
+	MaxPlanIterations             int               `json:"max_plan_iterations,omitempty"`
+	MaxStepNum                    int               `json:"max_step_num,omitempty"`
+	AutoAcceptedPlan              bool              `json:"auto_accepted_plan"`
+	EnableBackgroundInvestigation bool              `json:"enable_background_investigation"`
+	PlanOnly                      bool              `json:"plan_only,omitempty"`         // 仅制定计划，计划生成后结束运行
+	StructuredReport              bool              `json:"structured_report,omitempty"` // Reporter 以结构化输出生成报告
+	PromptVariants                map[string]string `json:"prompt_variants,omitempty"`   // 各智能体使用的提示词模板变体，未指定时使用默认模板
+	Workflow                      string            `json:"workflow,omitempty"`          // 本次运行使用的工作流
 }
@@ -0,0 +1,31 @@
+package model
+
+// TokenUsage 定义 token 用量及对应费用
+type TokenUsage struct {
+	PromptTokens       int     `json:"prompt_tokens"`
+	CachedPromptTokens int     `json:"cached_prompt_tokens,omitempty"`
+	CompletionTokens   int     `json:"completion_tokens"`
+	TotalTokens        int     `json:"total_tokens"`
+	Requests           int     `json:"requests"`
+	Cost               float64 `json:"cost"`
+}
+
+// Add 累加另一份用量
+func (u *TokenUsage) Add(o TokenUsage) {
+	u.PromptTokens += o.PromptTokens
+	u.CachedPromptTokens += o.CachedPromptTokens
+	u.CompletionTokens += o.CompletionTokens
+	u.TotalTokens += o.TotalTokens
+	u.Requests += o.Requests
+	u.Cost += o.Cost
+}
+
+// UsageSummary 定义单次运行的用量汇总，分别按 agent 和模型聚合
+type UsageSummary struct {
+	Total          TokenUsage             `json:"total"`
+	ByAgent        map[string]*TokenUsage `json:"by_agent,omitempty"`
+	ByModel        map[string]*TokenUsage `json:"by_model,omitempty"`
+	MaxTokens      int                    `json:"max_tokens,omitempty"`
+	MaxCost        float64                `json:"max_cost,omitempty"`
+	BudgetExceeded bool                   `json:"budget_exceeded,omitempty"`
+}
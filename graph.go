@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/hildam/deer-flow-go/agent"
+	"github.com/hildam/deer-flow-go/entity/model"
+	"github.com/hildam/deer-flow-go/repo/flowchart"
+	"github.com/hildam/deer-flow-go/repo/history"
+	"github.com/hildam/deer-flow-go/repo/template"
+	"github.com/hildam/deer-flow-go/repo/workflow"
+)
+
+// 流程图格式
+const (
+	formatMermaid = "mermaid"
+	formatDOT     = "dot"
+)
+
+const graphUsage = `用法：deer-flow-go graph [--workflow NAME] [--run THREAD_ID] [--format mermaid|dot] [--output FILE]
+`
+
+// runGraph 导出编译后的代理图，包含各智能体内部的子图，可叠加一次运行的执行路径和耗时
+func runGraph(args []string) error {
+	fs := flag.NewFlagSet("graph", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, graphUsage)
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&configPath, "config", configPath, "配置文件路径")
+	name := fs.String("workflow", "", "工作流名称，默认使用运行记录的工作流或配置的默认工作流")
+	run := fs.String("run", "", "叠加该线程运行记录中的执行路径和各智能体耗时")
+	format := fs.String("format", formatMermaid, "输出格式：mermaid 或 dot")
+	output := fs.String("output", "", "输出文件，默认输出到标准输出")
+	_ = fs.Parse(args)
+	if *format != formatMermaid && *format != formatDOT {
+		fs.Usage()
+		return exitWith(exitUsage, nil)
+	}
+
+	defer func() {
+		_ = template.Close()
+	}()
+	if err := initAll(initConf, template.Init, workflow.Init); err != nil {
+		return err
+	}
+
+	// 叠加运行路径时只读查询运行历史
+	var rec *model.RunRecord
+	if *run != "" {
+		if err := history.InitReadOnly(); err != nil {
+			return err
+		}
+		defer func() {
+			_ = history.Close()
+		}()
+		var err error
+		if rec, err = history.Get(*run); err != nil {
+			return fmt.Errorf("graph failed, get run %s err: %w", *run, err)
+		}
+		if *name == "" {
+			*name = rec.Workflow
+		}
+	}
+
+	info, err := agent.InspectWorkflowGraph(context.Background(), *name)
+	if err != nil {
+		return fmt.Errorf("graph failed, err: %w", err)
+	}
+	g := flowchart.FromGraphInfo(info)
+	var overlay *flowchart.Overlay
+	if rec != nil {
+		overlay = flowchart.NewOverlay(rec)
+	}
+
+	out := g.Mermaid(overlay)
+	if *format == formatDOT {
+		out = g.DOT(overlay)
+	}
+	if err := writeOutput(*output, []byte(out)); err != nil {
+		return fmt.Errorf("graph failed, write output err: %w", err)
+	}
+	return nil
+}
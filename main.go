@@ -1,69 +1,183 @@
 package main
 
 import (
-	"bufio"
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"os"
-	"strings"
 
-	"github.com/HildaM/logs/slog"
-	"github.com/cloudwego/eino/compose"
-	"github.com/cloudwego/eino/schema"
-	"github.com/hildam/deer-flow-go/agent"
+	"github.com/cloudwego/hertz/pkg/app/server"
 	"github.com/hildam/deer-flow-go/entity/conf"
-	"github.com/hildam/deer-flow-go/entity/consts"
-	"github.com/hildam/deer-flow-go/repo/callback"
+	"github.com/hildam/deer-flow-go/repo/artifact"
+	"github.com/hildam/deer-flow-go/repo/history"
 	"github.com/hildam/deer-flow-go/repo/mcp"
+	"github.com/hildam/deer-flow-go/repo/replay"
+	"github.com/hildam/deer-flow-go/repo/template"
+	"github.com/hildam/deer-flow-go/repo/tracing"
+	"github.com/hildam/deer-flow-go/repo/workflow"
 )
 
+const usage = `用法：deer-flow-go [--config FILE] <命令> [参数]
+
+命令：
+  console       交互式控制台（默认）
+  research      执行一次研究并输出报告
+  plan          仅制定研究计划
+  serve         启动HTTP服务
+  tools list    列出可用的MCP工具
+  mcp status    查看MCP服务连接状态
+  prompts list  列出提示词模板及校验结果
+  workflows     查看工作流及流转表（list/mermaid）
+  graph         导出编译后的代理图（Mermaid/DOT），可叠加运行路径
+  batch         批量研究文件中的问题
+  runs          管理运行历史（list/search/get/delete/export）
+
+使用 deer-flow-go <命令> -h 查看命令参数。
+`
+
+var configPath string // 配置文件路径
+
 func main() {
-	runConsule()
+	flag.StringVar(&configPath, "config", "config.yaml", "配置文件路径")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+	}
+	flag.Parse()
+
+	args := flag.Args()
+	cmd := "console"
+	if len(args) > 0 {
+		cmd, args = args[0], args[1:]
+	}
+	// 命令返回后其延迟的资源释放已完成，再按错误退出
+	if err := runCommand(cmd, args); err != nil {
+		os.Exit(exitCode(err))
+	}
+}
+
+// runCommand 执行子命令
+func runCommand(cmd string, args []string) error {
+	switch cmd {
+	case "console":
+		return runConsule()
+	case "research":
+		return runResearch(args)
+	case "plan":
+		return runPlan(args)
+	case "serve":
+		return runServer(args)
+	case "tools":
+		return runTools(args)
+	case "mcp":
+		return runMcp(args)
+	case "batch":
+		return runBatch(args)
+	case "runs":
+		return runRuns(args)
+	case "prompts":
+		return runPrompts(args)
+	case "workflows":
+		return runWorkflows(args)
+	case "graph":
+		return runGraph(args)
+	case "help":
+		fmt.Print(usage)
+		return nil
+	default:
+		fmt.Fprintf(os.Stderr, "未知命令 %s\n\n", cmd)
+		flag.Usage()
+		return exitWith(exitUsage, nil)
+	}
+}
+
+// exitError 指定退出码的错误
+type exitError struct {
+	code int
+	err  error // 需要输出的错误，为空表示已输出提示
+}
+
+func (e *exitError) Error() string {
+	if e.err == nil {
+		return fmt.Sprintf("exit status %d", e.code)
+	}
+	return e.err.Error()
 }
 
-// runConsule 运行控制台
-func runConsule() {
-	ctx := context.Background()
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// exitWith 以指定的退出码结束命令
+func exitWith(code int, err error) error {
+	return &exitError{code: code, err: err}
+}
+
+// exitCode 输出错误并返回退出码，未指定退出码的错误按运行失败处理
+func exitCode(err error) int {
+	var ee *exitError
+	if !errors.As(err, &ee) {
+		log.Print(err)
+		return exitFailed
+	}
+	// 输出完整的错误链，保留外层调用补充的上下文
+	if ee.err != nil {
+		log.Print(err)
+	}
+	return ee.code
+}
+
+// initConf 按命令行指定的路径加载配置
+func initConf() error {
+	conf.SetPath(configPath)
+	return conf.Init()
+}
+
+// initDeps 初始化配置、提示词模板、工作流、链路追踪、运行历史、产物存储、录制回放和MCP服务
+// 失败时已初始化的资源仍需调用 closeDeps 释放
+func initDeps() error {
+	return initAll(initConf, template.Init, workflow.Init, tracing.Init, history.Init, artifact.Init, replay.Init, mcp.InitMcpServer)
+}
 
-	// 初始化配置
-	funcs := []func() error{conf.Init, mcp.InitMcpServer}
+// initAll 依次执行初始化，遇到错误时停止
+func initAll(funcs ...func() error) error {
 	for _, f := range funcs {
 		if err := f(); err != nil {
-			log.Fatal(err)
+			return err
 		}
 	}
+	return nil
+}
 
-	// 读取用户终端输入
-	reader := bufio.NewReader(os.Stdin)
-	fmt.Print("请输入你的需求： ")
+// closeDeps 释放 initDeps 初始化的资源
+func closeDeps() {
+	_ = tracing.Shutdown(context.Background())
+	_ = history.Close()
+	_ = replay.Close()
+	_ = template.Close()
+}
 
-	// 构造用户输入Prompt
-	userPrompt, _ := reader.ReadString('\n')
-	userPrompt = strings.TrimSpace(userPrompt) // 去除换行符
-	userMessage := []*schema.Message{
-		schema.UserMessage(userPrompt),
-	}
+// runServer 运行HTTP服务
+func runServer(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	fs.StringVar(&configPath, "config", configPath, "配置文件路径")
+	addr := fs.String("addr", "", "监听地址，默认使用配置中的 server.addr")
+	_ = fs.Parse(args)
 
-	// 创建 Agent 工作流
-	graph, err := agent.BuildAgentGraph[string, string](ctx, userMessage)
-	if err != nil {
-		slog.Fatal("BuildAgentGraph failed, err: %v", err)
+	defer closeDeps()
+	if err := initDeps(); err != nil {
+		return err
 	}
 
-	// 流式输出
-	outChan := make(chan string)
-	go func() {
-		for out := range outChan {
-			fmt.Print(out)
-		}
-	}()
-
-	_, err = graph.Stream(ctx, consts.Coordinator,
-		compose.WithCallbacks(&callback.LoggerCallback{
-			Out: outChan,
-		}))
-	if err != nil {
-		slog.Error("Stream failed, err: %v", err)
+	if *addr == "" {
+		*addr = conf.GetCfg().Server.Addr
+	}
+	if *addr == "" {
+		*addr = ":8888"
 	}
+	h := server.Default(server.WithHostPorts(*addr))
+	register(h)
+	h.Spin()
+	return nil
 }
@@ -1,7 +1,15 @@
 import asyncio
+import importlib
 import io
-import subprocess
+import multiprocessing
+import os
 import re
+import resource
+import shutil
+import subprocess
+import sys
+import tempfile
+import time
 from contextlib import redirect_stdout, redirect_stderr
 import traceback
 from mcp.server import Server, NotificationOptions
@@ -9,29 +17,259 @@ from mcp.server.models import InitializationOptions
 import mcp.server.stdio
 import mcp.types as types
 
+# Session used when the caller does not send _meta.session_id
+DEFAULT_SESSION = "default"
+
+# Limits, configurable through the MCP server env in config.yaml
+IDLE_TIMEOUT = float(os.environ.get("PYTHON_REPL_IDLE_TIMEOUT", "1800"))  # seconds before an idle session is closed
+EXEC_TIMEOUT = float(os.environ.get("PYTHON_REPL_EXEC_TIMEOUT", "120"))  # seconds per execution
+INSTALL_TIMEOUT = float(os.environ.get("PYTHON_REPL_INSTALL_TIMEOUT", "300"))  # seconds per package install
+MEMORY_LIMIT_MB = int(os.environ.get("PYTHON_REPL_MEMORY_LIMIT_MB", "1024"))  # address space per session, 0 disables
+MAX_SESSIONS = int(os.environ.get("PYTHON_REPL_MAX_SESSIONS", "16"))  # least recently used idle sessions are evicted
+
+
+def _safe_repr(value) -> str:
+    try:
+        return repr(value)
+    except Exception as e:  # noqa: BLE001
+        return f"<unrepresentable {type(value).__name__}: {e}>"
+
+
+def _execute(code: str, namespace: dict) -> str:
+    """Execute code in the namespace and format the output"""
+    stdout = io.StringIO()
+    stderr = io.StringIO()
+    try:
+        # Execute code with output redirection
+        with redirect_stdout(stdout), redirect_stderr(stderr):
+            exec(code, namespace)
+    except BaseException:  # noqa: BLE001
+        # Capture and format any exceptions, including MemoryError and SystemExit
+        return f"Error executing code:\n{traceback.format_exc()}"
+
+    # Combine output
+    output = stdout.getvalue()
+    errors = stderr.getvalue()
+
+    # Format response
+    result = ""
+    if output:
+        result += f"Output:\n{output}"
+    if errors:
+        result += f"\nErrors:\n{errors}"
+    if not output and not errors:
+        # Try to get the value of the last expression
+        try:
+            last_line = code.strip().split('\n')[-1]
+            last_value = eval(last_line, namespace)
+            result = f"Result: {_safe_repr(last_value)}"
+        except Exception:  # noqa: BLE001
+            result = "Code executed successfully (no output)"
+    return result
+
+
+def _worker(conn, memory_limit_mb: int, site_dir: str):
+    """Session process: owns one namespace and serves requests from the server"""
+    if memory_limit_mb > 0:
+        limit = memory_limit_mb * 1024 * 1024
+        resource.setrlimit(resource.RLIMIT_AS, (limit, limit))
+    # Packages installed for this session only
+    sys.path.insert(0, site_dir)
+    os.chdir(site_dir)
+
+    namespace = {"__builtins__": __builtins__}
+    while True:
+        try:
+            op, arg = conn.recv()
+        except EOFError:
+            return
+
+        if op == "execute":
+            conn.send(_execute(arg, namespace))
+        elif op == "reset":
+            namespace.clear()
+            namespace["__builtins__"] = __builtins__
+            conn.send("Python session reset. All variables cleared.")
+        elif op == "variables":
+            # Filter out builtins and private variables
+            conn.send({
+                k: _safe_repr(v) for k, v in namespace.items()
+                if not k.startswith('_') and k != '__builtins__'
+            })
+        elif op == "import":
+            try:
+                importlib.invalidate_caches()
+                exec(f"import {arg}", namespace)
+                conn.send(None)
+            except BaseException as e:  # noqa: BLE001
+                conn.send(str(e))
+        else:
+            conn.send(f"Unknown operation: {op}")
+
+
+class SessionError(Exception):
+    """The session process timed out or died, its state is lost"""
+
+
+class Session:
+    """An isolated Python namespace running in its own process"""
+
+    def __init__(self, session_id: str):
+        self.id = session_id
+        self.site_dir = tempfile.mkdtemp(prefix="python-repl-")
+        self.lock = asyncio.Lock()
+        self.last_used = time.monotonic()
+
+        # spawn avoids inheriting the server's event loop and threads
+        ctx = multiprocessing.get_context("spawn")
+        self.conn, child_conn = ctx.Pipe()
+        self.process = ctx.Process(
+            target=_worker,
+            args=(child_conn, MEMORY_LIMIT_MB, self.site_dir),
+            daemon=True,
+        )
+        self.process.start()
+        child_conn.close()
+
+    def call(self, op: str, arg=None, timeout: float = EXEC_TIMEOUT):
+        """Send a request to the session process and wait for the reply (blocking)"""
+        self.last_used = time.monotonic()
+        try:
+            self.conn.send((op, arg))
+            if not self.conn.poll(timeout):
+                raise SessionError(f"execution exceeded {timeout:.0f}s, session terminated and all variables lost")
+            return self.conn.recv()
+        except (EOFError, OSError) as e:
+            raise SessionError(f"session process exited (possibly out of memory, limit {MEMORY_LIMIT_MB}MB), all variables lost") from e
+        finally:
+            self.last_used = time.monotonic()
+
+    def close(self):
+        self.conn.close()
+        if self.process.is_alive():
+            self.process.kill()
+        self.process.join(timeout=5)
+        shutil.rmtree(self.site_dir, ignore_errors=True)
+
+
+def install_package(session: Session, package: str) -> str:
+    """Install a package into the session's directory and import it in the session (blocking)"""
+    try:
+        subprocess.run(
+            ["uv", "pip", "install", "--python", sys.executable, "--target", session.site_dir, package],
+            capture_output=True,
+            text=True,
+            check=True,
+            timeout=INSTALL_TIMEOUT,
+        )
+    except subprocess.CalledProcessError as e:
+        return f"Failed to install package:\n{e.stderr}"
+    except subprocess.TimeoutExpired:
+        return f"Failed to install package: timed out after {INSTALL_TIMEOUT:.0f}s"
+
+    # Import the package to make it available in the REPL
+    module = re.split(r"[\[=<>,]", package)[0].replace("-", "_")
+    err = session.call("import", module)
+    if err:
+        return f"Package installed but import failed: {err}"
+    return f"Successfully installed and imported {package}"
+
+
+class SessionManager:
+    """Keeps one Session per session ID, evicting idle and least recently used sessions"""
+
+    def __init__(self):
+        self.sessions: dict[str, Session] = {}
+
+    def get(self, session_id: str) -> Session:
+        session = self.sessions.get(session_id)
+        if session is not None:
+            return session
+        while len(self.sessions) >= MAX_SESSIONS > 0:
+            # Sessions running a request are never evicted, as in cleanup()
+            idle = [s for s in self.sessions.values() if not s.lock.locked()]
+            if not idle:
+                raise SessionError(f"all {MAX_SESSIONS} python sessions are busy, try again later")
+            oldest = min(idle, key=lambda s: s.last_used)
+            print(f"evicting python session {oldest.id}", file=sys.stderr)
+            self.close(oldest.id)
+        session = Session(session_id)
+        self.sessions[session_id] = session
+        return session
+
+    async def call(self, session_id: str, op: str, arg=None, timeout: float = EXEC_TIMEOUT):
+        """Run a request in the session, serialized per session; a failed session is discarded"""
+        return await self.run_locked(session_id, lambda session: session.call(op, arg, timeout))
+
+    async def run_locked(self, session_id: str, fn):
+        """Run fn(session) in a thread while holding the session lock; a failed session is discarded
+
+        The held lock and the refreshed last_used keep the session from being evicted or
+        used by another request until fn returns.
+        """
+        session = self.get(session_id)
+        async with session.lock:
+            session.last_used = time.monotonic()
+            try:
+                return await asyncio.to_thread(fn, session)
+            except SessionError:
+                self.close(session_id)
+                raise
+            finally:
+                session.last_used = time.monotonic()
+
+    def close(self, session_id: str) -> bool:
+        session = self.sessions.pop(session_id, None)
+        if session is None:
+            return False
+        session.close()
+        return True
+
+    def close_all(self):
+        for session_id in list(self.sessions):
+            self.close(session_id)
+
+    async def cleanup(self):
+        """Periodically close sessions that have been idle longer than IDLE_TIMEOUT"""
+        while True:
+            await asyncio.sleep(min(60.0, IDLE_TIMEOUT))
+            now = time.monotonic()
+            for session in list(self.sessions.values()):
+                if not session.lock.locked() and now - session.last_used > IDLE_TIMEOUT:
+                    print(f"closing idle python session {session.id}", file=sys.stderr)
+                    self.close(session.id)
+
+
 class PythonREPLServer:
     def __init__(self):
         self.server = Server("python-repl")
-        # Shared namespace for all executions
-        self.global_namespace = {
-            "__builtins__": __builtins__,
-        }
-        print("python mcp server init success!")
+        # One namespace per session, see SessionManager
+        self.sessions = SessionManager()
+        print("python mcp server init success!", file=sys.stderr)
         # Set up handlers using decorators
         @self.server.list_tools()
         async def handle_list_tools() -> list[types.Tool]:
             return await self.handle_list_tools()
-            
+
         @self.server.call_tool()
         async def handle_call_tool(name: str, arguments: dict | None) -> list[types.TextContent | types.ImageContent | types.EmbeddedResource]:
             return await self.handle_call_tool(name, arguments)
 
+    def session_id(self) -> str:
+        """Session ID sent by the caller in _meta.session_id"""
+        try:
+            meta = self.server.request_context.meta
+        except LookupError:
+            return DEFAULT_SESSION
+        session_id = getattr(meta, "session_id", None) if meta is not None else None
+        return str(session_id) if session_id else DEFAULT_SESSION
+
     async def handle_list_tools(self) -> list[types.Tool]:
         """List available tools"""
         return [
             types.Tool(
                 name="execute_python",
-                description="Execute Python code and return the output. Variables persist between executions.",
+                description="Execute Python code and return the output. Variables persist between executions within the same research run.",
                 inputSchema={
                     "type": "object",
                     "properties": {
@@ -58,7 +296,7 @@ class PythonREPLServer:
             ),
             types.Tool(
                 name="install_package",
-                description="Install a Python package using uv",
+                description="Install a Python package using uv, available to the current session only",
                 inputSchema={
                     "type": "object",
                     "properties": {
@@ -69,16 +307,30 @@ class PythonREPLServer:
                     },
                     "required": ["package"],
                 },
-            )
+            ),
+            types.Tool(
+                name="close_session",
+                description="Close the current session and release its variables and installed packages. Called by the host when a run completes.",
+                inputSchema={
+                    "type": "object",
+                    "properties": {},
+                },
+            ),
         ]
 
     async def handle_call_tool(
         self, name: str, arguments: dict | None
     ) -> list[types.TextContent | types.ImageContent | types.EmbeddedResource]:
         """Handle tool execution requests"""
-        if not arguments:
-            raise ValueError("Missing arguments")
-        
+        arguments = arguments or {}
+        session_id = self.session_id()
+        try:
+            text = await self.call_tool(session_id, name, arguments)
+        except SessionError as e:
+            text = f"Python session error: {e}"
+        return [types.TextContent(type="text", text=text)]
+
+    async def call_tool(self, session_id: str, name: str, arguments: dict) -> str:
         if name == "execute_python":
             code = arguments.get("code")
             if not code:
@@ -86,158 +338,59 @@ class PythonREPLServer:
 
             # Check if we should reset the session
             if arguments.get("reset", False):
-                self.global_namespace.clear()
-                self.global_namespace["__builtins__"] = __builtins__
-                return [
-                    types.TextContent(
-                        type="text",
-                        text="Python session reset. All variables cleared."
-                    )
-                ]
-
-            # Capture stdout and stderr
-            stdout = io.StringIO()
-            stderr = io.StringIO()
-            
-            try:
-                # Execute code with output redirection
-                with redirect_stdout(stdout), redirect_stderr(stderr):
-                    exec(code, self.global_namespace)
-                
-                # Combine output
-                output = stdout.getvalue()
-                errors = stderr.getvalue()
-                
-                # Format response
-                result = ""
-                if output:
-                    result += f"Output:\n{output}"
-                if errors:
-                    result += f"\nErrors:\n{errors}"
-                if not output and not errors:
-                    # Try to get the value of the last expression
-                    try:
-                        last_line = code.strip().split('\n')[-1]
-                        last_value = eval(last_line, self.global_namespace)
-                        result = f"Result: {repr(last_value)}"
-                    except (SyntaxError, ValueError, NameError):
-                        result = "Code executed successfully (no output)"
-                
-                return [
-                    types.TextContent(
-                        type="text",
-                        text=result
-                    )
-                ]
-                    
-            except Exception as e:  # noqa: F841
-                # Capture and format any exceptions
-                error_msg = f"Error executing code:\n{traceback.format_exc()}"
-                return [
-                    types.TextContent(
-                        type="text",
-                        text=error_msg
-                    )
-                ]
+                return await self.sessions.call(session_id, "reset")
+            return await self.sessions.call(session_id, "execute", code)
 
         elif name == "install_package":
             package = arguments.get("package")
             if not package:
                 raise ValueError("Missing package name")
-                
+
             # Basic package name validation
-            if not re.match("^[A-Za-z0-9][A-Za-z0-9._-]*$", package):
-                return [
-                    types.TextContent(
-                        type="text",
-                        text=f"Invalid package name: {package}"
-                    )
-                ]
-            
-            try:
-                # Install package using uv
-                process = subprocess.run(
-                    ["uv", "pip", "install", package],
-                    capture_output=True,
-                    text=True,
-                    check=True
-                )
+            if not re.match(r"^[A-Za-z0-9][A-Za-z0-9._\-\[\],=<>]*$", package):
+                return f"Invalid package name: {package}"
+
+            # Install into the session's own directory so other sessions are unaffected,
+            # holding the session so it is not evicted or used by another request meanwhile
+            return await self.sessions.run_locked(session_id, lambda session: install_package(session, package))
 
-                if process.returncode != 0:
-                    return [
-                        types.TextContent(
-                            type="text",
-                            text=f"Failed to install package: {process.stderr}"
-                        )
-                    ]
-                
-                # Import the package to make it available in the REPL
-                try:
-                    exec(f"import {package.split('[')[0]}", self.global_namespace)
-                    return [
-                        types.TextContent(
-                            type="text",
-                            text=f"Successfully installed and imported {package}"
-                        )
-                    ]
-                except ImportError as e:
-                    return [
-                        types.TextContent(
-                            type="text",
-                            text=f"Package installed but import failed: {str(e)}"
-                        )
-                    ]
-                    
-            except subprocess.CalledProcessError as e:
-                return [
-                    types.TextContent(
-                        type="text",
-                        text=f"Failed to install package:\n{e.stderr}"
-                    )
-                ]
-                
         elif name == "list_variables":
-            # Filter out builtins and private variables
-            vars_dict = {
-                k: repr(v) for k, v in self.global_namespace.items() 
-                if not k.startswith('_') and k != '__builtins__'
-            }
-            
+            vars_dict = await self.sessions.call(session_id, "variables")
             if not vars_dict:
-                return [
-                    types.TextContent(
-                        type="text",
-                        text="No variables in current session."
-                    )
-                ]
-            
+                return "No variables in current session."
+
             # Format variables list
             var_list = "\n".join(f"{k} = {v}" for k, v in vars_dict.items())
-            return [
-                types.TextContent(
-                    type="text",
-                    text=f"Current session variables:\n\n{var_list}"
-                )
-            ]
-            
+            return f"Current session variables:\n\n{var_list}"
+
+        elif name == "close_session":
+            if self.sessions.close(session_id):
+                return f"Session {session_id} closed."
+            return f"Session {session_id} not found."
+
         else:
             raise ValueError(f"Unknown tool: {name}")
 
     async def run(self):
         """Run the server"""
-        async with mcp.server.stdio.stdio_server() as (read_stream, write_stream):
-            await self.server.run(
-                read_stream,
-                write_stream,
-                InitializationOptions(
-                    server_name="python-repl",
-                    server_version="0.1.0",
-                    capabilities=self.server.get_capabilities(
-                        notification_options=NotificationOptions(),
-                        experimental_capabilities={},
+        cleanup = asyncio.create_task(self.sessions.cleanup())
+        try:
+            async with mcp.server.stdio.stdio_server() as (read_stream, write_stream):
+                await self.server.run(
+                    read_stream,
+                    write_stream,
+                    InitializationOptions(
+                        server_name="python-repl",
+                        server_version="0.2.0",
+                        capabilities=self.server.get_capabilities(
+                            notification_options=NotificationOptions(),
+                            experimental_capabilities={},
+                        ),
                     ),
-                ),
-            )
+                )
+        finally:
+            cleanup.cancel()
+            self.sessions.close_all()
 
 async def main():
     server = PythonREPLServer()
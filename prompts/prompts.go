@@ -0,0 +1,11 @@
+// Package prompts 内置的提示词模板，编译进二进制文件，保证在任意工作目录下都能运行
+//
+// 默认模板为 <name>.md，特定语言的模板放在以 locale 命名的子目录中，如 zh-CN/planner.md
+package prompts
+
+import "embed"
+
+// FS 内置的提示词模板
+//
+//go:embed *.md */*.md
+var FS embed.FS
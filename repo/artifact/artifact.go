@@ -0,0 +1,214 @@
+package artifact
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/hildam/deer-flow-go/entity/conf"
+	"github.com/hildam/deer-flow-go/entity/model"
+)
+
+const (
+	backendLocal = "local"
+	defaultDir   = "data/artifacts"
+)
+
+var (
+	// ErrNotFound 产物不存在
+	ErrNotFound = errors.New("artifact not found")
+	// ErrNotInitialized 产物存储未初始化
+	ErrNotInitialized = errors.New("artifact store not initialized")
+	// ErrInvalidName 运行ID或产物名称不合法
+	ErrInvalidName = errors.New("invalid artifact name")
+)
+
+// Store 产物存储后端，按运行隔离，同一运行内以文件名唯一标识
+// 实现需支持并发调用
+type Store interface {
+	// Put 保存产物，同名产物会被覆盖，保存后回填大小、MIME 类型和保存时间
+	Put(ctx context.Context, a *model.Artifact, r io.Reader) error
+	// List 按保存顺序列出运行的全部产物
+	List(ctx context.Context, runID string) ([]*model.Artifact, error)
+	// Open 读取产物内容，调用方负责关闭
+	Open(ctx context.Context, runID, name string) (*model.Artifact, io.ReadCloser, error)
+	// DeleteRun 删除运行的全部产物
+	DeleteRun(ctx context.Context, runID string) error
+}
+
+var (
+	storeMu sync.RWMutex
+	store   Store // 当前使用的存储后端
+)
+
+// Init 根据配置初始化产物存储
+func Init() error {
+	cfg := conf.GetCfg().Artifact
+	switch cfg.Backend {
+	case "", backendLocal:
+		dir := cfg.Dir
+		if dir == "" {
+			dir = defaultDir
+		}
+		SetStore(NewLocalStore(dir))
+		return nil
+	default:
+		return fmt.Errorf("Init artifact failed, unknown backend: %s", cfg.Backend)
+	}
+}
+
+// SetStore 替换存储后端，用于接入对象存储等自定义实现
+func SetStore(s Store) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	store = s
+}
+
+// current 获取当前存储后端
+func current() (Store, error) {
+	storeMu.RLock()
+	defer storeMu.RUnlock()
+	if store == nil {
+		return nil, ErrNotInitialized
+	}
+	return store, nil
+}
+
+// Put 保存产物
+func Put(ctx context.Context, a *model.Artifact, r io.Reader) error {
+	s, err := current()
+	if err != nil {
+		return err
+	}
+	name, err := CleanName(a.Name)
+	if err != nil {
+		return err
+	}
+	if err := checkRunID(a.RunID); err != nil {
+		return err
+	}
+	a.Name = name
+	return s.Put(ctx, a, r)
+}
+
+// List 列出运行的全部产物，存储未初始化时视为没有产物
+func List(ctx context.Context, runID string) ([]*model.Artifact, error) {
+	s, err := current()
+	if err != nil {
+		return nil, nil
+	}
+	if err := checkRunID(runID); err != nil {
+		return nil, err
+	}
+	return s.List(ctx, runID)
+}
+
+// Open 读取产物内容
+func Open(ctx context.Context, runID, name string) (*model.Artifact, io.ReadCloser, error) {
+	s, err := current()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkRunID(runID); err != nil {
+		return nil, nil, err
+	}
+	if name, err = CleanName(name); err != nil {
+		return nil, nil, err
+	}
+	return s.Open(ctx, runID, name)
+}
+
+// DeleteRun 删除运行的全部产物，存储未初始化时忽略
+func DeleteRun(ctx context.Context, runID string) error {
+	s, err := current()
+	if err != nil {
+		return nil
+	}
+	if err := checkRunID(runID); err != nil {
+		return err
+	}
+	return s.DeleteRun(ctx, runID)
+}
+
+// CleanName 规范化产物名称，拒绝绝对路径和指向上级目录的名称
+func CleanName(name string) (string, error) {
+	name = path.Clean(strings.TrimPrefix(filepath.ToSlash(name), "/"))
+	if name == "." || name == ".." || strings.HasPrefix(name, "../") {
+		return "", fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+	return name, nil
+}
+
+// checkRunID 校验运行ID，运行ID来自请求参数且用作目录名
+func checkRunID(runID string) error {
+	if runID == "" || runID == "." || runID == ".." || strings.ContainsAny(runID, `/\`) {
+		return fmt.Errorf("%w: run id %q", ErrInvalidName, runID)
+	}
+	return nil
+}
+
+// DetectMIME 根据文件扩展名和内容推断 MIME 类型
+func DetectMIME(name string, head []byte) string {
+	if t := mime.TypeByExtension(path.Ext(name)); t != "" {
+		return t
+	}
+	return http.DetectContentType(head)
+}
+
+// IsText 判断 MIME 类型是否为文本，文本产物可直接提供给智能体阅读
+func IsText(mimeType string) bool {
+	t, _, _ := mime.ParseMediaType(mimeType)
+	switch {
+	case strings.HasPrefix(t, "text/"):
+		return true
+	case t == "application/json", t == "application/xml", t == "application/yaml", t == "application/x-yaml":
+		return true
+	}
+	return false
+}
+
+// IsImage 判断 MIME 类型是否为图片，图片产物可嵌入报告
+func IsImage(mimeType string) bool {
+	return strings.HasPrefix(mimeType, "image/")
+}
+
+// URL 产物的下载地址，对应 HTTP 服务的 GET /api/runs/:thread_id/artifacts/*name
+func URL(runID, name string) string {
+	segments := strings.Split(name, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return "/api/runs/" + url.PathEscape(runID) + "/artifacts/" + strings.Join(segments, "/")
+}
+
+// producerKey 上下文中存放产物生成者的 key
+type producerKey struct{}
+
+// Producer 产物的生成者，由执行步骤的智能体写入上下文，工具保存产物时记录
+type Producer struct {
+	Agent     string // 智能体名称
+	Step      int    // 计划步骤序号，从 1 开始
+	StepTitle string // 计划步骤标题
+}
+
+// WithProducer 将生成者写入上下文
+func WithProducer(ctx context.Context, p Producer) context.Context {
+	return context.WithValue(ctx, producerKey{}, p)
+}
+
+// New 创建运行内的产物元信息，生成者从上下文中获取
+func New(ctx context.Context, runID, name, tool string) *model.Artifact {
+	a := &model.Artifact{RunID: runID, Name: name, Tool: tool}
+	if p, ok := ctx.Value(producerKey{}).(Producer); ok {
+		a.Agent, a.Step, a.StepTitle = p.Agent, p.Step, p.StepTitle
+	}
+	return a
+}
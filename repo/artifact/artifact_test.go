@@ -0,0 +1,99 @@
+package artifact
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestLocalStore(t *testing.T) {
+	SetStore(NewLocalStore(t.TempDir()))
+	ctx := WithProducer(context.Background(), Producer{Agent: "coder", Step: 2, StepTitle: "Plot"})
+
+	a := New(ctx, "run-1", "charts/plot.png", "execute_code")
+	if err := Put(ctx, a, strings.NewReader("\x89PNG\r\n\x1a\n")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if a.MIMEType != "image/png" || a.Size != 8 || a.Step != 2 || a.Agent != "coder" {
+		t.Errorf("artifact = %+v", a)
+	}
+	// 同名产物覆盖原有内容
+	if err := Put(ctx, New(ctx, "run-1", "data.csv", ""), strings.NewReader("a,b\n")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := Put(ctx, New(ctx, "run-1", "data.csv", ""), strings.NewReader("a,b\n1,2\n")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	items, err := List(ctx, "run-1")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(items) != 2 || items[0].Name != "charts/plot.png" || items[1].Name != "data.csv" {
+		t.Fatalf("List = %+v", items)
+	}
+
+	got, rc, err := Open(ctx, "run-1", "/data.csv")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	data, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(data) != "a,b\n1,2\n" || got.Size != 8 || !IsText(got.MIMEType) {
+		t.Errorf("Open = %+v, %q", got, data)
+	}
+
+	if _, _, err := Open(ctx, "run-2", "data.csv"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open other run err = %v, want ErrNotFound", err)
+	}
+	if err := DeleteRun(ctx, "run-1"); err != nil {
+		t.Fatalf("DeleteRun: %v", err)
+	}
+	if items, _ := List(ctx, "run-1"); len(items) != 0 {
+		t.Errorf("List after delete = %+v", items)
+	}
+}
+
+func TestInvalidNames(t *testing.T) {
+	SetStore(NewLocalStore(t.TempDir()))
+	ctx := context.Background()
+	for _, name := range []string{"../secret", "a/../../b", ".", ".artifacts.json"} {
+		if err := Put(ctx, New(ctx, "run-1", name, ""), strings.NewReader("x")); !errors.Is(err, ErrInvalidName) {
+			t.Errorf("Put(%q) err = %v, want ErrInvalidName", name, err)
+		}
+	}
+	for _, runID := range []string{"", "..", "a/b"} {
+		if _, _, err := Open(ctx, runID, "x"); !errors.Is(err, ErrInvalidName) {
+			t.Errorf("Open(%q) err = %v, want ErrInvalidName", runID, err)
+		}
+	}
+}
+
+func TestLocalStoreConcurrent(t *testing.T) {
+	SetStore(NewLocalStore(t.TempDir()))
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := string(rune('a'+i)) + ".txt"
+			if err := Put(ctx, New(ctx, "run-1", name, ""), strings.NewReader(name)); err != nil {
+				t.Errorf("Put: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if items, _ := List(ctx, "run-1"); len(items) != 10 {
+		t.Errorf("List = %d items, want 10", len(items))
+	}
+}
+
+func TestURL(t *testing.T) {
+	if got, want := URL("run 1", "charts/a b.png"), "/api/runs/run%201/artifacts/charts/a%20b.png"; got != want {
+		t.Errorf("URL = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,146 @@
+package artifact
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/hildam/deer-flow-go/entity/model"
+)
+
+// indexFile 运行目录中保存产物元信息的文件
+const indexFile = ".artifacts.json"
+
+// localStore 本地文件系统存储
+// 产物保存在 <dir>/<run_id>/<name>，元信息按保存顺序记录在 <dir>/<run_id>/.artifacts.json
+type localStore struct {
+	dir string
+	mu  sync.Mutex // 保护元信息文件的读写
+}
+
+// NewLocalStore 创建本地文件系统存储
+func NewLocalStore(dir string) Store {
+	return &localStore{dir: dir}
+}
+
+// Put 保存产物，先写入临时文件再重命名，避免读取到不完整的文件
+func (s *localStore) Put(ctx context.Context, a *model.Artifact, r io.Reader) error {
+	if a.Name == indexFile {
+		return fmt.Errorf("%w: %q", ErrInvalidName, a.Name)
+	}
+	target := filepath.Join(s.dir, a.RunID, filepath.FromSlash(a.Name))
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return fmt.Errorf("Put artifact failed, mkdir err: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("Put artifact failed, create err: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	// 读取开头部分用于推断 MIME 类型
+	br := bufio.NewReaderSize(r, 512)
+	head, _ := br.Peek(512)
+	if a.MIMEType == "" {
+		a.MIMEType = DetectMIME(a.Name, head)
+	}
+	size, err := io.Copy(tmp, br)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("Put artifact failed, write err: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return fmt.Errorf("Put artifact failed, rename err: %w", err)
+	}
+	a.Size = size
+	a.CreatedAt = time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items, err := s.readIndex(a.RunID)
+	if err != nil {
+		return err
+	}
+	for i, item := range items {
+		if item.Name == a.Name {
+			items = append(items[:i], items[i+1:]...)
+			break
+		}
+	}
+	return s.writeIndex(a.RunID, append(items, a))
+}
+
+// List 按保存顺序列出运行的全部产物
+func (s *localStore) List(ctx context.Context, runID string) ([]*model.Artifact, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.readIndex(runID)
+}
+
+// Open 读取产物内容
+func (s *localStore) Open(ctx context.Context, runID, name string) (*model.Artifact, io.ReadCloser, error) {
+	items, err := s.List(ctx, runID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, item := range items {
+		if item.Name != name {
+			continue
+		}
+		f, err := os.Open(filepath.Join(s.dir, runID, filepath.FromSlash(name)))
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil, ErrNotFound
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("Open artifact failed, err: %w", err)
+		}
+		return item, f, nil
+	}
+	return nil, nil, ErrNotFound
+}
+
+// DeleteRun 删除运行目录
+func (s *localStore) DeleteRun(ctx context.Context, runID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return os.RemoveAll(filepath.Join(s.dir, runID))
+}
+
+// readIndex 读取运行的产物元信息，运行没有产物时返回空列表
+func (s *localStore) readIndex(runID string) ([]*model.Artifact, error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, runID, indexFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read artifact index failed, err: %w", err)
+	}
+	var items []*model.Artifact
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("read artifact index failed, unmarshal err: %w", err)
+	}
+	return items, nil
+}
+
+// writeIndex 写入运行的产物元信息
+func (s *localStore) writeIndex(runID string, items []*model.Artifact) error {
+	data, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return fmt.Errorf("write artifact index failed, marshal err: %w", err)
+	}
+	path := filepath.Join(s.dir, runID, indexFile)
+	if err := os.WriteFile(path+".tmp", data, 0o644); err != nil {
+		return fmt.Errorf("write artifact index failed, err: %w", err)
+	}
+	return os.Rename(path+".tmp", path)
+}
@@ -0,0 +1,42 @@
+package callback
+
+import (
+	"context"
+	"slices"
+
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/compose"
+	"github.com/hildam/deer-flow-go/entity/conf"
+	"github.com/hildam/deer-flow-go/entity/consts"
+)
+
+// agentKey 上下文中存放当前 agent 名称的 key
+type agentKey struct{}
+
+// withAgent 如果当前节点是 agent 子图，则将 agent 名称写入上下文，子节点的回调可据此得知所属 agent
+func withAgent(ctx context.Context, info *callbacks.RunInfo) context.Context {
+	if info == nil || info.Component != compose.ComponentOfGraph {
+		return ctx
+	}
+	if !slices.Contains(consts.GetAgentNameList(), info.Name) && !isCustomAgent(info.Name) {
+		return ctx
+	}
+	return context.WithValue(ctx, agentKey{}, info.Name)
+}
+
+// isCustomAgent 判断是否为配置中声明的自定义智能体
+func isCustomAgent(name string) bool {
+	cfg := conf.GetCfg()
+	if cfg == nil {
+		return false
+	}
+	return slices.ContainsFunc(cfg.Agents, func(a conf.AgentConfig) bool {
+		return a.Name == name
+	})
+}
+
+// AgentFromContext 获取当前回调所属的 agent 名称，不在任何 agent 内时返回空字符串
+func AgentFromContext(ctx context.Context) string {
+	name, _ := ctx.Value(agentKey{}).(string)
+	return name
+}
@@ -6,7 +6,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/HildaM/logs/slog"
 	"github.com/cloudwego/eino/callbacks"
@@ -25,28 +28,53 @@ type LoggerCallback struct {
 	ID  string      // 线程ID，用于标识当前对话会话
 	SSE *sse.Writer // SSE写入器，用于向客户端推送实时流式数据
 	Out chan string // 输出通道，用于异步传递消息内容
+
+	wg sync.WaitGroup // 尚未推送完毕的流式输出
+}
+
+// Wait 等待所有流式输出推送完毕，运行结束后调用以保证输出完整且有序
+func (cb *LoggerCallback) Wait() {
+	cb.wg.Wait()
 }
 
 // pushF 推送格式化数据到客户端
 // 将聊天响应数据序列化后通过SSE和输出通道进行双路推送
 func (cb *LoggerCallback) pushF(ctx context.Context, event string, data *model.ChatResp) error {
+	return cb.push(event, data, data.Content)
+}
+
+// push 将数据序列化后通过SSE推送，并将 content 写入输出通道
+func (cb *LoggerCallback) push(name string, data any, content string) error {
 	// 将响应数据序列化为JSON格式
 	dataByte, err := json.Marshal(data)
 	if err != nil {
-		slog.Error("pushF failed, marshal data err = %+v, data = %+v", err, data)
+		slog.Error("push failed, marshal data err = %+v, data = %+v", err, data)
 		return err
 	}
 	// 通过SSE推送到客户端（如果SSE连接存在）
 	if cb.SSE != nil {
-		err = cb.SSE.WriteEvent("", event, dataByte)
+		err = cb.SSE.WriteEvent("", name, dataByte)
 	}
 	// 通过输出通道异步传递消息内容（如果通道存在）
-	if cb.Out != nil {
-		cb.Out <- data.Content
+	if cb.Out != nil && content != "" {
+		cb.Out <- content
 	}
 	return nil
 }
 
+// Emit 推送工作流事件，实现 event.Emitter
+// SSE 的事件名为事件类型，数据为 model.Event，控制台输出可读的摘要
+// 流式输出在后台推送，先等待推送完毕，保证事件排在之前输出的消息之后，如 step_completed 在步骤的最后一个消息分片之后
+// 事件均在发出事件的智能体读完自身的输出流之后发出，等待不会阻塞在尚未结束的流上
+func (cb *LoggerCallback) Emit(ctx context.Context, e *model.Event) {
+	cb.wg.Wait()
+	e.ThreadID = cb.ID
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	_ = cb.push(string(e.Type), e, formatEvent(e))
+}
+
 // pushMsg 推送消息到客户端
 // 根据消息类型（普通消息、工具调用、工具结果）进行不同的处理和推送
 func (cb *LoggerCallback) pushMsg(ctx context.Context, msgID string, msg *schema.Message) error {
@@ -55,12 +83,8 @@ func (cb *LoggerCallback) pushMsg(ctx context.Context, msgID string, msg *schema
 		return nil
 	}
 
-	// 从状态中获取当前智能体名称
-	agentName := ""
-	_ = compose.ProcessState[*model.State](ctx, func(_ context.Context, state *model.State) error {
-		agentName = state.Goto
-		return nil
-	})
+	// 当前智能体名称由 OnStart 写入上下文，state.Goto 此时已指向下一个智能体
+	agentName := AgentFromContext(ctx)
 
 	// 提取完成原因（如果存在响应元数据）
 	fr := ""
@@ -126,8 +150,116 @@ func (cb *LoggerCallback) pushMsg(ctx context.Context, msgID string, msg *schema
 	return cb.pushF(ctx, "message_chunk", data)
 }
 
+// PushUsage 推送本次运行的用量汇总
+// 在运行结束后调用，通过SSE推送usage_summary事件，并在控制台输出可读的汇总信息
+func (cb *LoggerCallback) PushUsage(ctx context.Context, summary *model.UsageSummary) error {
+	if summary == nil {
+		return nil
+	}
+	data := &model.ChatResp{
+		ThreadID: cb.ID,
+		Role:     "assistant",
+		Content:  formatUsage(summary),
+		Usage:    summary,
+	}
+	return cb.pushF(ctx, "usage_summary", data)
+}
+
+// PushRouteError 推送非法流转事件
+// 智能体设置了流转表之外的 Goto 导致运行失败时调用，事件中包含出错的智能体、目标、允许的目标和状态摘要
+func (cb *LoggerCallback) PushRouteError(ctx context.Context, routeErr *model.RouteError) error {
+	data := &model.ChatResp{
+		ThreadID:     cb.ID,
+		Agent:        routeErr.Agent,
+		ID:           uuid.New().String(),
+		Role:         "assistant",
+		Content:      fmt.Sprintf("\n=========[RouteError]=========\n%v\n", routeErr),
+		FinishReason: "error",
+		RouteError:   routeErr,
+	}
+	return cb.pushF(ctx, "route_error", data)
+}
+
+// formatEvent 将工作流事件格式化为控制台可读的文本
+func formatEvent(e *model.Event) string {
+	switch e.Type {
+	case model.EventAgentStart:
+		return fmt.Sprintf("\n==================\n [%s] %s\n==================\n", e.Type, e.Agent)
+	case model.EventAgentEnd:
+		return fmt.Sprintf("\n [%s] %s -> %s\n", e.Type, e.Agent, e.Next)
+	case model.EventPlanCreated:
+		sb := strings.Builder{}
+		sb.WriteString(fmt.Sprintf("\n [%s] %s\n", e.Type, e.Plan.Title))
+		for i, step := range e.Plan.Steps {
+			sb.WriteString(fmt.Sprintf("   %d. [%s] %s\n", i+1, step.StepType, step.Title))
+		}
+		return sb.String()
+	case model.EventStepStarted:
+		return fmt.Sprintf("\n [%s] %d/%d [%s] %s\n", e.Type, e.Step.Index+1, e.Step.Total, e.Step.StepType, e.Step.Title)
+	case model.EventStepCompleted:
+		return fmt.Sprintf("\n [%s] %d/%d %s\n", e.Type, e.Step.Index+1, e.Step.Total, e.Step.Title)
+	case model.EventStepRetry:
+		return fmt.Sprintf("\n [%s] %d/%d %s (attempt %d): %s\n", e.Type, e.Step.Index+1, e.Step.Total, e.Step.Title, e.Step.Attempts, e.Step.Error)
+	case model.EventStepFailed, model.EventStepSkipped:
+		return fmt.Sprintf("\n [%s] %d/%d %s: %s\n", e.Type, e.Step.Index+1, e.Step.Total, e.Step.Title, e.Step.Error)
+	case model.EventWarning:
+		return fmt.Sprintf("\n [%s] %s: %s\n", e.Type, e.Agent, e.Message)
+	case model.EventReport:
+		// 取消时生成的部分报告没有结构化报告
+		if e.Report == nil {
+			return fmt.Sprintf("\n [%s]\n\n%s\n", e.Type, e.Content)
+		}
+		return fmt.Sprintf("\n [%s] %s\n\n%s\n", e.Type, e.Report.Title, e.Content)
+	case model.EventInterrupt:
+		return fmt.Sprintf("\n [%s] %s\n", e.Type, e.Message)
+	case model.EventRunCompleted:
+		if e.Error != "" {
+			return fmt.Sprintf("\n [%s] %s: %s\n", e.Type, e.Status, e.Error)
+		}
+		return fmt.Sprintf("\n [%s] %s\n", e.Type, e.Status)
+	}
+	return ""
+}
+
+// formatUsage 将用量汇总格式化为控制台可读的文本
+func formatUsage(summary *model.UsageSummary) string {
+	sb := strings.Builder{}
+	sb.WriteString("\n==================\n [Usage] ")
+	sb.WriteString(formatTokenUsage(&summary.Total))
+	if summary.BudgetExceeded {
+		sb.WriteString(" (budget exceeded)")
+	}
+	sb.WriteString("\n")
+
+	agents := make([]string, 0, len(summary.ByAgent))
+	for name := range summary.ByAgent {
+		agents = append(agents, name)
+	}
+	sort.Strings(agents)
+	for _, name := range agents {
+		sb.WriteString(fmt.Sprintf("   - agent %s: %s\n", name, formatTokenUsage(summary.ByAgent[name])))
+	}
+
+	models := make([]string, 0, len(summary.ByModel))
+	for name := range summary.ByModel {
+		models = append(models, name)
+	}
+	sort.Strings(models)
+	for _, name := range models {
+		sb.WriteString(fmt.Sprintf("   - model %s: %s\n", name, formatTokenUsage(summary.ByModel[name])))
+	}
+	sb.WriteString("==================\n")
+	return sb.String()
+}
+
+// formatTokenUsage 格式化单条用量
+func formatTokenUsage(u *model.TokenUsage) string {
+	return fmt.Sprintf("requests=%d, prompt=%d, completion=%d, total=%d, cost=%.4f",
+		u.Requests, u.PromptTokens, u.CompletionTokens, u.TotalTokens, u.Cost)
+}
+
 // OnStart 智能体开始执行时的回调方法
-// 当智能体或组件开始执行时被调用，用于记录执行开始的信息
+// 当智能体或组件开始执行时被调用，将所属智能体写入上下文供流式输出使用，智能体开始时推送 agent_start 事件
 //
 // 参数:
 //   - ctx: 上下文对象
@@ -137,13 +269,14 @@ func (cb *LoggerCallback) pushMsg(ctx context.Context, msgID string, msg *schema
 // 返回值:
 //   - context.Context: 可能被修改的上下文对象
 func (cb *LoggerCallback) OnStart(ctx context.Context, info *callbacks.RunInfo, input callbacks.CallbackInput) context.Context {
-	// 如果输入是字符串类型，通过输出通道记录开始信息
-	if inputStr, ok := input.(string); ok {
-		if cb.Out != nil {
-			cb.Out <- "\n==================\n"
-			cb.Out <- fmt.Sprintf(" [OnStart] %s ", inputStr)
-			cb.Out <- "\n==================\n"
-		}
+	return cb.start(ctx, info)
+}
+
+// start 记录所属智能体，智能体子图开始执行时推送 agent_start 事件
+func (cb *LoggerCallback) start(ctx context.Context, info *callbacks.RunInfo) context.Context {
+	ctx = withAgent(ctx, info)
+	if info != nil && info.Component == compose.ComponentOfGraph && AgentFromContext(ctx) == info.Name {
+		cb.Emit(ctx, &model.Event{Type: model.EventAgentStart, Agent: info.Name})
 	}
 	return ctx
 }
@@ -181,8 +314,15 @@ func (cb *LoggerCallback) OnEnd(ctx context.Context, info *callbacks.RunInfo, ou
 // 返回值:
 //   - context.Context: 可能被修改的上下文对象
 func (cb *LoggerCallback) OnError(ctx context.Context, info *callbacks.RunInfo, err error) context.Context {
-	fmt.Println("=========[OnError]=========")
-	fmt.Println(err)
+	// 计划确认等中断属于正常流程，不作为错误输出
+	if _, ok := compose.ExtractInterruptInfo(err); ok || errors.Is(err, compose.InterruptAndRerun) {
+		slog.Debug("OnError interrupt, node = %s, err = %v", info.Name, err)
+		return ctx
+	}
+	slog.Error("OnError failed, node = %s, err = %v", info.Name, err)
+	if cb.Out != nil {
+		cb.Out <- fmt.Sprintf("\n=========[OnError]=========\n%v\n", err)
+	}
 	return ctx
 }
 
@@ -209,7 +349,9 @@ func (cb *LoggerCallback) OnEndWithStreamOutput(ctx context.Context, info *callb
 	// 生成唯一消息ID，用于标识本次流式会话
 	msgID := uuid.New().String()
 	// 启动异步goroutine处理流式数据，避免阻塞主流程
+	cb.wg.Add(1)
 	go func() {
+		defer cb.wg.Done()
 		// 确保流在函数结束时被正确关闭
 		defer output.Close() // remember to close the stream in defer
 		// 异常恢复机制，防止panic导致整个程序崩溃
@@ -267,10 +409,10 @@ func (cb *LoggerCallback) OnEndWithStreamOutput(ctx context.Context, info *callb
 // 返回值:
 //   - context.Context: 可能被修改的上下文对象
 //
-// 注意: 当前实现仅进行资源清理，未对输入流进行实际处理
+// 注意: 当前实现仅记录所属智能体并进行资源清理，未对输入流进行实际处理
 func (cb *LoggerCallback) OnStartWithStreamInput(ctx context.Context, info *callbacks.RunInfo,
 	input *schema.StreamReader[callbacks.CallbackInput]) context.Context {
 	// 确保输入流被正确关闭，释放相关资源
 	defer input.Close()
-	return ctx
+	return cb.start(ctx, info)
 }
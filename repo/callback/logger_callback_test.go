@@ -0,0 +1,46 @@
+package callback
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/schema"
+
+	"github.com/hildam/deer-flow-go/entity/model"
+)
+
+// TestEmitAfterStreamOutput 流式输出尚未推送完毕时发出的事件排在最后一个消息分片之后
+func TestEmitAfterStreamOutput(t *testing.T) {
+	out := make(chan string)
+	cb := &LoggerCallback{ID: "ordered", Out: out}
+
+	// 模型输出较慢，第二个分片在事件发出之后才到达
+	sr, sw := schema.Pipe[callbacks.CallbackOutput](2)
+	go func() {
+		defer sw.Close()
+		sw.Send(schema.AssistantMessage("first ", nil), nil)
+		time.Sleep(50 * time.Millisecond)
+		sw.Send(schema.AssistantMessage("last", nil), nil)
+	}()
+	cb.OnEndWithStreamOutput(context.Background(), &callbacks.RunInfo{Name: "researcher"}, sr)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		cb.Emit(context.Background(), &model.Event{Type: model.EventAgentEnd, Agent: "researcher", Next: "research_team"})
+	}()
+
+	var got []string
+	for len(got) < 3 {
+		// 输出的消费方较慢
+		time.Sleep(10 * time.Millisecond)
+		got = append(got, <-out)
+	}
+	<-done
+	if got[0] != "first " || got[1] != "last" || !strings.Contains(got[2], string(model.EventAgentEnd)) {
+		t.Errorf("output = %q, want both chunks before agent_end", got)
+	}
+}
@@ -0,0 +1,138 @@
+package callback
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components"
+	ecmodel "github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+
+	"github.com/hildam/deer-flow-go/entity/conf"
+	"github.com/hildam/deer-flow-go/entity/model"
+	"github.com/hildam/deer-flow-go/repo/metrics"
+)
+
+// MetricsCallback 指标采集回调
+// 采集每个 agent 的执行耗时和每次 ChatModel 调用的结果，并记录依次执行的 agent
+type MetricsCallback struct {
+	callbacks.HandlerBuilder
+
+	mu   sync.Mutex
+	path []model.NodeVisit // 依次开始执行的 agent
+}
+
+// startKey 上下文中存放 agent 开始时间的 key
+type startKey struct{}
+
+// OnStart agent 开始时记录开始时间，ChatModel 开始时记录模型名称
+func (cb *MetricsCallback) OnStart(ctx context.Context, info *callbacks.RunInfo, input callbacks.CallbackInput) context.Context {
+	ctx = withAgent(ctx, info)
+	return cb.start(ctx, info, input)
+}
+
+// OnEnd agent 结束时记录耗时，ChatModel 结束时记录请求数
+func (cb *MetricsCallback) OnEnd(ctx context.Context, info *callbacks.RunInfo, output callbacks.CallbackOutput) context.Context {
+	cb.end(ctx, info, nil)
+	return ctx
+}
+
+// OnError 记录失败
+func (cb *MetricsCallback) OnError(ctx context.Context, info *callbacks.RunInfo, err error) context.Context {
+	cb.end(ctx, info, err)
+	return ctx
+}
+
+// OnEndWithStreamOutput 流式输出开始返回即视为执行结束
+func (cb *MetricsCallback) OnEndWithStreamOutput(ctx context.Context, info *callbacks.RunInfo,
+	output *schema.StreamReader[callbacks.CallbackOutput]) context.Context {
+	output.Close()
+	cb.end(ctx, info, nil)
+	return ctx
+}
+
+// OnStartWithStreamInput 与 OnStart 相同
+func (cb *MetricsCallback) OnStartWithStreamInput(ctx context.Context, info *callbacks.RunInfo,
+	input *schema.StreamReader[callbacks.CallbackInput]) context.Context {
+	defer input.Close()
+	ctx = withAgent(ctx, info)
+	return cb.start(ctx, info, nil)
+}
+
+// LastAgent 返回最后一个开始执行的 agent，即运行结束时所处的 agent
+func (cb *MetricsCallback) LastAgent() string {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if len(cb.path) == 0 {
+		return ""
+	}
+	return cb.path[len(cb.path)-1].Node
+}
+
+// Path 返回依次执行的 agent 及其耗时
+func (cb *MetricsCallback) Path() []model.NodeVisit {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return slices.Clone(cb.path)
+}
+
+// start 为 agent 和 ChatModel 节点记录开始信息
+func (cb *MetricsCallback) start(ctx context.Context, info *callbacks.RunInfo, input callbacks.CallbackInput) context.Context {
+	if info == nil {
+		return ctx
+	}
+	switch {
+	case info.Component == compose.ComponentOfGraph && AgentFromContext(ctx) == info.Name:
+		now := time.Now()
+		cb.mu.Lock()
+		cb.path = append(cb.path, model.NodeVisit{Node: info.Name, StartedAt: now})
+		visit := len(cb.path) - 1
+		cb.mu.Unlock()
+		return context.WithValue(ctx, startKey{}, &metricsEntry{info: *info, start: now, visit: visit})
+	case info.Component == components.ComponentOfChatModel:
+		name := conf.GetCfg().Model.DefaultModel.ModelID
+		if in := ecmodel.ConvCallbackInput(input); in != nil && in.Config != nil && in.Config.Model != "" {
+			name = in.Config.Model
+		}
+		return context.WithValue(ctx, startKey{}, &metricsEntry{info: *info, start: time.Now(), model: name})
+	}
+	return ctx
+}
+
+// end 节点结束时上报指标，仅处理由当前节点记录的开始信息
+func (cb *MetricsCallback) end(ctx context.Context, info *callbacks.RunInfo, err error) {
+	entry, ok := ctx.Value(startKey{}).(*metricsEntry)
+	if !ok || info == nil || entry.info != *info {
+		return
+	}
+	if info.Component == components.ComponentOfChatModel {
+		metrics.ObserveLLM(entry.model, err)
+		return
+	}
+	elapsed := time.Since(entry.start)
+	metrics.ObserveAgent(info.Name, elapsed)
+
+	// 计划确认等中断属于正常流程，不记录为错误
+	if _, ok := compose.ExtractInterruptInfo(err); ok || errors.Is(err, compose.InterruptAndRerun) {
+		err = nil
+	}
+	cb.mu.Lock()
+	cb.path[entry.visit].DurationMs = elapsed.Milliseconds()
+	if err != nil {
+		cb.path[entry.visit].Error = err.Error()
+	}
+	cb.mu.Unlock()
+}
+
+// metricsEntry 节点开始时记录的信息
+type metricsEntry struct {
+	info  callbacks.RunInfo
+	start time.Time
+	model string
+	visit int // agent 在执行路径中的位置
+}
@@ -0,0 +1,178 @@
+package callback
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/HildaM/logs/slog"
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components"
+	ecmodel "github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/hildam/deer-flow-go/entity/consts"
+	"github.com/hildam/deer-flow-go/entity/model"
+	"github.com/hildam/deer-flow-go/repo/tracing"
+)
+
+// TraceCallback 链路追踪回调
+// 为整次运行、每个 agent 子图以及每次 ChatModel 调用创建 OpenTelemetry span
+type TraceCallback struct {
+	callbacks.HandlerBuilder
+
+	ID string // 线程ID，记录到根 span 上
+}
+
+// spanKey 上下文中存放当前节点 span 的 key
+type spanKey struct{}
+
+// spanEntry 记录 span 及其所属的节点，用于在 OnEnd 时判断 span 是否由当前节点创建
+type spanEntry struct {
+	info callbacks.RunInfo
+	span trace.Span
+}
+
+// OnStart 节点开始时创建 span
+func (cb *TraceCallback) OnStart(ctx context.Context, info *callbacks.RunInfo, input callbacks.CallbackInput) context.Context {
+	ctx = withAgent(ctx, info)
+	return cb.startSpan(ctx, info, input)
+}
+
+// OnEnd 节点结束时关闭 span，ChatModel 额外记录 token 用量
+func (cb *TraceCallback) OnEnd(ctx context.Context, info *callbacks.RunInfo, output callbacks.CallbackOutput) context.Context {
+	span := spanOf(ctx, info)
+	if span == nil {
+		return ctx
+	}
+	if info.Component == components.ComponentOfChatModel {
+		if out := ecmodel.ConvCallbackOutput(output); out != nil {
+			setUsageAttributes(span, tokenUsage(out))
+		}
+	}
+	span.End()
+	return ctx
+}
+
+// OnError 记录错误并关闭 span
+func (cb *TraceCallback) OnError(ctx context.Context, info *callbacks.RunInfo, err error) context.Context {
+	span := spanOf(ctx, info)
+	if span == nil {
+		return ctx
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	span.End()
+	return ctx
+}
+
+// OnEndWithStreamOutput 流式输出结束时关闭 span
+// ChatModel 的用量在最后一个数据帧中，因此需要读完整个流后再关闭 span
+func (cb *TraceCallback) OnEndWithStreamOutput(ctx context.Context, info *callbacks.RunInfo,
+	output *schema.StreamReader[callbacks.CallbackOutput]) context.Context {
+	span := spanOf(ctx, info)
+	if span == nil || info.Component != components.ComponentOfChatModel {
+		output.Close()
+		if span != nil {
+			span.End()
+		}
+		return ctx
+	}
+
+	go func() {
+		defer output.Close()
+		defer span.End()
+		defer func() {
+			if err := recover(); err != nil {
+				slog.Error("TraceCallback panic_recover, err = %v", err)
+			}
+		}()
+
+		var tu *model.TokenUsage
+		for {
+			frame, err := output.Recv()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+				return
+			}
+			if out := ecmodel.ConvCallbackOutput(frame); out != nil {
+				if u := tokenUsage(out); u != nil {
+					tu = u
+				}
+			}
+		}
+		setUsageAttributes(span, tu)
+	}()
+	return ctx
+}
+
+// OnStartWithStreamInput 流式输入时同样创建 span
+func (cb *TraceCallback) OnStartWithStreamInput(ctx context.Context, info *callbacks.RunInfo,
+	input *schema.StreamReader[callbacks.CallbackInput]) context.Context {
+	defer input.Close()
+	ctx = withAgent(ctx, info)
+	return cb.startSpan(ctx, info, nil)
+}
+
+// startSpan 仅为整次运行、agent 子图和 ChatModel 创建 span，其余节点不产生 span
+func (cb *TraceCallback) startSpan(ctx context.Context, info *callbacks.RunInfo, input callbacks.CallbackInput) context.Context {
+	if info == nil {
+		return ctx
+	}
+
+	var (
+		spanName string
+		attrs    []attribute.KeyValue
+	)
+	switch {
+	case info.Component == compose.ComponentOfGraph && info.Name == consts.GraphName:
+		spanName = "run"
+		attrs = append(attrs, attribute.String("thread_id", cb.ID))
+	case info.Component == compose.ComponentOfGraph && AgentFromContext(ctx) == info.Name:
+		spanName = "agent." + info.Name
+		attrs = append(attrs, attribute.String("agent", info.Name))
+	case info.Component == components.ComponentOfChatModel:
+		spanName = "chat_model"
+		attrs = append(attrs, attribute.String("agent", AgentFromContext(ctx)))
+		if in := ecmodel.ConvCallbackInput(input); in != nil {
+			attrs = append(attrs, attribute.Int("llm.messages", len(in.Messages)), attribute.Int("llm.tools", len(in.Tools)))
+			if in.Config != nil {
+				attrs = append(attrs, attribute.String("llm.model", in.Config.Model))
+			}
+		}
+	default:
+		return ctx
+	}
+
+	ctx, span := tracing.Tracer().Start(ctx, spanName, trace.WithAttributes(attrs...))
+	return context.WithValue(ctx, spanKey{}, &spanEntry{info: *info, span: span})
+}
+
+// spanOf 获取当前节点创建的 span，不是当前节点创建的返回 nil
+func spanOf(ctx context.Context, info *callbacks.RunInfo) trace.Span {
+	entry, ok := ctx.Value(spanKey{}).(*spanEntry)
+	if !ok || info == nil || entry.info != *info {
+		return nil
+	}
+	return entry.span
+}
+
+// setUsageAttributes 将 token 用量写入 span
+func setUsageAttributes(span trace.Span, tu *model.TokenUsage) {
+	if tu == nil {
+		return
+	}
+	span.SetAttributes(
+		attribute.Int("llm.prompt_tokens", tu.PromptTokens),
+		attribute.Int("llm.completion_tokens", tu.CompletionTokens),
+		attribute.Int("llm.total_tokens", tu.TotalTokens),
+	)
+}
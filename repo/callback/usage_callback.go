@@ -0,0 +1,143 @@
+package callback
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/HildaM/logs/slog"
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components"
+	ecmodel "github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+	"github.com/hildam/deer-flow-go/entity/conf"
+	"github.com/hildam/deer-flow-go/entity/model"
+	"github.com/hildam/deer-flow-go/repo/usage"
+)
+
+// UsageCallback 用量统计回调
+// 从每一次 ChatModel 调用的输出中提取 token 用量，并按 agent 和模型记录到 Tracker 中
+type UsageCallback struct {
+	callbacks.HandlerBuilder
+
+	Tracker *usage.Tracker // 单次运行的用量统计器
+}
+
+// OnStart 记录当前所处的 agent
+func (cb *UsageCallback) OnStart(ctx context.Context, info *callbacks.RunInfo, input callbacks.CallbackInput) context.Context {
+	return withAgent(ctx, info)
+}
+
+// OnEnd 非流式调用结束时记录用量
+func (cb *UsageCallback) OnEnd(ctx context.Context, info *callbacks.RunInfo, output callbacks.CallbackOutput) context.Context {
+	if info.Component != components.ComponentOfChatModel {
+		return ctx
+	}
+	out := ecmodel.ConvCallbackOutput(output)
+	if out == nil {
+		return ctx
+	}
+	cb.record(ctx, modelName(out), tokenUsage(out))
+	return ctx
+}
+
+// OnError 出错时不产生用量
+func (cb *UsageCallback) OnError(ctx context.Context, info *callbacks.RunInfo, err error) context.Context {
+	return ctx
+}
+
+// OnEndWithStreamOutput 流式调用结束时记录用量
+// 用量通常只出现在最后一个数据帧中，因此需要读完整个流；读取在后台进行，登记到 Tracker 中，路由检查预算前等待读取完成
+func (cb *UsageCallback) OnEndWithStreamOutput(ctx context.Context, info *callbacks.RunInfo,
+	output *schema.StreamReader[callbacks.CallbackOutput]) context.Context {
+	if info.Component != components.ComponentOfChatModel || cb.Tracker == nil {
+		output.Close()
+		return ctx
+	}
+
+	done := cb.Tracker.Track()
+	go func() {
+		defer done()
+		defer output.Close()
+		defer func() {
+			if err := recover(); err != nil {
+				slog.Error("UsageCallback panic_recover, err = %v", err)
+			}
+		}()
+
+		name := ""
+		var tu *model.TokenUsage
+		for {
+			frame, err := output.Recv()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				slog.Error("UsageCallback recv_error, err = %v", err)
+				return
+			}
+			out := ecmodel.ConvCallbackOutput(frame)
+			if out == nil {
+				continue
+			}
+			if n := modelName(out); n != "" {
+				name = n
+			}
+			if u := tokenUsage(out); u != nil {
+				tu = u
+			}
+		}
+		cb.record(ctx, name, tu)
+	}()
+	return ctx
+}
+
+// OnStartWithStreamInput 记录当前所处的 agent
+func (cb *UsageCallback) OnStartWithStreamInput(ctx context.Context, info *callbacks.RunInfo,
+	input *schema.StreamReader[callbacks.CallbackInput]) context.Context {
+	defer input.Close()
+	return withAgent(ctx, info)
+}
+
+// record 将一次调用的用量写入 Tracker
+func (cb *UsageCallback) record(ctx context.Context, name string, tu *model.TokenUsage) {
+	if cb.Tracker == nil || tu == nil {
+		return
+	}
+	if name == "" {
+		name = conf.GetCfg().Model.DefaultModel.ModelID
+	}
+	agentName := AgentFromContext(ctx)
+	cb.Tracker.Add(agentName, name, *tu)
+	slog.Debug("UsageCallback debug, agent = %s, model = %s, usage = %+v", agentName, name, tu)
+}
+
+// modelName 从回调输出中提取模型名称
+func modelName(out *ecmodel.CallbackOutput) string {
+	if out.Config != nil {
+		return out.Config.Model
+	}
+	return ""
+}
+
+// tokenUsage 从回调输出中提取 token 用量，优先使用回调自带的用量，其次使用消息的 ResponseMeta
+func tokenUsage(out *ecmodel.CallbackOutput) *model.TokenUsage {
+	if out.TokenUsage != nil {
+		return &model.TokenUsage{
+			PromptTokens:       out.TokenUsage.PromptTokens,
+			CachedPromptTokens: out.TokenUsage.PromptTokenDetails.CachedTokens,
+			CompletionTokens:   out.TokenUsage.CompletionTokens,
+			TotalTokens:        out.TokenUsage.TotalTokens,
+		}
+	}
+	if out.Message != nil && out.Message.ResponseMeta != nil && out.Message.ResponseMeta.Usage != nil {
+		u := out.Message.ResponseMeta.Usage
+		return &model.TokenUsage{
+			PromptTokens:       u.PromptTokens,
+			CachedPromptTokens: u.PromptTokenDetails.CachedTokens,
+			CompletionTokens:   u.CompletionTokens,
+			TotalTokens:        u.TotalTokens,
+		}
+	}
+	return nil
+}
@@ -2,33 +2,99 @@ package checkpoint
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	"github.com/cloudwego/eino/compose"
+	"github.com/hildam/deer-flow-go/entity/model"
 )
 
+// 注册需要随检查点序列化的状态类型，中断后恢复运行时依赖这些类型
+func init() {
+	_ = compose.RegisterSerializableType[model.State]("deer_state")
+	_ = compose.RegisterSerializableType[model.Plan]("deer_plan")
+	_ = compose.RegisterSerializableType[model.Step]("deer_step")
+	_ = compose.RegisterSerializableType[model.StepType]("deer_step_type")
+	_ = compose.RegisterSerializableType[model.StepStatus]("deer_step_status")
+	_ = compose.RegisterSerializableType[model.StepToolCall]("deer_step_tool_call")
+	_ = compose.RegisterSerializableType[model.UsageSummary]("deer_usage_summary")
+	_ = compose.RegisterSerializableType[model.TokenUsage]("deer_token_usage")
+	_ = compose.RegisterSerializableType[model.Report]("deer_report")
+	_ = compose.RegisterSerializableType[model.ReportSection]("deer_report_section")
+	_ = compose.RegisterSerializableType[model.ReportTable]("deer_report_table")
+	_ = compose.RegisterSerializableType[model.ReportCitation]("deer_report_citation")
+	_ = compose.RegisterSerializableType[model.PromptRef]("deer_prompt_ref")
+	_ = compose.RegisterSerializableType[time.Time]("deer_time")
+}
+
 // DeerCheckPoint DeerGo的全局状态存储点，
 // 实现CheckPointStore接口，用checkPointID进行索引
 // 此处粗略使用map实现，工程上可以用工业存储组件实现
 type checkpoint struct {
-	buf map[string][]byte // map映射存储
+	mu        sync.RWMutex      // 读写锁，HTTP服务下多个请求会并发读写
+	buf       map[string][]byte // map映射存储
+	workflows map[string]string // 检查点所属运行使用的工作流
 }
 
 func (c *checkpoint) Get(ctx context.Context, checkPointID string) ([]byte, bool, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	data, ok := c.buf[checkPointID]
 	return data, ok, nil
 }
 
 func (c *checkpoint) Set(ctx context.Context, checkPointID string, checkPoint []byte) error {
-	c.buf[checkPointID] = checkPoint
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// 复制一份，避免调用方复用缓冲区时影响已存储的检查点
+	c.buf[checkPointID] = append([]byte(nil), checkPoint...)
 	return nil
 }
 
 // 创建一个全局状态存储点实例并返回
 var checkpointImpl = checkpoint{
-	buf: make(map[string][]byte),
+	buf:       make(map[string][]byte),
+	workflows: make(map[string]string),
 }
 
 // NewCheckPoint 创建一个全局状态存储点实例并返回
 func NewCheckPoint() compose.CheckPointStore {
 	return &checkpointImpl
 }
+
+// Delete 删除检查点，运行结束且无需恢复时调用以释放内存
+func Delete(checkPointID string) {
+	checkpointImpl.mu.Lock()
+	defer checkpointImpl.mu.Unlock()
+	delete(checkpointImpl.buf, checkPointID)
+	delete(checkpointImpl.workflows, checkPointID)
+}
+
+// SetWorkflow 记录检查点所属运行使用的工作流，恢复运行时需按同一工作流构建运行图
+func SetWorkflow(checkPointID, workflow string) {
+	checkpointImpl.mu.Lock()
+	defer checkpointImpl.mu.Unlock()
+	if _, ok := checkpointImpl.buf[checkPointID]; ok {
+		checkpointImpl.workflows[checkPointID] = workflow
+	}
+}
+
+// Workflow 返回检查点所属运行使用的工作流，ok 表示检查点是否存在，未记录工作流时返回空字符串
+func Workflow(checkPointID string) (workflow string, ok bool) {
+	checkpointImpl.mu.RLock()
+	defer checkpointImpl.mu.RUnlock()
+	if _, ok = checkpointImpl.buf[checkPointID]; !ok {
+		return "", false
+	}
+	return checkpointImpl.workflows[checkPointID], true
+}
+
+// Size 返回当前存储的检查点数量及总字节数
+func Size() (entries int, bytes int) {
+	checkpointImpl.mu.RLock()
+	defer checkpointImpl.mu.RUnlock()
+	for _, data := range checkpointImpl.buf {
+		bytes += len(data)
+	}
+	return len(checkpointImpl.buf), bytes
+}
@@ -0,0 +1,163 @@
+package checkpoint
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+
+	"github.com/hildam/deer-flow-go/entity/model"
+)
+
+// TestCheckPointConcurrent 并发读写不同线程的检查点，需配合 -race 运行
+func TestCheckPointConcurrent(t *testing.T) {
+	ctx := context.Background()
+	store := NewCheckPoint()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := fmt.Sprintf("thread-%d", i)
+			for j := 0; j < 100; j++ {
+				want := fmt.Sprintf("%s-%d", id, j)
+				if err := store.Set(ctx, id, []byte(want)); err != nil {
+					t.Errorf("set %s: %v", id, err)
+					return
+				}
+				data, ok, err := store.Get(ctx, id)
+				if err != nil || !ok || string(data) != want {
+					t.Errorf("get %s = %q, %v, %v, want %q", id, data, ok, err, want)
+					return
+				}
+				_, _ = Size()
+			}
+			Delete(id)
+			if _, ok, _ := store.Get(ctx, id); ok {
+				t.Errorf("get %s after delete: still exists", id)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+// TestCheckPointCopy 存储的检查点不受调用方后续修改缓冲区的影响
+func TestCheckPointCopy(t *testing.T) {
+	ctx := context.Background()
+	store := NewCheckPoint()
+	buf := []byte("state")
+	_ = store.Set(ctx, "copy", buf)
+	defer Delete("copy")
+	buf[0] = 'S'
+
+	data, _, _ := store.Get(ctx, "copy")
+	if string(data) != "state" {
+		t.Errorf("get = %q, want %q", data, "state")
+	}
+}
+
+// TestCheckPointWorkflow 检查点记录所属的工作流，删除检查点时一并删除
+func TestCheckPointWorkflow(t *testing.T) {
+	ctx := context.Background()
+	store := NewCheckPoint()
+	const id = "workflow"
+	SetWorkflow(id, "quick_answer")
+	if _, ok := Workflow(id); ok {
+		t.Fatal("workflow recorded without a checkpoint")
+	}
+
+	_ = store.Set(ctx, id, []byte("state"))
+	if wf, ok := Workflow(id); !ok || wf != "" {
+		t.Errorf("Workflow = %q, %v, want an unknown workflow of an existing checkpoint", wf, ok)
+	}
+	SetWorkflow(id, "quick_answer")
+	if wf, ok := Workflow(id); !ok || wf != "quick_answer" {
+		t.Errorf("Workflow = %q, %v, want quick_answer", wf, ok)
+	}
+	Delete(id)
+	if wf, ok := Workflow(id); ok || wf != "" {
+		t.Errorf("Workflow after delete = %q, %v, want none", wf, ok)
+	}
+}
+
+// fullState 各字段均有值的状态，覆盖需要随检查点序列化的全部类型
+func fullState() *model.State {
+	started := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	finished := started.Add(time.Minute)
+	res := "结果"
+	return &model.State{
+		Messages: []*schema.Message{schema.UserMessage("问题")},
+		Goto:     "human_feedback",
+		CurrentPlan: &model.Plan{Title: "计划", Steps: []model.Step{{
+			Title:        "步骤",
+			StepType:     model.Research,
+			Status:       model.StepSucceeded,
+			ExecutionRes: &res,
+			StartedAt:    &started,
+			FinishedAt:   &finished,
+			ToolCalls:    []model.StepToolCall{{Name: "web_search"}},
+		}}},
+		Usage: &model.UsageSummary{
+			Total:   model.TokenUsage{Requests: 1, TotalTokens: 10},
+			ByAgent: map[string]*model.TokenUsage{"planner": {Requests: 1, TotalTokens: 10}},
+		},
+		Report: &model.Report{
+			Title:     "报告",
+			Sections:  []model.ReportSection{{Title: "章节", Tables: []model.ReportTable{{Headers: []string{"a"}, Rows: [][]string{{"1"}}}}}},
+			Citations: []model.ReportCitation{{Title: "来源", URL: "https://example.com"}},
+		},
+		Prompts:        map[string]model.PromptRef{"planner": {Version: "v1", Source: "embedded"}},
+		PromptVariants: map[string]string{"planner": "concise"},
+	}
+}
+
+// TestCheckpointState 中断时保存状态，恢复后状态与中断前一致
+func TestCheckpointState(t *testing.T) {
+	ctx := context.Background()
+	want := fullState()
+	var got *model.State
+
+	g := compose.NewGraph[string, string](compose.WithGenLocalState(func(context.Context) *model.State {
+		return fullState()
+	}))
+	_ = g.AddLambdaNode("before", compose.InvokableLambda(func(ctx context.Context, in string) (string, error) {
+		return in, nil
+	}))
+	_ = g.AddLambdaNode("after", compose.InvokableLambda(func(ctx context.Context, in string) (string, error) {
+		return in, compose.ProcessState[*model.State](ctx, func(_ context.Context, state *model.State) error {
+			got = state
+			return nil
+		})
+	}))
+	_ = g.AddEdge(compose.START, "before")
+	_ = g.AddEdge("before", "after")
+	_ = g.AddEdge("after", compose.END)
+	r, err := g.Compile(ctx,
+		compose.WithCheckPointStore(NewCheckPoint()),
+		compose.WithInterruptBeforeNodes([]string{"after"}),
+	)
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+
+	const id = "checkpoint-state"
+	defer Delete(id)
+	_, err = r.Invoke(ctx, "in", compose.WithCheckPointID(id))
+	if _, ok := compose.ExtractInterruptInfo(err); !ok {
+		t.Fatalf("Invoke err = %v, want interrupt", err)
+	}
+	if _, err := r.Invoke(ctx, "in", compose.WithCheckPointID(id)); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	gotJSON, _ := json.Marshal(got)
+	wantJSON, _ := json.Marshal(want)
+	if string(gotJSON) != string(wantJSON) {
+		t.Errorf("restored state = %s, want %s", gotJSON, wantJSON)
+	}
+}
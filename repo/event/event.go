@@ -0,0 +1,33 @@
+package event
+
+import (
+	"context"
+	"time"
+
+	"github.com/hildam/deer-flow-go/entity/model"
+)
+
+// Emitter 工作流事件的接收方，如推送 SSE 和控制台输出的 LoggerCallback
+type Emitter interface {
+	Emit(ctx context.Context, e *model.Event)
+}
+
+// emitterKey 上下文中存放事件接收方的 key
+type emitterKey struct{}
+
+// WithEmitter 将事件接收方注入上下文，供各智能体的 router 发出事件
+func WithEmitter(ctx context.Context, e Emitter) context.Context {
+	return context.WithValue(ctx, emitterKey{}, e)
+}
+
+// Emit 发出事件，上下文中没有接收方时忽略
+func Emit(ctx context.Context, e *model.Event) {
+	emitter, _ := ctx.Value(emitterKey{}).(Emitter)
+	if emitter == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	emitter.Emit(ctx, e)
+}
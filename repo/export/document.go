@@ -0,0 +1,259 @@
+package export
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+
+	"github.com/hildam/deer-flow-go/entity/model"
+)
+
+// Document 报告的结构化表示，用于 JSON 导出及下游系统消费
+type Document struct {
+	ThreadID  string            `json:"thread_id"`
+	Title     string            `json:"title"`
+	Query     string            `json:"query"`
+	Locale    string            `json:"locale,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	KeyPoints []string          `json:"key_points,omitempty"` // Key Points 章节的要点
+	Sections  []Section         `json:"sections"`             // 按标题切分的章节
+	Tables    []Table           `json:"tables,omitempty"`     // 报告中的全部表格
+	Citations []Citation        `json:"citations,omitempty"`  // 引用来源
+	Artifacts []*model.Artifact `json:"artifacts,omitempty"`  // 运行生成的产物
+	Markdown  string            `json:"markdown"`             // 原始 Markdown 报告
+}
+
+// Section 报告章节
+type Section struct {
+	Level   int    `json:"level"`   // 标题级别
+	Title   string `json:"title"`   // 标题文本
+	Content string `json:"content"` // 章节正文，Markdown 格式，不含子章节
+}
+
+// Table 报告中的表格
+type Table struct {
+	Section string     `json:"section,omitempty"` // 所在章节标题
+	Headers []string   `json:"headers"`
+	Rows    [][]string `json:"rows"`
+}
+
+// Citation 引用来源
+type Citation struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+var (
+	// keyPointsTitle 要点章节标题
+	keyPointsTitle = regexp.MustCompile(`(?i)key\s*points|要点|关键点`)
+	// citationsTitle 引用章节标题
+	citationsTitle = regexp.MustCompile(`(?i)citations?|references?|sources|参考|引用|来源`)
+)
+
+// markdown 解析报告使用的 Markdown 处理器，支持 GFM 表格、删除线和自动链接
+var markdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// parseMarkdown 解析 Markdown 为语法树
+func parseMarkdown(src []byte) ast.Node {
+	return markdown.Parser().Parse(text.NewReader(src))
+}
+
+// NewDocument 解析运行记录的最终报告，生成结构化文档
+func NewDocument(rec *model.RunRecord, artifacts []*model.Artifact) *Document {
+	src := []byte(rec.Report)
+	doc := &Document{
+		ThreadID:  rec.ID,
+		Query:     rec.Query,
+		Locale:    rec.Locale,
+		CreatedAt: rec.FinishedAt,
+		Artifacts: artifacts,
+		Markdown:  rec.Report,
+	}
+	if doc.CreatedAt.IsZero() {
+		doc.CreatedAt = rec.StartedAt
+	}
+
+	root := parseMarkdown(src)
+	var headings []*ast.Heading
+	var links []Citation
+	section := ""
+	_ = ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Heading:
+			headings = append(headings, n)
+			section = plainText(n, src)
+		case *east.Table:
+			doc.Tables = append(doc.Tables, tableOf(n, src, section))
+			return ast.WalkSkipChildren, nil
+		case *ast.ListItem:
+			if keyPointsTitle.MatchString(section) && n.Parent().Parent() == root {
+				doc.KeyPoints = append(doc.KeyPoints, plainText(n, src))
+			}
+		case *ast.Link, *ast.AutoLink:
+			c := citationOf(n, src)
+			if citationsTitle.MatchString(section) {
+				doc.Citations = append(doc.Citations, c)
+			} else if strings.HasPrefix(c.URL, "http") {
+				links = append(links, c)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	// 报告没有引用章节时，使用正文中的外部链接
+	if len(doc.Citations) == 0 {
+		doc.Citations = dedupCitations(links)
+	}
+
+	doc.Sections = splitSections(src, headings)
+	// 结构化输出模式下的要点、表格和引用由模型直接给出，无需从 Markdown 中提取
+	if r := rec.Structured; r != nil {
+		doc.KeyPoints = r.KeyPoints
+		doc.Tables = structuredTables(r)
+		doc.Citations = nil
+		for _, c := range r.Citations {
+			doc.Citations = append(doc.Citations, Citation{Title: c.Title, URL: c.URL})
+		}
+	}
+	switch {
+	case rec.Structured != nil && rec.Structured.Title != "":
+		doc.Title = rec.Structured.Title
+	case len(headings) > 0 && headings[0].Level == 1:
+		doc.Title = plainText(headings[0], src)
+	case rec.Plan != nil && rec.Plan.Title != "":
+		doc.Title = rec.Plan.Title
+	default:
+		doc.Title = rec.Query
+	}
+	return doc
+}
+
+// structuredTables 结构化报告中的全部表格
+func structuredTables(r *model.Report) []Table {
+	var tables []Table
+	for _, s := range r.Sections {
+		for _, t := range s.Tables {
+			tables = append(tables, Table{Section: s.Title, Headers: t.Headers, Rows: t.Rows})
+		}
+	}
+	return tables
+}
+
+// splitSections 按标题切分章节，第一个标题之前的内容作为无标题章节
+func splitSections(src []byte, headings []*ast.Heading) []Section {
+	var sections []Section
+	start := 0
+	level, title := 0, ""
+	for _, h := range headings {
+		hStart, hEnd := headingLine(src, h)
+		if body := strings.TrimSpace(string(src[start:hStart])); body != "" || title != "" {
+			sections = append(sections, Section{Level: level, Title: title, Content: body})
+		}
+		start, level, title = hEnd, h.Level, plainText(h, src)
+	}
+	if body := strings.TrimSpace(string(src[start:])); body != "" || title != "" {
+		sections = append(sections, Section{Level: level, Title: title, Content: body})
+	}
+	return sections
+}
+
+// headingLine 标题在源文本中的起止位置，Setext 标题包含下划线所在行
+func headingLine(src []byte, h *ast.Heading) (int, int) {
+	lines := h.Lines()
+	if lines.Len() == 0 {
+		return 0, 0
+	}
+	start := bytes.LastIndexByte(src[:lines.At(0).Start], '\n') + 1
+	end := lineEnd(src, lines.At(lines.Len()-1).Stop)
+	if src[start] != '#' && end < len(src) {
+		if underline := bytes.TrimSpace(src[end:lineEnd(src, end)]); len(underline) > 0 &&
+			(len(bytes.Trim(underline, "=")) == 0 || len(bytes.Trim(underline, "-")) == 0) {
+			end = lineEnd(src, end)
+		}
+	}
+	return start, end
+}
+
+// lineEnd 返回 pos 所在行的下一行起始位置
+func lineEnd(src []byte, pos int) int {
+	if i := bytes.IndexByte(src[pos:], '\n'); i >= 0 {
+		return pos + i + 1
+	}
+	return len(src)
+}
+
+// tableOf 提取表格内容
+func tableOf(t *east.Table, src []byte, section string) Table {
+	table := Table{Section: section}
+	for row := t.FirstChild(); row != nil; row = row.NextSibling() {
+		var cells []string
+		for cell := row.FirstChild(); cell != nil; cell = cell.NextSibling() {
+			cells = append(cells, plainText(cell, src))
+		}
+		if _, ok := row.(*east.TableHeader); ok {
+			table.Headers = cells
+		} else {
+			table.Rows = append(table.Rows, cells)
+		}
+	}
+	return table
+}
+
+// plainText 提取节点的纯文本内容
+func plainText(n ast.Node, src []byte) string {
+	var sb strings.Builder
+	writeText(&sb, n, src)
+	return strings.TrimSpace(sb.String())
+}
+
+// writeText 递归写入节点的纯文本，块级节点之间以空格分隔
+func writeText(sb *strings.Builder, n ast.Node, src []byte) {
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		switch c := c.(type) {
+		case *ast.Text:
+			sb.Write(c.Segment.Value(src))
+			if c.SoftLineBreak() || c.HardLineBreak() {
+				sb.WriteByte(' ')
+			}
+		case *ast.String:
+			sb.Write(c.Value)
+		case *ast.AutoLink:
+			sb.Write(c.Label(src))
+		default:
+			if c.Type() == ast.TypeBlock && sb.Len() > 0 {
+				sb.WriteByte(' ')
+			}
+			writeText(sb, c, src)
+		}
+	}
+}
+
+// citationOf 链接节点对应的引用
+func citationOf(n ast.Node, src []byte) Citation {
+	if l, ok := n.(*ast.AutoLink); ok {
+		return Citation{Title: string(l.Label(src)), URL: string(l.URL(src))}
+	}
+	return Citation{Title: plainText(n, src), URL: string(n.(*ast.Link).Destination)}
+}
+
+// dedupCitations 按链接地址去重
+func dedupCitations(items []Citation) []Citation {
+	seen := map[string]bool{}
+	var out []Citation
+	for _, c := range items {
+		if !seen[c.URL] {
+			seen[c.URL] = true
+			out = append(out, c)
+		}
+	}
+	return out
+}
@@ -0,0 +1,502 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"image"
+	_ "image/gif"  // 注册 GIF 解码器，用于读取图片尺寸
+	_ "image/jpeg" // 注册 JPEG 解码器
+	_ "image/png"  // 注册 PNG 解码器
+	"io"
+	"mime"
+	"strings"
+	"time"
+
+	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+)
+
+// DOCX 排版相关常量，长度单位：twip（1/1440 英寸）、EMU（1/914400 英寸）
+const (
+	docxTextWidth   = 9026    // A4 页面减去左右页边距后的正文宽度，twip
+	docxMaxImageEMU = 5731510 // 图片最大宽度，与正文等宽，EMU
+	docxEMUPerPixel = 9525    // 96 DPI 下每像素的 EMU
+	docxListIndent  = 360     // 每级列表的缩进，twip
+)
+
+// 关系类型
+const (
+	relStyles    = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles"
+	relImage     = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/image"
+	relHyperlink = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink"
+)
+
+// docxImageExt Word 支持内嵌的图片格式
+var docxImageExt = map[string]string{"image/png": "png", "image/jpeg": "jpeg", "image/gif": "gif"}
+
+// docxRel 文档关系
+type docxRel struct {
+	id, typ, target string
+	external        bool
+}
+
+// docxMedia 文档包中的文件，如内嵌的图片
+type docxMedia struct {
+	name string
+	data []byte
+}
+
+// docxWriter 将 Markdown 语法树转换为 WordprocessingML
+type docxWriter struct {
+	ctx   context.Context
+	doc   *Document
+	opt   Options
+	src   []byte
+	body  bytes.Buffer
+	rels  []docxRel
+	media []docxMedia
+}
+
+// runProps 文本样式
+type runProps struct {
+	bold, italic, strike, code, link bool
+}
+
+// paraProps 段落样式
+type paraProps struct {
+	style   string
+	left    int // 左缩进
+	hanging int // 悬挂缩进，用于列表符号
+}
+
+// writeDOCX 导出 Word 文档，报告中引用的 PNG/JPEG/GIF 图片产物内嵌到文档中
+func writeDOCX(ctx context.Context, w io.Writer, doc *Document, opt Options) error {
+	d := &docxWriter{ctx: ctx, doc: doc, opt: opt, src: []byte(doc.Markdown)}
+	d.rels = append(d.rels, docxRel{id: "rId1", typ: relStyles, target: "styles.xml"})
+	root := parseMarkdown(d.src)
+
+	// 报告以一级标题开头时作为文档标题，否则使用计划标题
+	first := root.FirstChild()
+	if h, ok := first.(*ast.Heading); ok && h.Level == 1 {
+		d.paragraph(paraProps{style: "Title"}, d.inlines(h, runProps{}))
+		first = first.NextSibling()
+	} else {
+		d.paragraph(paraProps{style: "Title"}, run(doc.Title, runProps{}))
+	}
+	d.paragraph(paraProps{style: "Subtitle"}, run(fmt.Sprintf("%s · %s", doc.Query, doc.CreatedAt.Format("2006-01-02 15:04")), runProps{}))
+	for n := first; n != nil; n = n.NextSibling() {
+		d.block(n, paraProps{}, 0)
+	}
+
+	zw := zip.NewWriter(w)
+	files := []docxMedia{
+		{"[Content_Types].xml", []byte(docxContentTypes)},
+		{"_rels/.rels", []byte(docxPackageRels)},
+		{"docProps/core.xml", d.coreXML()},
+		{"word/document.xml", d.documentXML()},
+		{"word/styles.xml", []byte(docxStyles)},
+		{"word/_rels/document.xml.rels", d.relsXML()},
+	}
+	for _, m := range d.media {
+		files = append(files, docxMedia{"word/media/" + m.name, m.data})
+	}
+	for _, f := range files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: doc.CreatedAt})
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(f.data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// block 转换块级节点
+func (d *docxWriter) block(n ast.Node, pp paraProps, depth int) {
+	switch n := n.(type) {
+	case *ast.Heading:
+		d.paragraph(paraProps{style: fmt.Sprintf("Heading%d", min(n.Level, 4))}, d.inlines(n, runProps{}))
+	case *ast.Paragraph, *ast.TextBlock:
+		d.paragraph(pp, d.inlines(n, runProps{}))
+	case *ast.List:
+		d.list(n, pp, depth)
+	case *ast.Blockquote:
+		for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+			d.block(c, paraProps{style: "Quote", left: pp.left}, depth)
+		}
+	case *ast.FencedCodeBlock, *ast.CodeBlock:
+		var sb strings.Builder
+		lines := n.Lines()
+		for i := 0; i < lines.Len(); i++ {
+			if i > 0 {
+				sb.WriteString("<w:r><w:br/></w:r>")
+			}
+			seg := lines.At(i)
+			sb.WriteString(run(strings.TrimRight(string(seg.Value(d.src)), "\r\n"), runProps{}))
+		}
+		d.paragraph(paraProps{style: "Code", left: pp.left}, sb.String())
+	case *ast.ThematicBreak:
+		d.body.WriteString(`<w:p><w:pPr><w:pBdr><w:bottom w:val="single" w:sz="6" w:space="1" w:color="D0D7DE"/></w:pBdr></w:pPr></w:p>`)
+	case *east.Table:
+		d.table(n)
+	case *ast.HTMLBlock:
+		// 原始 HTML 不导出
+	default:
+		for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+			d.block(c, pp, depth)
+		}
+	}
+}
+
+// list 转换列表，列表符号直接写入文本，不依赖 Word 的编号定义
+func (d *docxWriter) list(l *ast.List, pp paraProps, depth int) {
+	indent := docxListIndent * (depth + 1)
+	i := l.Start
+	for item := l.FirstChild(); item != nil; item = item.NextSibling() {
+		marker := "•"
+		if l.IsOrdered() {
+			marker = fmt.Sprintf("%d.", i)
+			i++
+		}
+		first := true
+		for c := item.FirstChild(); c != nil; c = c.NextSibling() {
+			switch c := c.(type) {
+			case *ast.List:
+				d.list(c, pp, depth+1)
+			case *ast.Paragraph, *ast.TextBlock:
+				content := d.inlines(c, runProps{})
+				if first {
+					content = run(marker+"\t", runProps{}) + content
+					first = false
+				}
+				d.paragraph(paraProps{style: "ListParagraph", left: indent + docxListIndent, hanging: docxListIndent}, content)
+			default:
+				d.block(c, paraProps{left: indent + docxListIndent}, depth+1)
+			}
+		}
+	}
+}
+
+// table 转换表格，表头加粗并在每页重复
+func (d *docxWriter) table(t *east.Table) {
+	cols := 0
+	for row := t.FirstChild(); row != nil; row = row.NextSibling() {
+		cols = max(cols, row.ChildCount())
+	}
+	if cols == 0 {
+		return
+	}
+	d.body.WriteString(`<w:tbl><w:tblPr><w:tblStyle w:val="TableGrid"/><w:tblW w:w="5000" w:type="pct"/></w:tblPr><w:tblGrid>`)
+	for i := 0; i < cols; i++ {
+		fmt.Fprintf(&d.body, `<w:gridCol w:w="%d"/>`, docxTextWidth/cols)
+	}
+	d.body.WriteString(`</w:tblGrid>`)
+	for row := t.FirstChild(); row != nil; row = row.NextSibling() {
+		_, header := row.(*east.TableHeader)
+		d.body.WriteString(`<w:tr>`)
+		if header {
+			d.body.WriteString(`<w:trPr><w:tblHeader/></w:trPr>`)
+		}
+		n := 0
+		for cell := row.FirstChild(); cell != nil; cell = cell.NextSibling() {
+			d.body.WriteString(`<w:tc>`)
+			if header {
+				d.body.WriteString(`<w:tcPr><w:shd w:val="clear" w:color="auto" w:fill="F2F2F2"/></w:tcPr>`)
+			}
+			d.paragraph(paraProps{style: "TableText"}, d.inlines(cell, runProps{bold: header}))
+			d.body.WriteString(`</w:tc>`)
+			n++
+		}
+		// 补齐缺少的单元格，每个单元格至少包含一个段落
+		for ; n < cols; n++ {
+			d.body.WriteString(`<w:tc><w:p/></w:tc>`)
+		}
+		d.body.WriteString(`</w:tr>`)
+	}
+	d.body.WriteString(`</w:tbl>`)
+}
+
+// paragraph 写入段落
+func (d *docxWriter) paragraph(pp paraProps, content string) {
+	d.body.WriteString(`<w:p>`)
+	if pp.style != "" || pp.left > 0 {
+		d.body.WriteString(`<w:pPr>`)
+		if pp.style != "" {
+			fmt.Fprintf(&d.body, `<w:pStyle w:val="%s"/>`, pp.style)
+		}
+		if pp.left > 0 {
+			fmt.Fprintf(&d.body, `<w:ind w:left="%d" w:hanging="%d"/>`, pp.left, pp.hanging)
+		}
+		d.body.WriteString(`</w:pPr>`)
+	}
+	d.body.WriteString(content)
+	d.body.WriteString(`</w:p>`)
+}
+
+// inlines 转换行内节点
+func (d *docxWriter) inlines(n ast.Node, rp runProps) string {
+	var sb, text strings.Builder
+	// 相邻的文本节点合并为一个片段
+	flush := func() {
+		sb.WriteString(run(text.String(), rp))
+		text.Reset()
+	}
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		switch c := c.(type) {
+		case *ast.Text:
+			text.Write(c.Segment.Value(d.src))
+			if c.HardLineBreak() {
+				flush()
+				sb.WriteString("<w:r><w:br/></w:r>")
+			} else if c.SoftLineBreak() {
+				text.WriteByte(' ')
+			}
+			continue
+		case *ast.String:
+			text.Write(c.Value)
+			continue
+		}
+		flush()
+		switch c := c.(type) {
+		case *ast.Emphasis:
+			sub := rp
+			if c.Level >= 2 {
+				sub.bold = true
+			} else {
+				sub.italic = true
+			}
+			sb.WriteString(d.inlines(c, sub))
+		case *east.Strikethrough:
+			sub := rp
+			sub.strike = true
+			sb.WriteString(d.inlines(c, sub))
+		case *ast.CodeSpan:
+			sub := rp
+			sub.code = true
+			sb.WriteString(d.inlines(c, sub))
+		case *ast.Link:
+			sb.WriteString(d.hyperlink(string(c.Destination), d.inlines(c, linkProps(rp))))
+		case *ast.AutoLink:
+			sb.WriteString(d.hyperlink(string(c.URL(d.src)), run(string(c.Label(d.src)), linkProps(rp))))
+		case *ast.Image:
+			sb.WriteString(d.image(c, rp))
+		case *ast.RawHTML:
+			// 原始 HTML 不导出
+		default:
+			sb.WriteString(d.inlines(c, rp))
+		}
+	}
+	flush()
+	return sb.String()
+}
+
+// linkProps 链接文本的样式
+func linkProps(rp runProps) runProps {
+	rp.link = true
+	return rp
+}
+
+// hyperlink 生成超链接，相对地址在未配置 BaseURL 时无法打开，只保留文本
+func (d *docxWriter) hyperlink(dest, content string) string {
+	dest = absURL(d.opt.BaseURL, dest)
+	if !strings.Contains(dest, "://") && !strings.HasPrefix(dest, "mailto:") {
+		return content
+	}
+	id := d.addRel(relHyperlink, dest, true)
+	return fmt.Sprintf(`<w:hyperlink r:id="%s">%s</w:hyperlink>`, id, content)
+}
+
+// image 内嵌图片产物，无法内嵌时输出替代文本及链接
+func (d *docxWriter) image(img *ast.Image, rp runProps) string {
+	alt := plainText(img, d.src)
+	dest := string(img.Destination)
+	e, ok := loadArtifact(d.ctx, d.doc.ThreadID, dest)
+	if ok {
+		mediaType, _, _ := mime.ParseMediaType(e.meta.MIMEType)
+		ext, supported := docxImageExt[mediaType]
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(e.data))
+		if supported && err == nil && cfg.Width > 0 && cfg.Height > 0 {
+			d.media = append(d.media, docxMedia{name: fmt.Sprintf("image%d.%s", len(d.media)+1, ext), data: e.data})
+			id := d.addRel(relImage, "media/"+d.media[len(d.media)-1].name, false)
+			cx, cy := int64(cfg.Width)*docxEMUPerPixel, int64(cfg.Height)*docxEMUPerPixel
+			if cx > docxMaxImageEMU {
+				cx, cy = docxMaxImageEMU, cy*docxMaxImageEMU/cx
+			}
+			return drawing(id, len(d.media), alt, cx, cy)
+		}
+	}
+	if alt == "" {
+		alt = dest
+	}
+	return d.hyperlink(dest, run("["+alt+"]", linkProps(rp)))
+}
+
+// addRel 添加文档关系并返回关系ID
+func (d *docxWriter) addRel(typ, target string, external bool) string {
+	id := fmt.Sprintf("rId%d", len(d.rels)+1)
+	d.rels = append(d.rels, docxRel{id: id, typ: typ, target: target, external: external})
+	return id
+}
+
+// documentXML 生成 word/document.xml
+func (d *docxWriter) documentXML() []byte {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString(`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"` +
+		` xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"` +
+		` xmlns:wp="http://schemas.openxmlformats.org/drawingml/2006/wordprocessingDrawing"` +
+		` xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main"` +
+		` xmlns:pic="http://schemas.openxmlformats.org/drawingml/2006/picture"><w:body>`)
+	buf.Write(d.body.Bytes())
+	// 正文以表格结尾时 Word 要求其后仍有段落
+	buf.WriteString(`<w:p/><w:sectPr><w:pgSz w:w="11906" w:h="16838"/>` +
+		`<w:pgMar w:top="1440" w:right="1440" w:bottom="1440" w:left="1440" w:header="708" w:footer="708" w:gutter="0"/>` +
+		`</w:sectPr></w:body></w:document>`)
+	return buf.Bytes()
+}
+
+// relsXML 生成 word/_rels/document.xml.rels
+func (d *docxWriter) relsXML() []byte {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for _, r := range d.rels {
+		mode := ""
+		if r.external {
+			mode = ` TargetMode="External"`
+		}
+		fmt.Fprintf(&buf, `<Relationship Id="%s" Type="%s" Target="%s"%s/>`, r.id, r.typ, escape(r.target), mode)
+	}
+	buf.WriteString(`</Relationships>`)
+	return buf.Bytes()
+}
+
+// coreXML 生成 docProps/core.xml，记录标题、研究问题和创建时间
+func (d *docxWriter) coreXML() []byte {
+	return []byte(xml.Header + `<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties"` +
+		` xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:dcterms="http://purl.org/dc/terms/"` +
+		` xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">` +
+		`<dc:title>` + escape(d.doc.Title) + `</dc:title>` +
+		`<dc:subject>` + escape(d.doc.Query) + `</dc:subject>` +
+		`<dc:creator>deer-flow-go</dc:creator>` +
+		`<dcterms:created xsi:type="dcterms:W3CDTF">` + d.doc.CreatedAt.UTC().Format(time.RFC3339) + `</dcterms:created>` +
+		`</cp:coreProperties>`)
+}
+
+// run 生成文本片段
+func run(text string, rp runProps) string {
+	if text == "" {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString(`<w:r>`)
+	if rp.bold || rp.italic || rp.strike || rp.code || rp.link {
+		sb.WriteString(`<w:rPr>`)
+		if rp.link {
+			sb.WriteString(`<w:rStyle w:val="Hyperlink"/>`)
+		}
+		if rp.code {
+			sb.WriteString(`<w:rFonts w:ascii="Consolas" w:hAnsi="Consolas" w:cs="Consolas"/>`)
+		}
+		if rp.bold {
+			sb.WriteString(`<w:b/>`)
+		}
+		if rp.italic {
+			sb.WriteString(`<w:i/>`)
+		}
+		if rp.strike {
+			sb.WriteString(`<w:strike/>`)
+		}
+		if rp.code {
+			sb.WriteString(`<w:shd w:val="clear" w:color="auto" w:fill="F2F2F2"/>`)
+		}
+		sb.WriteString(`</w:rPr>`)
+	}
+	// 制表符用于列表符号与正文对齐
+	for i, part := range strings.Split(text, "\t") {
+		if i > 0 {
+			sb.WriteString(`<w:tab/>`)
+		}
+		if part != "" {
+			sb.WriteString(`<w:t xml:space="preserve">` + escape(part) + `</w:t>`)
+		}
+	}
+	sb.WriteString(`</w:r>`)
+	return sb.String()
+}
+
+// drawing 生成内嵌图片
+func drawing(relID string, id int, descr string, cx, cy int64) string {
+	return fmt.Sprintf(`<w:r><w:drawing><wp:inline distT="0" distB="0" distL="0" distR="0">`+
+		`<wp:extent cx="%[3]d" cy="%[4]d"/><wp:docPr id="%[2]d" name="Picture %[2]d" descr="%[5]s"/>`+
+		`<a:graphic><a:graphicData uri="http://schemas.openxmlformats.org/drawingml/2006/picture">`+
+		`<pic:pic><pic:nvPicPr><pic:cNvPr id="%[2]d" name="Picture %[2]d"/><pic:cNvPicPr/></pic:nvPicPr>`+
+		`<pic:blipFill><a:blip r:embed="%[1]s"/><a:stretch><a:fillRect/></a:stretch></pic:blipFill>`+
+		`<pic:spPr><a:xfrm><a:off x="0" y="0"/><a:ext cx="%[3]d" cy="%[4]d"/></a:xfrm><a:prstGeom prst="rect"><a:avLst/></a:prstGeom></pic:spPr>`+
+		`</pic:pic></a:graphicData></a:graphic></wp:inline></w:drawing></w:r>`,
+		relID, id, cx, cy, escape(descr))
+}
+
+// escape 转义 XML 文本
+func escape(s string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+const docxContentTypes = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Default Extension="png" ContentType="image/png"/>` +
+	`<Default Extension="jpeg" ContentType="image/jpeg"/>` +
+	`<Default Extension="gif" ContentType="image/gif"/>` +
+	`<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>` +
+	`<Override PartName="/word/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.styles+xml"/>` +
+	`<Override PartName="/docProps/core.xml" ContentType="application/vnd.openxmlformats-package.core-properties+xml"/>` +
+	`</Types>`
+
+const docxPackageRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>` +
+	`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/package/2006/relationships/metadata/core-properties" Target="docProps/core.xml"/>` +
+	`</Relationships>`
+
+// docxStyles 文档样式，中文使用微软雅黑
+const docxStyles = xml.Header + `<w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">` +
+	`<w:docDefaults><w:rPrDefault><w:rPr><w:rFonts w:ascii="Calibri" w:hAnsi="Calibri" w:eastAsia="Microsoft YaHei" w:cs="Calibri"/>` +
+	`<w:sz w:val="22"/><w:szCs w:val="22"/><w:lang w:val="en-US" w:eastAsia="zh-CN"/></w:rPr></w:rPrDefault>` +
+	`<w:pPrDefault><w:pPr><w:spacing w:after="120" w:line="300" w:lineRule="auto"/></w:pPr></w:pPrDefault></w:docDefaults>` +
+	`<w:style w:type="paragraph" w:default="1" w:styleId="Normal"><w:name w:val="Normal"/><w:qFormat/></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Title"><w:name w:val="Title"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/>` +
+	`<w:pPr><w:spacing w:after="80"/></w:pPr><w:rPr><w:b/><w:color w:val="1F2328"/><w:sz w:val="48"/><w:szCs w:val="48"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Subtitle"><w:name w:val="Subtitle"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/>` +
+	`<w:pPr><w:pBdr><w:bottom w:val="single" w:sz="6" w:space="4" w:color="D0D7DE"/></w:pBdr><w:spacing w:after="240"/></w:pPr>` +
+	`<w:rPr><w:color w:val="59636E"/><w:sz w:val="20"/><w:szCs w:val="20"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Heading1"><w:name w:val="heading 1"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/>` +
+	`<w:pPr><w:keepNext/><w:spacing w:before="360" w:after="120"/><w:outlineLvl w:val="0"/></w:pPr><w:rPr><w:b/><w:sz w:val="36"/><w:szCs w:val="36"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Heading2"><w:name w:val="heading 2"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/>` +
+	`<w:pPr><w:keepNext/><w:spacing w:before="320" w:after="120"/><w:outlineLvl w:val="1"/></w:pPr><w:rPr><w:b/><w:sz w:val="30"/><w:szCs w:val="30"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Heading3"><w:name w:val="heading 3"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/>` +
+	`<w:pPr><w:keepNext/><w:spacing w:before="240" w:after="80"/><w:outlineLvl w:val="2"/></w:pPr><w:rPr><w:b/><w:sz w:val="26"/><w:szCs w:val="26"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Heading4"><w:name w:val="heading 4"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/>` +
+	`<w:pPr><w:keepNext/><w:spacing w:before="200" w:after="80"/><w:outlineLvl w:val="3"/></w:pPr><w:rPr><w:b/><w:sz w:val="22"/><w:szCs w:val="22"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="ListParagraph"><w:name w:val="List Paragraph"/><w:basedOn w:val="Normal"/><w:qFormat/>` +
+	`<w:pPr><w:spacing w:after="60"/></w:pPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Quote"><w:name w:val="Quote"/><w:basedOn w:val="Normal"/><w:qFormat/>` +
+	`<w:pPr><w:pBdr><w:left w:val="single" w:sz="18" w:space="8" w:color="D0D7DE"/></w:pBdr><w:ind w:left="240"/></w:pPr>` +
+	`<w:rPr><w:color w:val="59636E"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Code"><w:name w:val="Code"/><w:basedOn w:val="Normal"/>` +
+	`<w:pPr><w:shd w:val="clear" w:color="auto" w:fill="F6F8FA"/><w:spacing w:after="120" w:line="240" w:lineRule="auto"/></w:pPr>` +
+	`<w:rPr><w:rFonts w:ascii="Consolas" w:hAnsi="Consolas" w:cs="Consolas"/><w:sz w:val="18"/><w:szCs w:val="18"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="TableText"><w:name w:val="Table Text"/><w:basedOn w:val="Normal"/>` +
+	`<w:pPr><w:spacing w:before="40" w:after="40"/></w:pPr><w:rPr><w:sz w:val="20"/><w:szCs w:val="20"/></w:rPr></w:style>` +
+	`<w:style w:type="character" w:styleId="Hyperlink"><w:name w:val="Hyperlink"/><w:rPr><w:color w:val="0563C1"/><w:u w:val="single"/></w:rPr></w:style>` +
+	`<w:style w:type="table" w:styleId="TableGrid"><w:name w:val="Table Grid"/><w:tblPr><w:tblBorders>` +
+	`<w:top w:val="single" w:sz="4" w:space="0" w:color="D0D7DE"/><w:left w:val="single" w:sz="4" w:space="0" w:color="D0D7DE"/>` +
+	`<w:bottom w:val="single" w:sz="4" w:space="0" w:color="D0D7DE"/><w:right w:val="single" w:sz="4" w:space="0" w:color="D0D7DE"/>` +
+	`<w:insideH w:val="single" w:sz="4" w:space="0" w:color="D0D7DE"/><w:insideV w:val="single" w:sz="4" w:space="0" w:color="D0D7DE"/>` +
+	`</w:tblBorders><w:tblCellMar><w:left w:w="108" w:type="dxa"/><w:right w:w="108" w:type="dxa"/></w:tblCellMar></w:tblPr></w:style>` +
+	`</w:styles>`
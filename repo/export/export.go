@@ -0,0 +1,158 @@
+package export
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/HildaM/logs/slog"
+
+	"github.com/hildam/deer-flow-go/entity/model"
+	"github.com/hildam/deer-flow-go/repo/artifact"
+)
+
+// 支持的导出格式
+const (
+	FormatHTML     = "html" // 独立的 HTML 页面，图表以 data URI 内嵌
+	FormatPDF      = "pdf"  // 针对打印排版的 HTML，在浏览器中打印即可得到 PDF
+	FormatDOCX     = "docx" // Word 文档
+	FormatJSON     = "json" // 结构化文档，见 Document
+	FormatMarkdown = "md"   // 原始 Markdown 报告
+)
+
+// maxEmbedSize 内嵌到导出文件中的单个产物大小上限
+const maxEmbedSize = 20 * 1024 * 1024
+
+var (
+	// ErrNoReport 运行没有生成最终报告
+	ErrNoReport = errors.New("run has no report")
+	// ErrUnsupportedFormat 不支持的导出格式
+	ErrUnsupportedFormat = errors.New("unsupported export format")
+)
+
+// formatInfo 导出格式对应的文件后缀和 MIME 类型
+type formatInfo struct {
+	suffix      string
+	contentType string
+}
+
+var formats = map[string]formatInfo{
+	FormatHTML:     {".html", "text/html; charset=utf-8"},
+	FormatPDF:      {".print.html", "text/html; charset=utf-8"},
+	FormatDOCX:     {".docx", "application/vnd.openxmlformats-officedocument.wordprocessingml.document"},
+	FormatJSON:     {".json", "application/json; charset=utf-8"},
+	FormatMarkdown: {".md", "text/markdown; charset=utf-8"},
+}
+
+// Options 导出选项
+type Options struct {
+	Format  string // 导出格式，默认 html
+	BaseURL string // 服务访问地址，如 http://localhost:8888，用于将报告中的产物链接转换为绝对地址，为空时保留相对地址
+}
+
+// Formats 支持的导出格式列表
+func Formats() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ContentType 导出格式对应的 MIME 类型
+func ContentType(format string) string {
+	return formats[format].contentType
+}
+
+// FileName 导出文件的默认文件名
+func FileName(threadID, format string) string {
+	return threadID + formats[format].suffix
+}
+
+// Export 将运行的最终报告及引用、产物导出为指定格式
+func Export(ctx context.Context, rec *model.RunRecord, opt Options, w io.Writer) error {
+	if opt.Format == "" {
+		opt.Format = FormatHTML
+	}
+	if _, ok := formats[opt.Format]; !ok {
+		return fmt.Errorf("%w: %s, supported: %s", ErrUnsupportedFormat, opt.Format, strings.Join(Formats(), ", "))
+	}
+	if strings.TrimSpace(rec.Report) == "" {
+		return ErrNoReport
+	}
+
+	artifacts, err := artifact.List(ctx, rec.ID)
+	if err != nil {
+		slog.Error("Export failed, list artifacts err = %v, thread_id = %s", err, rec.ID)
+	}
+	doc := NewDocument(rec, artifacts)
+
+	switch opt.Format {
+	case FormatMarkdown:
+		_, err = io.WriteString(w, rec.Report)
+		return err
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(doc)
+	case FormatDOCX:
+		return writeDOCX(ctx, w, doc, opt)
+	default:
+		return writeHTML(ctx, w, doc, opt)
+	}
+}
+
+// embedded 报告中引用的、可以内嵌到导出文件的产物
+type embedded struct {
+	meta *model.Artifact
+	data []byte
+}
+
+// loadArtifact 读取报告中链接指向的本次运行的产物，链接不是产物地址时返回 false
+func loadArtifact(ctx context.Context, threadID, dest string) (*embedded, bool) {
+	prefix := artifact.URL(threadID, "")
+	if !strings.HasPrefix(dest, prefix) {
+		return nil, false
+	}
+	name, err := url.PathUnescape(strings.TrimPrefix(dest, prefix))
+	if err != nil {
+		return nil, false
+	}
+	a, rc, err := artifact.Open(ctx, threadID, name)
+	if err != nil {
+		slog.Error("loadArtifact failed, open err = %v, thread_id = %s, name = %s", err, threadID, name)
+		return nil, false
+	}
+	defer rc.Close()
+	if a.Size > maxEmbedSize {
+		return nil, false
+	}
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		slog.Error("loadArtifact failed, read err = %v, thread_id = %s, name = %s", err, threadID, name)
+		return nil, false
+	}
+	return &embedded{meta: a, data: data}, true
+}
+
+// dataURI 将产物编码为 data URI
+func (e *embedded) dataURI() string {
+	mediaType, _, _ := mime.ParseMediaType(e.meta.MIMEType)
+	return "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(e.data)
+}
+
+// absURL 将服务内的相对地址转换为绝对地址
+func absURL(baseURL, dest string) string {
+	if baseURL == "" || !strings.HasPrefix(dest, "/") {
+		return dest
+	}
+	return strings.TrimSuffix(baseURL, "/") + dest
+}
@@ -0,0 +1,198 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hildam/deer-flow-go/entity/model"
+	"github.com/hildam/deer-flow-go/repo/artifact"
+)
+
+const testReport = `# Electric Vehicle Market 2025
+
+## Key Points
+
+- Sales grew **35%** year over year
+- China accounts for [most demand](https://example.com/china)
+
+## Overview
+
+Battery prices keep falling.
+
+![Sales chart](/api/runs/run-1/artifacts/chart.png)
+
+| Region | Share |
+|--------|-------|
+| China  | 60%   |
+| Europe | 25%   |
+
+## Key Citations
+
+- [IEA Global EV Outlook](https://www.iea.org/ev)
+
+- [BloombergNEF](https://about.bnef.com/ev)
+`
+
+// newTestRun 创建测试用的运行记录及图表产物
+func newTestRun(t *testing.T) *model.RunRecord {
+	t.Helper()
+	artifact.SetStore(artifact.NewLocalStore(t.TempDir()))
+	var img bytes.Buffer
+	_ = png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 40, 20)))
+	ctx := context.Background()
+	if err := artifact.Put(ctx, artifact.New(ctx, "run-1", "chart.png", "execute_code"), &img); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	return &model.RunRecord{
+		ID:         "run-1",
+		Query:      "EV market outlook",
+		Report:     testReport,
+		Status:     model.RunCompleted,
+		StartedAt:  time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		FinishedAt: time.Date(2025, 1, 2, 3, 14, 5, 0, time.UTC),
+	}
+}
+
+func TestExportJSON(t *testing.T) {
+	rec := newTestRun(t)
+	var buf bytes.Buffer
+	if err := Export(context.Background(), rec, Options{Format: FormatJSON}, &buf); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	doc := &Document{}
+	if err := json.Unmarshal(buf.Bytes(), doc); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	if doc.Title != "Electric Vehicle Market 2025" {
+		t.Errorf("title = %q", doc.Title)
+	}
+	if len(doc.KeyPoints) != 2 || doc.KeyPoints[0] != "Sales grew 35% year over year" {
+		t.Errorf("key points = %q", doc.KeyPoints)
+	}
+	var titles []string
+	for _, s := range doc.Sections {
+		titles = append(titles, s.Title)
+	}
+	if got := strings.Join(titles, "|"); got != "Electric Vehicle Market 2025|Key Points|Overview|Key Citations" {
+		t.Errorf("sections = %s", got)
+	}
+	if !strings.HasPrefix(doc.Sections[2].Content, "Battery prices keep falling.") || strings.Contains(doc.Sections[2].Content, "## ") {
+		t.Errorf("overview content = %q", doc.Sections[2].Content)
+	}
+	if len(doc.Tables) != 1 || doc.Tables[0].Section != "Overview" ||
+		strings.Join(doc.Tables[0].Headers, ",") != "Region,Share" || len(doc.Tables[0].Rows) != 2 {
+		t.Errorf("tables = %+v", doc.Tables)
+	}
+	if len(doc.Citations) != 2 || doc.Citations[1].URL != "https://about.bnef.com/ev" {
+		t.Errorf("citations = %+v", doc.Citations)
+	}
+	if len(doc.Artifacts) != 1 || doc.Artifacts[0].Name != "chart.png" {
+		t.Errorf("artifacts = %+v", doc.Artifacts)
+	}
+}
+
+func TestExportHTML(t *testing.T) {
+	rec := newTestRun(t)
+	for _, format := range []string{FormatHTML, FormatPDF} {
+		var buf bytes.Buffer
+		if err := Export(context.Background(), rec, Options{Format: format, BaseURL: "http://localhost:8888"}, &buf); err != nil {
+			t.Fatalf("Export %s: %v", format, err)
+		}
+		out := buf.String()
+		if !strings.Contains(out, `<img src="data:image/png;base64,`) {
+			t.Errorf("%s: chart not embedded", format)
+		}
+		if !strings.Contains(out, "<table>") || !strings.Contains(out, "<title>Electric Vehicle Market 2025</title>") {
+			t.Errorf("%s: missing table or title", format)
+		}
+		if got := strings.Contains(out, "@page"); got != (format == FormatPDF) {
+			t.Errorf("%s: print layout = %v", format, got)
+		}
+	}
+}
+
+func TestExportDOCX(t *testing.T) {
+	rec := newTestRun(t)
+	var buf bytes.Buffer
+	if err := Export(context.Background(), rec, Options{Format: FormatDOCX}, &buf); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("zip: %v", err)
+	}
+	parts := map[string]string{}
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		parts[f.Name] = string(data)
+		// 所有 XML 部件必须格式正确，否则 Word 无法打开
+		if strings.HasSuffix(f.Name, ".xml") || strings.HasSuffix(f.Name, ".rels") {
+			dec := xml.NewDecoder(bytes.NewReader(data))
+			for {
+				if _, err := dec.Token(); err == io.EOF {
+					break
+				} else if err != nil {
+					t.Fatalf("%s: invalid xml: %v", f.Name, err)
+				}
+			}
+		}
+	}
+	if _, ok := parts["word/media/image1.png"]; !ok {
+		t.Errorf("chart not embedded, parts = %v", len(parts))
+	}
+	doc := parts["word/document.xml"]
+	for _, want := range []string{`<w:pStyle w:val="Title"/>`, `<w:tbl>`, `<w:drawing>`, `<w:hyperlink r:id=`, "Battery prices keep falling."} {
+		if !strings.Contains(doc, want) {
+			t.Errorf("document.xml missing %s", want)
+		}
+	}
+	if !strings.Contains(parts["word/_rels/document.xml.rels"], `Target="https://www.iea.org/ev" TargetMode="External"`) {
+		t.Errorf("hyperlink relationship missing")
+	}
+}
+
+func TestExportErrors(t *testing.T) {
+	rec := newTestRun(t)
+	if err := Export(context.Background(), rec, Options{Format: "pptx"}, io.Discard); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("pptx err = %v, want ErrUnsupportedFormat", err)
+	}
+	rec.Report = ""
+	if err := Export(context.Background(), rec, Options{}, io.Discard); !errors.Is(err, ErrNoReport) {
+		t.Errorf("empty report err = %v, want ErrNoReport", err)
+	}
+}
+
+func TestNewDocumentStructured(t *testing.T) {
+	rec := newTestRun(t)
+	rec.Structured = &model.Report{
+		Title:     "EV Outlook",
+		KeyPoints: []string{"Sales grew"},
+		Sections: []model.ReportSection{{
+			Title:  "Regions",
+			Tables: []model.ReportTable{{Headers: []string{"Region", "Share"}, Rows: [][]string{{"China", "60%"}}}},
+		}},
+		Citations: []model.ReportCitation{{Title: "IEA", URL: "https://www.iea.org/ev"}},
+	}
+	rec.Report = rec.Structured.Markdown()
+
+	doc := NewDocument(rec, nil)
+	if doc.Title != "EV Outlook" || len(doc.KeyPoints) != 1 || len(doc.Citations) != 1 {
+		t.Errorf("doc = %+v", doc)
+	}
+	if len(doc.Tables) != 1 || doc.Tables[0].Section != "Regions" || doc.Tables[0].Rows[0][1] != "60%" {
+		t.Errorf("tables = %+v", doc.Tables)
+	}
+}
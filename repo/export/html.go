@@ -0,0 +1,102 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"html/template"
+	"io"
+
+	"github.com/yuin/goldmark/ast"
+
+	"github.com/hildam/deer-flow-go/repo/artifact"
+)
+
+// htmlTemplate 导出 HTML 的页面模板，样式内联，无需访问外部资源
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="{{if .Doc.Locale}}{{.Doc.Locale}}{{else}}en{{end}}">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Doc.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", "PingFang SC", "Microsoft YaHei", sans-serif; line-height: 1.65; color: #1f2328; margin: 0; }
+main { max-width: 860px; margin: 0 auto; padding: 40px 24px; }
+header.meta { color: #59636e; font-size: 0.9em; border-bottom: 1px solid #d1d9e0; padding-bottom: 12px; margin-bottom: 24px; }
+header.meta p { margin: 2px 0; }
+h1, h2, h3, h4 { line-height: 1.3; margin-top: 1.6em; }
+h1 { font-size: 2em; margin-top: 0; }
+h2 { border-bottom: 1px solid #d1d9e0; padding-bottom: 0.3em; }
+a { color: #0969da; word-break: break-word; }
+img { max-width: 100%; height: auto; display: block; margin: 16px auto; }
+table { border-collapse: collapse; width: 100%; margin: 16px 0; font-size: 0.95em; }
+th, td { border: 1px solid #d1d9e0; padding: 6px 12px; text-align: left; vertical-align: top; }
+th { background: #f6f8fa; }
+tr:nth-child(even) td { background: #fbfcfd; }
+code { font-family: ui-monospace, Consolas, monospace; background: #f6f8fa; padding: 0.1em 0.3em; border-radius: 4px; font-size: 0.9em; }
+pre { background: #f6f8fa; padding: 12px 16px; border-radius: 6px; overflow-x: auto; }
+pre code { padding: 0; background: none; }
+blockquote { margin: 0; padding: 0 1em; color: #59636e; border-left: 4px solid #d1d9e0; }
+{{if .Print}}
+@page { size: A4; margin: 20mm 18mm; }
+main { max-width: none; padding: 0; }
+.cover { height: 240mm; display: flex; flex-direction: column; justify-content: center; page-break-after: always; }
+.cover h1 { font-size: 2.4em; border: none; }
+{{end}}
+@media print {
+  body { font-size: 11pt; }
+  a { color: inherit; text-decoration: none; }
+  h1, h2, h3, h4 { page-break-after: avoid; }
+  img, table, pre, blockquote { page-break-inside: avoid; }
+}
+</style>
+</head>
+<body>
+<main>
+{{if .Print}}<section class="cover">
+<h1>{{.Doc.Title}}</h1>
+<p>{{.Doc.Query}}</p>
+<p>{{.Doc.CreatedAt.Format "2006-01-02 15:04"}}</p>
+</section>
+{{else}}<header class="meta">
+<p><strong>{{.Doc.Query}}</strong></p>
+<p>{{.Doc.CreatedAt.Format "2006-01-02 15:04"}} · {{.Doc.ThreadID}}</p>
+</header>
+{{end}}<article>
+{{.Body}}
+</article>
+</main>
+</body>
+</html>
+`))
+
+// writeHTML 导出独立的 HTML 页面，报告中引用的图片产物以 data URI 内嵌
+func writeHTML(ctx context.Context, w io.Writer, doc *Document, opt Options) error {
+	src := []byte(doc.Markdown)
+	root := parseMarkdown(src)
+	_ = ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Image:
+			if e, ok := loadArtifact(ctx, doc.ThreadID, string(n.Destination)); ok && artifact.IsImage(e.meta.MIMEType) {
+				n.Destination = []byte(e.dataURI())
+			} else {
+				n.Destination = []byte(absURL(opt.BaseURL, string(n.Destination)))
+			}
+		case *ast.Link:
+			n.Destination = []byte(absURL(opt.BaseURL, string(n.Destination)))
+		}
+		return ast.WalkContinue, nil
+	})
+
+	var body bytes.Buffer
+	if err := markdown.Renderer().Render(&body, src, root); err != nil {
+		return err
+	}
+	return htmlTemplate.Execute(w, map[string]any{
+		"Doc":   doc,
+		"Print": opt.Format == FormatPDF,
+		"Body":  template.HTML(body.String()),
+	})
+}
@@ -0,0 +1,130 @@
+package flowchart
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cloudwego/eino/compose"
+)
+
+// DOT 中起止节点的 ID
+const (
+	dotStart = "__start__"
+	dotEnd   = "__end__"
+)
+
+// DOT 渲染为 Graphviz DOT，子图以 cluster 展示，智能体之间的边连接子图的入口和出口节点
+// overlay 不为 nil 时标出执行过的节点及其耗时，经过的边以流转序号作为标签并加粗
+func (g *Graph) DOT(overlay *Overlay) string {
+	d := &dotWriter{}
+	d.printf(0, "digraph %s {", dotQuote(g.Name))
+	d.printf(1, "compound=true;")
+	d.printf(1, `node [shape=box, style="rounded"];`)
+	d.printf(1, `%s [label="START", shape=ellipse];`, dotQuote(dotStart))
+	d.printf(1, `%s [label="END", shape=ellipse];`, dotQuote(dotEnd))
+	d.graph(g, "", 1, overlay)
+	d.printf(0, "}")
+	return d.sb.String()
+}
+
+// dotWriter 按缩进输出 DOT 语句
+type dotWriter struct {
+	sb strings.Builder
+}
+
+// printf 输出一行
+func (d *dotWriter) printf(depth int, format string, args ...any) {
+	d.sb.WriteString(strings.Repeat("    ", depth))
+	d.sb.WriteString(fmt.Sprintf(format, args...))
+	d.sb.WriteString("\n")
+}
+
+// graph 输出图的节点和边，prefix 为子图节点 ID 的前缀，顶层图为空
+// 子图内部的 START 和 END 不单独展示
+func (d *dotWriter) graph(g *Graph, prefix string, depth int, overlay *Overlay) {
+	nodes := map[string]*Node{}
+	for _, n := range g.Nodes {
+		nodes[n.Key] = n
+		stat := overlay.visit(n.Key)
+		attrs := []string{"label=" + dotQuote(stat.label(n.Key))}
+		if stat != nil {
+			fill, stroke := stat.colors()
+			attrs = append(attrs, `style="rounded,filled"`, "fillcolor="+dotQuote(fill), "color="+dotQuote(stroke))
+		}
+		if n.Sub == nil {
+			d.printf(depth, "%s [%s];", dotQuote(prefix+n.Key), strings.Join(attrs, ", "))
+			continue
+		}
+		d.printf(depth, "subgraph %s {", dotQuote(dotCluster(prefix+n.Key)))
+		for _, attr := range attrs {
+			d.printf(depth+1, "%s;", attr)
+		}
+		d.graph(n.Sub, prefix+n.Key+"/", depth+1, nil)
+		d.printf(depth, "}")
+	}
+
+	for _, e := range g.Edges {
+		if prefix != "" && (e.From == compose.START || e.To == compose.END) {
+			continue
+		}
+		attrs := []string{}
+		from, to := prefix+e.From, prefix+e.To
+		switch {
+		case e.From == compose.START:
+			from = dotStart
+		case nodes[e.From] != nil && nodes[e.From].Sub != nil:
+			from = dotAnchor(nodes[e.From], prefix, false)
+			attrs = append(attrs, "ltail="+dotQuote(dotCluster(prefix+e.From)))
+		}
+		switch {
+		case e.To == compose.END:
+			to = dotEnd
+		case nodes[e.To] != nil && nodes[e.To].Sub != nil:
+			to = dotAnchor(nodes[e.To], prefix, true)
+			attrs = append(attrs, "lhead="+dotQuote(dotCluster(prefix+e.To)))
+		}
+		if e.Branch {
+			attrs = append(attrs, "style=dashed")
+		}
+		if hops := overlay.hops(e.From, e.To); len(hops) > 0 {
+			attrs = append(attrs, "label="+dotQuote(hopLabel(hops)), "color="+dotQuote(colorVisited), "penwidth=2")
+		}
+		line := fmt.Sprintf("%s -> %s", dotQuote(from), dotQuote(to))
+		if len(attrs) > 0 {
+			line += " [" + strings.Join(attrs, ", ") + "]"
+		}
+		d.printf(depth, "%s;", line)
+	}
+}
+
+// dotAnchor 子图在边上的连接节点，进入时为入口节点，离开时为出口节点，嵌套子图时逐层查找
+func dotAnchor(n *Node, prefix string, in bool) string {
+	for n.Sub != nil {
+		key := n.Sub.exit()
+		if in {
+			key = n.Sub.entry()
+		}
+		prefix += n.Key + "/"
+		next := (*Node)(nil)
+		for _, sn := range n.Sub.Nodes {
+			if sn.Key == key {
+				next = sn
+			}
+		}
+		if next == nil {
+			return prefix + key
+		}
+		n = next
+	}
+	return prefix + n.Key
+}
+
+// dotCluster 子图的 cluster 名称，Graphviz 只将 cluster 开头的子图作为分组展示
+func dotCluster(path string) string {
+	return "cluster_" + idPattern.ReplaceAllString(path, "_")
+}
+
+// dotQuote 以双引号包裹 ID 或标签
+func dotQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
@@ -0,0 +1,247 @@
+package flowchart
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/compose"
+
+	"github.com/hildam/deer-flow-go/entity/model"
+)
+
+// Graph 流程图，由编译后的图结构生成，智能体内部的子图保存在节点的 Sub 中
+type Graph struct {
+	Name  string
+	Nodes []*Node // 按从 START 开始的遍历顺序排列
+	Edges []Edge
+}
+
+// Node 流程图节点
+type Node struct {
+	Key string
+	Sub *Graph // 节点为子图时的内部结构
+}
+
+// Edge 流程图的边
+type Edge struct {
+	From   string
+	To     string
+	Branch bool // 由分支决定是否流转
+}
+
+// FromGraphInfo 根据编译回调得到的图结构生成流程图
+func FromGraphInfo(info *compose.GraphInfo) *Graph {
+	g := &Graph{Name: info.Name}
+
+	// 收集边，同一对节点只保留一条，分支优先
+	type pair struct{ from, to string }
+	edges := map[pair]bool{}
+	for from, tos := range info.Edges {
+		for _, to := range tos {
+			if _, ok := edges[pair{from, to}]; !ok {
+				edges[pair{from, to}] = false
+			}
+		}
+	}
+	for from, branches := range info.Branches {
+		for _, b := range branches {
+			for to := range b.GetEndNode() {
+				edges[pair{from, to}] = true
+			}
+		}
+	}
+	next := map[string][]string{}
+	for p := range edges {
+		next[p.from] = append(next[p.from], p.to)
+	}
+	for from := range next {
+		sort.Strings(next[from])
+	}
+
+	// 从 START 广度优先遍历确定节点顺序，无法到达的节点按名称排在最后
+	order := []string{}
+	seen := map[string]bool{compose.START: true, compose.END: true}
+	queue := []string{compose.START}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, to := range next[cur] {
+			if !seen[to] {
+				seen[to] = true
+				order = append(order, to)
+				queue = append(queue, to)
+			}
+		}
+	}
+	rest := []string{}
+	for key := range info.Nodes {
+		if !seen[key] {
+			rest = append(rest, key)
+		}
+	}
+	sort.Strings(rest)
+	order = append(order, rest...)
+
+	for _, key := range order {
+		node := &Node{Key: key}
+		if sub := info.Nodes[key].GraphInfo; sub != nil {
+			node.Sub = FromGraphInfo(sub)
+		}
+		g.Nodes = append(g.Nodes, node)
+	}
+
+	// 边按起点的顺序排列
+	rank := map[string]int{compose.START: -1}
+	for i, key := range order {
+		rank[key] = i
+	}
+	rank[compose.END] = len(order)
+	for p, branch := range edges {
+		g.Edges = append(g.Edges, Edge{From: p.from, To: p.to, Branch: branch})
+	}
+	sort.Slice(g.Edges, func(i, j int) bool {
+		a, b := g.Edges[i], g.Edges[j]
+		if rank[a.From] != rank[b.From] {
+			return rank[a.From] < rank[b.From]
+		}
+		return rank[a.To] < rank[b.To]
+	})
+	return g
+}
+
+// entry 子图的入口节点，即 START 指向的第一个节点
+func (g *Graph) entry() string {
+	for _, e := range g.Edges {
+		if e.From == compose.START && e.To != compose.END {
+			return e.To
+		}
+	}
+	if len(g.Nodes) > 0 {
+		return g.Nodes[0].Key
+	}
+	return ""
+}
+
+// exit 子图的出口节点，即最后一个指向 END 的节点
+func (g *Graph) exit() string {
+	exit := ""
+	for _, e := range g.Edges {
+		if e.To == compose.END && e.From != compose.START {
+			exit = e.From
+		}
+	}
+	if exit == "" && len(g.Nodes) > 0 {
+		exit = g.Nodes[len(g.Nodes)-1].Key
+	}
+	return exit
+}
+
+// Overlay 运行记录在流程图上的叠加信息：执行过的节点、耗时和实际经过的边
+type Overlay struct {
+	Visits map[string]*NodeStat // 节点 -> 执行统计
+	Hops   map[[2]string][]int  // 边 -> 第几次流转经过该边，从 1 开始
+	count  int                  // 已记录的流转次数
+}
+
+// NodeStat 节点的执行统计
+type NodeStat struct {
+	Count      int   // 执行次数
+	DurationMs int64 // 总耗时，单位毫秒
+	Failed     bool  // 是否有执行出错
+}
+
+// NewOverlay 根据运行记录中的执行路径生成叠加信息
+func NewOverlay(rec *model.RunRecord) *Overlay {
+	o := &Overlay{Visits: map[string]*NodeStat{}, Hops: map[[2]string][]int{}}
+	prev := compose.START
+	for _, v := range rec.Path {
+		stat := o.Visits[v.Node]
+		if stat == nil {
+			stat = &NodeStat{}
+			o.Visits[v.Node] = stat
+		}
+		stat.Count++
+		stat.DurationMs += v.DurationMs
+		stat.Failed = stat.Failed || v.Error != ""
+		o.hop(prev, v.Node)
+		prev = v.Node
+	}
+	if rec.Status == model.RunCompleted && prev != compose.START {
+		o.hop(prev, compose.END)
+	}
+	return o
+}
+
+// hop 记录一次流转
+func (o *Overlay) hop(from, to string) {
+	o.count++
+	key := [2]string{from, to}
+	o.Hops[key] = append(o.Hops[key], o.count)
+}
+
+// visit 节点的执行统计，overlay 为 nil 或节点未执行时返回 nil
+func (o *Overlay) visit(node string) *NodeStat {
+	if o == nil {
+		return nil
+	}
+	return o.Visits[node]
+}
+
+// hops 经过边的流转序号，overlay 为 nil 或未经过时返回 nil
+func (o *Overlay) hops(from, to string) []int {
+	if o == nil {
+		return nil
+	}
+	return o.Hops[[2]string{from, to}]
+}
+
+// label 节点标签，执行过的节点附带执行次数和耗时
+func (s *NodeStat) label(name string) string {
+	if s == nil {
+		return name
+	}
+	label := fmt.Sprintf("%s · %d× · %s", name, s.Count, formatDuration(s.DurationMs))
+	if s.Failed {
+		label += " · failed"
+	}
+	return label
+}
+
+// colors 节点的填充色和边框色
+func (s *NodeStat) colors() (fill, stroke string) {
+	if s.Failed {
+		return fillFailed, colorFailed
+	}
+	return fillVisited, colorVisited
+}
+
+// hopLabel 边标签，按顺序列出经过该边的流转序号
+func hopLabel(hops []int) string {
+	labels := make([]string, 0, len(hops))
+	for _, h := range hops {
+		labels = append(labels, fmt.Sprintf("#%d", h))
+	}
+	return strings.Join(labels, ",")
+}
+
+// formatDuration 格式化耗时
+func formatDuration(ms int64) string {
+	if ms < 1000 {
+		return fmt.Sprintf("%dms", ms)
+	}
+	return fmt.Sprintf("%.1fs", (time.Duration(ms) * time.Millisecond).Seconds())
+}
+
+// idPattern 流程图 ID 中不允许出现的字符
+var idPattern = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// 叠加信息的样式
+const (
+	colorVisited = "#2e7d32"
+	fillVisited  = "#e8f5e9"
+	colorFailed  = "#c62828"
+	fillFailed   = "#ffebee"
+)
@@ -0,0 +1,118 @@
+package flowchart
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/compose"
+
+	"github.com/hildam/deer-flow-go/entity/model"
+)
+
+// infoRecorder 记录编译得到的图结构
+type infoRecorder struct {
+	info *compose.GraphInfo
+}
+
+func (r *infoRecorder) OnFinish(_ context.Context, info *compose.GraphInfo) {
+	r.info = info
+}
+
+// testGraph 构造一个包含子图和分支的图：START -> a(load -> router) -> b | END，b -> END
+func testGraph(t *testing.T) *Graph {
+	t.Helper()
+	pass := compose.InvokableLambda(func(ctx context.Context, in string) (string, error) { return in, nil })
+
+	sub := compose.NewGraph[string, string]()
+	_ = sub.AddLambdaNode("load", pass)
+	_ = sub.AddLambdaNode("router", pass)
+	_ = sub.AddEdge(compose.START, "load")
+	_ = sub.AddEdge("load", "router")
+	_ = sub.AddEdge("router", compose.END)
+
+	g := compose.NewGraph[string, string]()
+	_ = g.AddGraphNode("a", sub)
+	_ = g.AddLambdaNode("b", pass)
+	_ = g.AddEdge(compose.START, "a")
+	_ = g.AddBranch("a", compose.NewGraphBranch(func(ctx context.Context, in string) (string, error) {
+		return "b", nil
+	}, map[string]bool{"b": true, compose.END: true}))
+	_ = g.AddEdge("b", compose.END)
+
+	rec := &infoRecorder{}
+	if _, err := g.Compile(context.Background(), compose.WithGraphName("test"),
+		compose.WithGraphCompileCallbacks(rec)); err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	return FromGraphInfo(rec.info)
+}
+
+func TestFromGraphInfo(t *testing.T) {
+	g := testGraph(t)
+	if len(g.Nodes) != 2 || g.Nodes[0].Key != "a" || g.Nodes[1].Key != "b" {
+		t.Fatalf("nodes = %+v, want [a b]", g.Nodes)
+	}
+	if sub := g.Nodes[0].Sub; sub == nil || sub.entry() != "load" || sub.exit() != "router" {
+		t.Fatalf("sub = %+v, want load -> router", sub)
+	}
+	want := []Edge{
+		{From: compose.START, To: "a"},
+		{From: "a", To: "b", Branch: true},
+		{From: "a", To: compose.END, Branch: true},
+		{From: "b", To: compose.END},
+	}
+	if !reflect.DeepEqual(g.Edges, want) {
+		t.Errorf("edges = %+v, want %+v", g.Edges, want)
+	}
+}
+
+func TestMermaid(t *testing.T) {
+	g := testGraph(t)
+	overlay := NewOverlay(&model.RunRecord{
+		Status: model.RunCompleted,
+		Path:   []model.NodeVisit{{Node: "a", DurationMs: 1500}, {Node: "b", DurationMs: 20, Error: "boom"}},
+	})
+	got := g.Mermaid(overlay)
+	for _, line := range []string{
+		`subgraph a ["a · 1× · 1.5s"]`,
+		"a__load --> a__router",
+		`__start__ -->|"#1"| a`,
+		`a -.->|"#2"| b`,
+		"a -.-> __end__",
+		`b["b · 1× · 20ms · failed"]`,
+		`b -->|"#3"| __end__`,
+		"style b fill:" + fillFailed,
+		// 子图内的边序号为 0，顶层的边依次为 1 到 4
+		"linkStyle 1,2,4 stroke:",
+	} {
+		if !strings.Contains(got, line) {
+			t.Errorf("Mermaid() missing %q:\n%s", line, got)
+		}
+	}
+	if plain := g.Mermaid(nil); strings.Contains(plain, "style") || strings.Contains(plain, "#1") {
+		t.Errorf("Mermaid(nil) should not contain overlay:\n%s", plain)
+	}
+}
+
+func TestDOT(t *testing.T) {
+	g := testGraph(t)
+	overlay := NewOverlay(&model.RunRecord{
+		Status: model.RunFailed,
+		Path:   []model.NodeVisit{{Node: "a"}},
+	})
+	got := g.DOT(overlay)
+	for _, line := range []string{
+		`digraph "test" {`,
+		`subgraph "cluster_a" {`,
+		`"a/load" -> "a/router";`,
+		`"__start__" -> "a/load" [lhead="cluster_a", label="#1"`,
+		`"a/router" -> "b" [ltail="cluster_a", style=dashed];`,
+		`"a/router" -> "__end__" [ltail="cluster_a", style=dashed];`,
+	} {
+		if !strings.Contains(got, line) {
+			t.Errorf("DOT() missing %q:\n%s", line, got)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package flowchart
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cloudwego/eino/compose"
+)
+
+// Mermaid 中 end 是保留字，起止节点使用单独的 ID
+const (
+	mermaidStart = "__start__"
+	mermaidEnd   = "__end__"
+)
+
+// Mermaid 渲染为 Mermaid 流程图，子图以 subgraph 展示
+// overlay 不为 nil 时标出执行过的节点及其耗时，经过的边以流转序号作为标签并加粗
+func (g *Graph) Mermaid(overlay *Overlay) string {
+	m := &mermaidWriter{}
+	m.printf(0, "flowchart TD")
+	m.printf(1, "%s([START])", mermaidStart)
+	m.printf(1, "%s([END])", mermaidEnd)
+	m.graph(g, "", 1, overlay)
+
+	// 叠加信息的样式
+	for _, n := range g.Nodes {
+		if stat := overlay.visit(n.Key); stat != nil {
+			fill, stroke := stat.colors()
+			m.printf(1, "style %s fill:%s,stroke:%s,stroke-width:2px", mermaidID(n.Key), fill, stroke)
+		}
+	}
+	if len(m.taken) > 0 {
+		ids := make([]string, 0, len(m.taken))
+		for _, i := range m.taken {
+			ids = append(ids, fmt.Sprint(i))
+		}
+		m.printf(1, "linkStyle %s stroke:%s,stroke-width:3px", strings.Join(ids, ","), colorVisited)
+	}
+	return m.sb.String()
+}
+
+// mermaidWriter 按缩进输出 Mermaid 语句，并记录边的序号供 linkStyle 使用
+type mermaidWriter struct {
+	sb    strings.Builder
+	links int   // 已输出的边数
+	taken []int // 经过的边的序号
+}
+
+// printf 输出一行
+func (m *mermaidWriter) printf(depth int, format string, args ...any) {
+	m.sb.WriteString(strings.Repeat("    ", depth))
+	m.sb.WriteString(fmt.Sprintf(format, args...))
+	m.sb.WriteString("\n")
+}
+
+// graph 输出图的节点和边，prefix 为子图节点 ID 的前缀，顶层图为空
+// 子图内部的 START 和 END 不单独展示
+func (m *mermaidWriter) graph(g *Graph, prefix string, depth int, overlay *Overlay) {
+	for _, n := range g.Nodes {
+		id := mermaidID(prefix + n.Key)
+		label := overlay.visit(n.Key).label(n.Key)
+		if n.Sub == nil {
+			m.printf(depth, `%s["%s"]`, id, mermaidLabel(label))
+			continue
+		}
+		m.printf(depth, `subgraph %s ["%s"]`, id, mermaidLabel(label))
+		m.printf(depth+1, "direction LR")
+		m.graph(n.Sub, prefix+n.Key+"/", depth+1, nil)
+		m.printf(depth, "end")
+	}
+	for _, e := range g.Edges {
+		from, to := mermaidID(prefix+e.From), mermaidID(prefix+e.To)
+		if prefix == "" {
+			from, to = mermaidNodeID(e.From), mermaidNodeID(e.To)
+		} else if e.From == compose.START || e.To == compose.END {
+			continue
+		}
+		arrow := "-->"
+		if e.Branch {
+			arrow = "-.->"
+		}
+		if hops := overlay.hops(e.From, e.To); len(hops) > 0 {
+			m.taken = append(m.taken, m.links)
+			m.printf(depth, `%s %s|"%s"| %s`, from, arrow, hopLabel(hops), to)
+		} else {
+			m.printf(depth, "%s %s %s", from, arrow, to)
+		}
+		m.links++
+	}
+}
+
+// mermaidNodeID 顶层节点的 ID，START 和 END 使用单独的 ID
+func mermaidNodeID(key string) string {
+	switch key {
+	case compose.START:
+		return mermaidStart
+	case compose.END:
+		return mermaidEnd
+	}
+	return mermaidID(key)
+}
+
+// mermaidID 将节点路径转换为合法的 Mermaid ID
+func mermaidID(path string) string {
+	return idPattern.ReplaceAllString(strings.ReplaceAll(path, "/", "__"), "_")
+}
+
+// mermaidLabel 转义标签中的引号
+func mermaidLabel(label string) string {
+	return strings.ReplaceAll(label, `"`, "#quot;")
+}
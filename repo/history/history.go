@@ -0,0 +1,274 @@
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/hildam/deer-flow-go/entity/conf"
+	"github.com/hildam/deer-flow-go/entity/model"
+)
+
+const (
+	defaultPath = "data/history.db"
+	runsBucket  = "runs"
+)
+
+var (
+	// 数据库按次打开、用完即关，不长期持有文件锁，服务或控制台运行时其他命令仍可访问运行历史
+	path     string       // 运行历史数据库路径，为空表示未初始化
+	readOnly bool         // 是否以只读方式初始化
+	mu       sync.RWMutex // 同一进程内查询共享锁、修改独占锁，与数据库文件锁一致
+
+	// ErrNotFound 运行记录不存在
+	ErrNotFound = errors.New("run not found")
+	// ErrReadOnly 运行历史以只读方式初始化，不能修改
+	ErrReadOnly = errors.New("history is read-only")
+)
+
+// ListOption 查询条件
+type ListOption struct {
+	Keyword string    // 关键词，匹配问题、计划标题和报告内容，为空表示不过滤
+	Status  string    // 运行状态，为空表示不过滤
+	Since   time.Time // 开始时间下限
+	Until   time.Time // 开始时间上限
+	Offset  int       // 分页偏移，负数按 0 处理
+	Limit   int       // 分页大小，0 表示不限制，负数按 0 处理
+}
+
+// Init 以读写方式初始化运行历史数据库，创建数据库文件和 bucket
+func Init() error {
+	p := dbPath()
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return fmt.Errorf("Init history failed, mkdir err: %w", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	db, err := open(p, false)
+	if err != nil {
+		return fmt.Errorf("Init history failed, open db err: %w", err)
+	}
+	defer db.Close()
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(runsBucket))
+		return err
+	}); err != nil {
+		return fmt.Errorf("Init history failed, create bucket err: %w", err)
+	}
+	path, readOnly = p, false
+	return nil
+}
+
+// InitReadOnly 以只读方式初始化运行历史数据库，仅供查询，数据库不存在时视为没有运行记录
+func InitReadOnly() error {
+	mu.Lock()
+	defer mu.Unlock()
+	path, readOnly = dbPath(), true
+	return nil
+}
+
+// Close 关闭运行历史，之后的查询和修改视为未初始化
+func Close() error {
+	mu.Lock()
+	defer mu.Unlock()
+	path, readOnly = "", false
+	return nil
+}
+
+// dbPath 配置的数据库路径
+func dbPath() string {
+	if p := conf.GetCfg().History.Path; p != "" {
+		return p
+	}
+	return defaultPath
+}
+
+// open 打开数据库，只读方式持有共享锁，可与其他查询同时打开；读写方式持有独占锁
+func open(p string, ro bool) (*bolt.DB, error) {
+	return bolt.Open(p, 0o600, &bolt.Options{Timeout: 3 * time.Second, ReadOnly: ro})
+}
+
+// view 以只读方式打开数据库执行查询，未初始化或数据库、bucket 不存在时 b 为 nil
+func view(fn func(b *bolt.Bucket) error) error {
+	mu.RLock()
+	defer mu.RUnlock()
+	if path == "" {
+		return fn(nil)
+	}
+	// 只读方式打开不存在的文件时 bbolt 会创建空文件后初始化失败，需先检查
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return fn(nil)
+	}
+	db, err := open(path, true)
+	if err != nil {
+		return fmt.Errorf("open history db err: %w", err)
+	}
+	defer db.Close()
+	return db.View(func(tx *bolt.Tx) error {
+		return fn(tx.Bucket([]byte(runsBucket)))
+	})
+}
+
+// update 以读写方式打开数据库执行修改，未初始化时 b 为 nil
+func update(fn func(b *bolt.Bucket) error) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if path == "" {
+		return fn(nil)
+	}
+	if readOnly {
+		return ErrReadOnly
+	}
+	db, err := open(path, false)
+	if err != nil {
+		return fmt.Errorf("open history db err: %w", err)
+	}
+	defer db.Close()
+	return db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(runsBucket))
+		if err != nil {
+			return err
+		}
+		return fn(b)
+	})
+}
+
+// Save 保存运行记录，同一线程恢复运行时保留首次的开始时间和问题，从中断或取消处恢复时接续执行路径
+func Save(rec *model.RunRecord) error {
+	return update(func(b *bolt.Bucket) error {
+		if b == nil {
+			return nil
+		}
+		if old := b.Get([]byte(rec.ID)); old != nil {
+			prev := &model.RunRecord{}
+			if err := json.Unmarshal(old, prev); err == nil {
+				if !prev.StartedAt.IsZero() {
+					rec.StartedAt = prev.StartedAt
+				}
+				if rec.Query == "" {
+					rec.Query = prev.Query
+				}
+				// 从中断或取消处恢复的运行接续之前的执行路径
+				if prev.Status == model.RunInterrupted || prev.Status == model.RunCancelled {
+					rec.Path = append(prev.Path, rec.Path...)
+				}
+			}
+		}
+		data, err := json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(rec.ID), data)
+	})
+}
+
+// Get 获取运行记录
+func Get(id string) (*model.RunRecord, error) {
+	rec := &model.RunRecord{}
+	err := view(func(b *bolt.Bucket) error {
+		if b == nil {
+			return ErrNotFound
+		}
+		data := b.Get([]byte(id))
+		if data == nil {
+			return ErrNotFound
+		}
+		return json.Unmarshal(data, rec)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// List 按开始时间倒序列出运行记录
+func List(opt ListOption) ([]*model.RunRecord, error) {
+	records := []*model.RunRecord{}
+	err := view(func(b *bolt.Bucket) error {
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			rec := &model.RunRecord{}
+			if err := json.Unmarshal(v, rec); err != nil {
+				return err
+			}
+			if match(rec, opt) {
+				records = append(records, rec)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].StartedAt.After(records[j].StartedAt)
+	})
+
+	// 分页
+	offset := max(opt.Offset, 0)
+	if offset >= len(records) {
+		return []*model.RunRecord{}, nil
+	}
+	records = records[offset:]
+	if opt.Limit > 0 && opt.Limit < len(records) {
+		records = records[:opt.Limit]
+	}
+	return records, nil
+}
+
+// Search 按关键词搜索运行记录
+func Search(keyword string, opt ListOption) ([]*model.RunRecord, error) {
+	opt.Keyword = keyword
+	return List(opt)
+}
+
+// Delete 删除运行记录
+func Delete(id string) error {
+	return update(func(b *bolt.Bucket) error {
+		if b == nil || b.Get([]byte(id)) == nil {
+			return ErrNotFound
+		}
+		return b.Delete([]byte(id))
+	})
+}
+
+// match 判断记录是否满足查询条件
+func match(rec *model.RunRecord, opt ListOption) bool {
+	if opt.Status != "" && string(rec.Status) != opt.Status {
+		return false
+	}
+	if !opt.Since.IsZero() && rec.StartedAt.Before(opt.Since) {
+		return false
+	}
+	if !opt.Until.IsZero() && rec.StartedAt.After(opt.Until) {
+		return false
+	}
+	if opt.Keyword == "" {
+		return true
+	}
+
+	keyword := strings.ToLower(opt.Keyword)
+	fields := []string{rec.Query, rec.Report}
+	if rec.Plan != nil {
+		fields = append(fields, rec.Plan.Title)
+	}
+	for _, field := range fields {
+		if strings.Contains(strings.ToLower(field), keyword) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,158 @@
+package history
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/hildam/deer-flow-go/entity/conf"
+	"github.com/hildam/deer-flow-go/entity/model"
+)
+
+// setup 使用临时目录中的数据库
+func setup(t *testing.T) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "history.db")
+	conf.SetCfg(&conf.AppConfig{History: conf.HistoryConfig{Path: p}})
+	t.Cleanup(func() {
+		_ = Close()
+	})
+	return p
+}
+
+func TestListPaging(t *testing.T) {
+	setup(t)
+	if err := Init(); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, id := range []string{"a", "b", "c"} {
+		if err := Save(&model.RunRecord{ID: id, StartedAt: start.Add(time.Duration(i) * time.Hour)}); err != nil {
+			t.Fatalf("Save %s: %v", id, err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		opt  ListOption
+		want []string
+	}{
+		{"all", ListOption{}, []string{"c", "b", "a"}},
+		{"offset and limit", ListOption{Offset: 1, Limit: 1}, []string{"b"}},
+		{"offset past end", ListOption{Offset: 3}, nil},
+		{"negative offset", ListOption{Offset: -1}, []string{"c", "b", "a"}},
+		{"negative limit", ListOption{Limit: -1}, []string{"c", "b", "a"}},
+	}
+	for _, tt := range tests {
+		records, err := List(tt.opt)
+		if err != nil {
+			t.Errorf("%s: List: %v", tt.name, err)
+			continue
+		}
+		var got []string
+		for _, rec := range records {
+			got = append(got, rec.ID)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: List = %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: List = %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+// TestSaveResumedPath 从中断或取消处恢复的运行接续之前的执行路径，其他运行覆盖之前的路径
+func TestSaveResumedPath(t *testing.T) {
+	setup(t)
+	if err := Init(); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	tests := []struct {
+		prev model.RunStatus
+		want []string
+	}{
+		{model.RunInterrupted, []string{"planner", "reporter"}},
+		{model.RunCancelled, []string{"planner", "reporter"}},
+		{model.RunFailed, []string{"reporter"}},
+	}
+	for _, tt := range tests {
+		id := string(tt.prev)
+		if err := Save(&model.RunRecord{ID: id, Query: "q", Status: tt.prev, Path: []model.NodeVisit{{Node: "planner"}}}); err != nil {
+			t.Fatalf("Save %s: %v", id, err)
+		}
+		if err := Save(&model.RunRecord{ID: id, Status: model.RunCompleted, Path: []model.NodeVisit{{Node: "reporter"}}}); err != nil {
+			t.Fatalf("Save resumed %s: %v", id, err)
+		}
+		rec, err := Get(id)
+		if err != nil {
+			t.Fatalf("Get %s: %v", id, err)
+		}
+		var got []string
+		for _, v := range rec.Path {
+			got = append(got, v.Node)
+		}
+		if !slices.Equal(got, tt.want) || rec.Query != "q" {
+			t.Errorf("after %s: path = %v, query = %q, want %v and q", tt.prev, got, rec.Query, tt.want)
+		}
+	}
+}
+
+// TestNoLockHeld 初始化后不持有数据库文件锁，其他进程可以读写
+func TestNoLockHeld(t *testing.T) {
+	p := setup(t)
+	if err := Init(); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	if err := Save(&model.RunRecord{ID: "a"}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	db, err := bolt.Open(p, 0o600, &bolt.Options{Timeout: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("open db while initialized: %v", err)
+	}
+	_ = db.Close()
+}
+
+func TestReadOnly(t *testing.T) {
+	setup(t)
+	if err := InitReadOnly(); err != nil {
+		t.Fatalf("InitReadOnly: %v", err)
+	}
+
+	// 数据库不存在时视为没有运行记录
+	if records, err := List(ListOption{}); err != nil || len(records) != 0 {
+		t.Errorf("List = %v, %v, want empty", records, err)
+	}
+	if _, err := Get("a"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get err = %v, want ErrNotFound", err)
+	}
+
+	if err := Init(); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	if err := Save(&model.RunRecord{ID: "a"}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := InitReadOnly(); err != nil {
+		t.Fatalf("InitReadOnly: %v", err)
+	}
+	if rec, err := Get("a"); err != nil || rec.ID != "a" {
+		t.Errorf("Get = %v, %v, want a", rec, err)
+	}
+	if err := Save(&model.RunRecord{ID: "b"}); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Save err = %v, want ErrReadOnly", err)
+	}
+	if err := Delete("a"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Delete err = %v, want ErrReadOnly", err)
+	}
+}
@@ -7,48 +7,97 @@ import (
 
 	"github.com/HildaM/logs/slog"
 	"github.com/cloudwego/eino-ext/components/model/openai"
+	ecmodel "github.com/cloudwego/eino/components/model"
+	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/getkin/kin-openapi/openapi3gen"
 	"github.com/hildam/deer-flow-go/entity/conf"
 	"github.com/hildam/deer-flow-go/entity/model"
+	"github.com/hildam/deer-flow-go/repo/replay"
+	"github.com/hildam/deer-flow-go/repo/usage"
 )
 
-// NewChatModel 创建Chat模型
-func NewChatModel(ctx context.Context) *openai.ChatModel {
+// NewChatModel 创建Chat模型，回放模式下返回回放模型
+func NewChatModel(ctx context.Context) ecmodel.ToolCallingChatModel {
+	return NewAgentModel(ctx, conf.Model{})
+}
+
+// NewAgentModel 按智能体配置的模型创建Chat模型，未配置的字段使用默认模型的配置，回放模式下返回回放模型
+// 返回的模型在每次调用前检查运行预算
+func NewAgentModel(ctx context.Context, m conf.Model) ecmodel.ToolCallingChatModel {
+	if replay.Replaying() {
+		return usage.WrapChatModel(replay.NewChatModel())
+	}
+	def := conf.GetCfg().Model.DefaultModel
+	if m.ModelID == "" {
+		m.ModelID = def.ModelID
+	}
+	if m.BaseURL == "" {
+		m.BaseURL = def.BaseURL
+	}
+	if m.APIKey == "" {
+		m.APIKey = def.APIKey
+	}
 	llm, err := openai.NewChatModel(ctx, &openai.ChatModelConfig{
-		Model:   conf.GetCfg().Model.DefaultModel.ModelID,
-		BaseURL: conf.GetCfg().Model.DefaultModel.BaseURL,
-		APIKey:  conf.GetCfg().Model.DefaultModel.APIKey,
+		Model:   m.ModelID,
+		BaseURL: m.BaseURL,
+		APIKey:  m.APIKey,
 	})
 	if err != nil {
-		slog.Fatal("NewChatModel failed, err: %v", err)
+		slog.Fatal("NewAgentModel failed, err: %v, model = %s", err, m.ModelID)
 		return nil
 	}
-	return llm
+	return usage.WrapChatModel(replay.WrapChatModel(llm))
+}
+
+// NewPlanModel 创建计划模型，步骤类型限定为 stepTypes，回放模式下返回回放模型
+func NewPlanModel(ctx context.Context, stepTypes []model.StepType) ecmodel.ToolCallingChatModel {
+	return newSchemaModel(ctx, "plan", &model.Plan{}, func(s *openapi3.Schema) {
+		step := s.Properties["steps"].Value.Items.Value
+		stepType := step.Properties["step_type"].Value
+		for _, t := range stepTypes {
+			stepType.Enum = append(stepType.Enum, string(t))
+		}
+		// 执行结果、状态等由运行时记录，不需要模型生成
+		for _, field := range model.StepRuntimeFields {
+			delete(step.Properties, field)
+		}
+	})
 }
 
-// NewPlanModel 创建计划模型
-func NewPlanModel(ctx context.Context) *openai.ChatModel {
+// NewReportModel 创建结构化报告模型，回放模式下返回回放模型
+func NewReportModel(ctx context.Context) ecmodel.ToolCallingChatModel {
+	return newSchemaModel(ctx, "report", &model.Report{})
+}
+
+// newSchemaModel 创建按 value 的 JSON Schema 输出的模型，modifiers 用于补充无法从类型推导的约束
+func newSchemaModel(ctx context.Context, name string, value any, modifiers ...func(*openapi3.Schema)) ecmodel.ToolCallingChatModel {
+	if replay.Replaying() {
+		return usage.WrapChatModel(replay.NewChatModel())
+	}
 	// 定义返回结构
-	planSchema, _ := openapi3gen.NewSchemaRefForValue(&model.Plan{}, nil)
+	schemaRef, _ := openapi3gen.NewSchemaRefForValue(value, nil)
+	for _, modify := range modifiers {
+		modify(schemaRef.Value)
+	}
 
 	// 创建 LLM
 	llm, err := openai.NewChatModel(ctx, &openai.ChatModelConfig{
 		Model:   conf.GetCfg().Model.DefaultModel.ModelID,
 		BaseURL: conf.GetCfg().Model.DefaultModel.BaseURL,
 		APIKey:  conf.GetCfg().Model.DefaultModel.APIKey,
-		// 计划模型响应格式
+		// 模型响应格式
 		ResponseFormat: &openai3.ChatCompletionResponseFormat{
 			Type: openai3.ChatCompletionResponseFormatTypeJSONSchema,
 			JSONSchema: &openai3.ChatCompletionResponseFormatJSONSchema{
-				Name:   "plan",
+				Name:   name,
 				Strict: false,
-				Schema: planSchema.Value,
+				Schema: schemaRef.Value,
 			},
 		},
 	})
 	if err != nil {
-		slog.Fatal("NewPlanModel failed, err: %v", err)
+		slog.Fatal("newSchemaModel failed, err: %v, name = %s", err, name)
 		return nil
 	}
-	return llm
+	return usage.WrapChatModel(replay.WrapChatModel(llm))
 }
@@ -0,0 +1,182 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"path"
+	"time"
+
+	"github.com/HildaM/logs/slog"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/hildam/deer-flow-go/repo/artifact"
+	"github.com/hildam/deer-flow-go/repo/metrics"
+)
+
+// 产物工具相关常量
+const (
+	listArtifactsToolName = "list_artifacts"
+	readArtifactToolName  = "read_artifact"
+
+	maxArtifactRead = 32 * 1024 // read_artifact 单次返回的最大字节数
+)
+
+// runID 当前运行的ID，即会话ID，未设置时使用 default
+func runID(ctx context.Context) string {
+	if id := SessionID(ctx); id != "" {
+		return id
+	}
+	return "default"
+}
+
+// saveContentArtifacts 将工具返回的图片和二进制资源保存为产物，并替换为指向产物的文本
+// 避免 base64 数据占用模型上下文，同时让报告可以引用这些文件
+func saveContentArtifacts(ctx context.Context, toolName string, contents []mcpgo.Content) []mcpgo.Content {
+	for i, content := range contents {
+		var data, mimeType, name string
+		switch c := content.(type) {
+		case mcpgo.ImageContent:
+			data, mimeType = c.Data, c.MIMEType
+		case mcpgo.AudioContent:
+			data, mimeType = c.Data, c.MIMEType
+		case mcpgo.EmbeddedResource:
+			blob, ok := c.Resource.(mcpgo.BlobResourceContents)
+			if !ok {
+				continue
+			}
+			data, mimeType, name = blob.Blob, blob.MIMEType, path.Base(blob.URI)
+		default:
+			continue
+		}
+		raw, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			slog.Error("saveContentArtifacts failed, tool = %s, decode err = %v", toolName, err)
+			continue
+		}
+		if name == "" || name == "." || name == "/" || path.Ext(name) == "" {
+			name = contentName(toolName, mimeType, raw)
+		}
+		a := artifact.New(ctx, runID(ctx), name, toolName)
+		a.MIMEType = mimeType
+		if err := artifact.Put(ctx, a, bytes.NewReader(raw)); err != nil {
+			slog.Error("saveContentArtifacts failed, tool = %s, put err = %v", toolName, err)
+			continue
+		}
+		contents[i] = mcpgo.NewTextContent(fmt.Sprintf("[artifact saved: name=%s, mime_type=%s, size=%d, url=%s]",
+			a.Name, a.MIMEType, a.Size, artifact.URL(a.RunID, a.Name)))
+	}
+	return contents
+}
+
+// contentName 为没有文件名的内容生成产物名称，使用内容摘要保证同一运行内不重复
+func contentName(toolName, mimeType string, raw []byte) string {
+	sum := sha1.Sum(raw)
+	ext := ".bin"
+	if exts, _ := mime.ExtensionsByType(mimeType); len(exts) > 0 {
+		ext = exts[0]
+	}
+	return fmt.Sprintf("%s-%s%s", toolName, hex.EncodeToString(sum[:4]), ext)
+}
+
+// ListArtifactsTool 内置工具，列出当前运行已保存的产物
+type ListArtifactsTool struct{}
+
+// Info 获取工具信息
+func (t *ListArtifactsTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{
+		Name: listArtifactsToolName,
+		Desc: "List the files (artifacts) produced so far in this research run, such as CSV files and charts " +
+			"written by execute_code or documents returned by other tools. " +
+			"Returns each artifact's name, MIME type, size and the step that produced it.",
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{}),
+	}, nil
+}
+
+// InvokableRun 列出产物
+func (t *ListArtifactsTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (result string, err error) {
+	start := time.Now()
+	defer func() { metrics.ObserveTool(builtinServer, listArtifactsToolName, time.Since(start), err) }()
+
+	items, err := artifact.List(ctx, runID(ctx))
+	if err != nil {
+		return "", err
+	}
+	if len(items) == 0 {
+		return "No artifacts have been produced in this run yet.", nil
+	}
+	data, err := json.Marshal(items)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result: %w", err)
+	}
+	return string(data), nil
+}
+
+// ReadArtifactTool 内置工具，读取当前运行的产物内容
+type ReadArtifactTool struct{}
+
+// readArtifactArgs 工具调用参数
+type readArtifactArgs struct {
+	Name string `json:"name"`
+}
+
+// Info 获取工具信息
+func (t *ReadArtifactTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{
+		Name: readArtifactToolName,
+		Desc: fmt.Sprintf("Read an artifact produced in this research run by name. "+
+			"Text files return up to %dKB of content; binary files such as images return metadata only.", maxArtifactRead/1024),
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"name": {
+				Type:     schema.String,
+				Desc:     "The artifact name returned by list_artifacts",
+				Required: true,
+			},
+		}),
+	}, nil
+}
+
+// InvokableRun 读取产物，产物不存在时返回提示而非错误，由模型改用 list_artifacts 查找
+func (t *ReadArtifactTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (result string, err error) {
+	start := time.Now()
+	defer func() { metrics.ObserveTool(builtinServer, readArtifactToolName, time.Since(start), err) }()
+
+	args := &readArtifactArgs{}
+	if err := json.Unmarshal([]byte(argumentsInJSON), args); err != nil {
+		return "", fmt.Errorf("failed to unmarshal params: %w", err)
+	}
+	if args.Name == "" {
+		return "", errors.New("missing name parameter")
+	}
+
+	a, rc, err := artifact.Open(ctx, runID(ctx), args.Name)
+	if errors.Is(err, artifact.ErrNotFound) {
+		return fmt.Sprintf("artifact %q not found, use %s to see available artifacts", args.Name, listArtifactsToolName), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	if !artifact.IsText(a.MIMEType) {
+		return fmt.Sprintf("artifact %s is binary (mime_type=%s, size=%d, url=%s), its content cannot be shown",
+			a.Name, a.MIMEType, a.Size, artifact.URL(a.RunID, a.Name)), nil
+	}
+	data, err := io.ReadAll(io.LimitReader(rc, maxArtifactRead+1))
+	if err != nil {
+		return "", fmt.Errorf("read artifact failed: %w", err)
+	}
+	if len(data) > maxArtifactRead {
+		return string(data[:maxArtifactRead]) + "\n...[content truncated]", nil
+	}
+	return string(data), nil
+}
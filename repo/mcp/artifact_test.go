@@ -0,0 +1,46 @@
+package mcp
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/hildam/deer-flow-go/repo/artifact"
+)
+
+func TestSaveContentArtifacts(t *testing.T) {
+	artifact.SetStore(artifact.NewLocalStore(t.TempDir()))
+	ctx := WithSession(context.Background(), "run-1")
+	png := base64.StdEncoding.EncodeToString([]byte("\x89PNG\r\n\x1a\n"))
+
+	contents := saveContentArtifacts(ctx, "screenshot", []mcpgo.Content{
+		mcpgo.NewTextContent("done"),
+		mcpgo.NewImageContent(png, "image/png"),
+		mcpgo.NewEmbeddedResource(mcpgo.BlobResourceContents{URI: "file:///tmp/report.pdf", MIMEType: "application/pdf", Blob: png}),
+	})
+	for i, c := range contents {
+		if _, ok := c.(mcpgo.TextContent); !ok {
+			t.Fatalf("contents[%d] = %T, want TextContent", i, c)
+		}
+	}
+	if text := contents[2].(mcpgo.TextContent).Text; !strings.Contains(text, "/api/runs/run-1/artifacts/report.pdf") {
+		t.Errorf("resource reference = %q", text)
+	}
+
+	items, _ := artifact.List(ctx, "run-1")
+	if len(items) != 2 || items[0].MIMEType != "image/png" || items[0].Tool != "screenshot" || items[1].Name != "report.pdf" {
+		t.Fatalf("artifacts = %+v", items)
+	}
+
+	out, err := (&ReadArtifactTool{}).InvokableRun(ctx, `{"name": "report.pdf"}`)
+	if err != nil || !strings.Contains(out, "is binary") {
+		t.Errorf("read binary = %q, %v", out, err)
+	}
+	out, err = (&ReadArtifactTool{}).InvokableRun(ctx, `{"name": "missing.csv"}`)
+	if err != nil || !strings.Contains(out, "not found") {
+		t.Errorf("read missing = %q, %v", out, err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -13,17 +14,24 @@ import (
 	"github.com/cloudwego/eino/schema"
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/hildam/deer-flow-go/entity/conf"
+	"github.com/hildam/deer-flow-go/repo/replay"
 	"github.com/mark3labs/mcp-go/client"
 	"github.com/mark3labs/mcp-go/client/transport"
 	mcpgo "github.com/mark3labs/mcp-go/mcp"
 )
 
-// InitMcpServer 初始化MCP服务端
+// InitMcpServer 初始化MCP服务端，回放模式下使用录制的工具，无需启动MCP服务
 func InitMcpServer() (err error) {
-	mcpServer, err = createMcpClients()
+	if replay.Replaying() {
+		return nil
+	}
+	clients, err := createMcpClients()
 	if err != nil {
 		return err
 	}
+	mcpServerMu.Lock()
+	defer mcpServerMu.Unlock()
+	mcpServer = clients
 	return nil
 }
 
@@ -141,6 +149,57 @@ func createMcpClients() (map[string]client.MCPClient, error) {
 	return clients, nil
 }
 
+// getClient 获取指定名称的MCP客户端
+func getClient(name string) (client.MCPClient, bool) {
+	mcpServerMu.RLock()
+	defer mcpServerMu.RUnlock()
+	cli, ok := mcpServer[name]
+	return cli, ok
+}
+
+// ServerStatus MCP服务状态
+type ServerStatus struct {
+	Name      string   `json:"name"`            // 服务名称
+	Connected bool     `json:"connected"`       // 是否连接正常
+	Tools     []string `json:"tools,omitempty"` // 提供的工具
+	Error     string   `json:"error,omitempty"` // 连接或调用失败的原因
+}
+
+// Status 检查配置中每个MCP服务的连接状态及提供的工具
+func Status(ctx context.Context) []ServerStatus {
+	names := make([]string, 0, len(conf.GetCfg().MCP.Servers))
+	for name := range conf.GetCfg().MCP.Servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	statuses := make([]ServerStatus, 0, len(names))
+	for _, name := range names {
+		status := ServerStatus{Name: name}
+		cli, ok := getClient(name)
+		if !ok {
+			status.Error = "not connected"
+			statuses = append(statuses, status)
+			continue
+		}
+		if err := cli.Ping(ctx); err != nil {
+			status.Error = err.Error()
+			statuses = append(statuses, status)
+			continue
+		}
+		status.Connected = true
+		if resp, err := cli.ListTools(ctx, mcpgo.ListToolsRequest{}); err != nil {
+			status.Error = err.Error()
+		} else {
+			for _, t := range resp.Tools {
+				status.Tools = append(status.Tools, t.Name)
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
 var (
 	// 工具缓存相关变量
 	cachedTools []tool.BaseTool // 缓存的MCP工具
@@ -150,6 +209,11 @@ var (
 
 // GetMCPTools 获取所有MCP工具
 func GetMCPTools(ctx context.Context) ([]tool.BaseTool, error) {
+	// 回放模式下使用录制文件中的工具
+	if replay.Replaying() {
+		return replay.Tools(), nil
+	}
+
 	// 使用 sync.Once 确保工具只被初始化一次
 	toolsOnce.Do(func() {
 		cachedTools, toolsErr = loadMCPTools(ctx)
@@ -162,7 +226,14 @@ func loadMCPTools(ctx context.Context) ([]tool.BaseTool, error) {
 	var allTools []tool.BaseTool
 
 	// 遍历所有MCP服务器
-	for serverName, mcpClient := range mcpServer {
+	mcpServerMu.RLock()
+	clients := make(map[string]client.MCPClient, len(mcpServer))
+	for name, cli := range mcpServer {
+		clients[name] = cli
+	}
+	mcpServerMu.RUnlock()
+
+	for serverName, mcpClient := range clients {
 		slog.Debug("loadMCPTools debug, Loading tools from MCP server = %s", serverName)
 
 		// 获取工具列表
@@ -177,17 +248,33 @@ func loadMCPTools(ctx context.Context) ([]tool.BaseTool, error) {
 
 		// 为每个工具创建MCPTool包装器
 		for _, mcpTool := range toolsResp.Tools {
+			// 会话释放工具由运行结束时调用，不提供给智能体
+			if mcpTool.Name == closeSessionTool {
+				addSessionServer(serverName)
+				continue
+			}
 			tool := &MCPTool{
 				cli:         mcpClient,
+				serverName:  serverName,
 				toolName:    mcpTool.Name,
 				toolDesc:    mcpTool.Description,
 				inputSchema: mcpTool.InputSchema,
 			}
-			allTools = append(allTools, tool)
+			allTools = append(allTools, replay.WrapTool(ctx, serverName, tool))
 			slog.Debug("loadMCPTools debug, Added tool: %s", mcpTool.Name)
 		}
 	}
 
+	// 内置代码执行工具，无需部署额外的MCP服务
+	if sandbox := newSandboxTool(); sandbox != nil {
+		allTools = append(allTools, replay.WrapTool(ctx, builtinServer, sandbox))
+	}
+	// 内置产物工具，读取本次运行中步骤和工具生成的文件
+	allTools = append(allTools,
+		replay.WrapTool(ctx, builtinServer, &ListArtifactsTool{}),
+		replay.WrapTool(ctx, builtinServer, &ReadArtifactTool{}),
+	)
+
 	slog.Debug("loadMCPTools debug, Total tools loaded: %d", len(allTools))
 	return allTools, nil
 }
@@ -0,0 +1,324 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/HildaM/logs/slog"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/hildam/deer-flow-go/entity/conf"
+	"github.com/hildam/deer-flow-go/entity/model"
+	"github.com/hildam/deer-flow-go/repo/artifact"
+	"github.com/hildam/deer-flow-go/repo/metrics"
+	"github.com/hildam/deer-flow-go/repo/tracing"
+)
+
+// 沙箱相关默认值
+const (
+	builtinServer   = "builtin"      // 内置工具在指标和录制文件中使用的服务名称
+	sandboxToolName = "execute_code" // 工具名称
+
+	defaultSandboxPython   = "python3"
+	defaultSandboxTimeout  = 60   // 秒
+	defaultSandboxMemoryMB = 1024 // MB
+
+	maxSandboxOutput   = 64 * 1024        // 标准输出和标准错误各自保留的最大字节数
+	maxArtifactFiles   = 20               // 单次执行最多保存的生成文件数
+	maxArtifactSize    = 20 * 1024 * 1024 // 单个生成文件的大小上限
+	sandboxLangPython  = "python"
+	sandboxLangShell   = "shell"
+	sandboxScriptPy    = "main.py"
+	sandboxScriptShell = "main.sh"
+)
+
+// SandboxTool 内置代码执行工具，在独立子进程中运行 Python 或 shell 脚本
+// 每次调用使用新的临时工作目录，限制 CPU 时间、内存和运行时长，默认禁止访问网络，
+// 无需部署 Python MCP 服务即可为 Coder 提供代码执行能力
+type SandboxTool struct {
+	cfg conf.SandboxConfig
+}
+
+// SandboxResult 代码执行结果
+type SandboxResult struct {
+	ExitCode  int               `json:"exit_code"`           // 退出码，被终止时为 -1
+	TimedOut  bool              `json:"timed_out,omitempty"` // 是否因超时被终止
+	Stdout    string            `json:"stdout,omitempty"`    // 标准输出
+	Stderr    string            `json:"stderr,omitempty"`    // 标准错误
+	Artifacts []*model.Artifact `json:"artifacts,omitempty"` // 执行过程中生成并保存到产物存储的文件
+}
+
+// sandboxArgs 工具调用参数
+type sandboxArgs struct {
+	Language string `json:"language"`
+	Code     string `json:"code"`
+}
+
+// newSandboxTool 根据配置创建内置代码执行工具，禁用或当前平台不支持时返回 nil
+func newSandboxTool() *SandboxTool {
+	cfg := conf.GetCfg().Sandbox
+	if cfg.Disable {
+		return nil
+	}
+	if !sandboxSupported {
+		slog.Info("newSandboxTool skipped, sandbox is only supported on linux")
+		return nil
+	}
+	if cfg.Python == "" {
+		cfg.Python = defaultSandboxPython
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultSandboxTimeout
+	}
+	if cfg.CPUSeconds <= 0 {
+		cfg.CPUSeconds = cfg.Timeout
+	}
+	if cfg.MemoryMB <= 0 {
+		cfg.MemoryMB = defaultSandboxMemoryMB
+	}
+	return &SandboxTool{cfg: cfg}
+}
+
+// Info 获取工具信息
+func (t *SandboxTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	languages := []string{sandboxLangPython}
+	if t.cfg.Shell {
+		languages = append(languages, sandboxLangShell)
+	}
+	network := "Network access is disabled."
+	if t.cfg.Network {
+		network = "Network access is allowed."
+	}
+	return &schema.ToolInfo{
+		Name: sandboxToolName,
+		Desc: fmt.Sprintf("Execute a Python script in an isolated sandbox and return its exit code, stdout and stderr. "+
+			"Each call runs in a fresh process and working directory, so variables do not persist between calls. "+
+			"Use print() to output results. Files written to the working directory are saved as run artifacts, "+
+			"which can be read with read_artifact in later steps and embedded in the final report. "+
+			"%s Limits: %ds wall time, %dMB memory.", network, t.cfg.Timeout, t.cfg.MemoryMB),
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"code": {
+				Type:     schema.String,
+				Desc:     "The complete script to execute",
+				Required: true,
+			},
+			"language": {
+				Type: schema.String,
+				Desc: "Script language, defaults to python",
+				Enum: languages,
+			},
+		}),
+	}, nil
+}
+
+// InvokableRun 在沙箱中执行脚本
+// 脚本本身的错误、超时通过结果中的退出码和标准错误返回，只有沙箱无法启动时才返回错误
+func (t *SandboxTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (result string, err error) {
+	// 链路追踪及指标采集
+	start := time.Now()
+	ctx, span := tracing.Tracer().Start(ctx, "sandbox.execute", trace.WithAttributes(
+		attribute.Int("sandbox.code_size", len(argumentsInJSON)),
+	))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+		metrics.ObserveTool(builtinServer, sandboxToolName, time.Since(start), err)
+	}()
+
+	args := &sandboxArgs{}
+	if err := json.Unmarshal([]byte(argumentsInJSON), args); err != nil {
+		return "", fmt.Errorf("failed to unmarshal params: %w", err)
+	}
+	if strings.TrimSpace(args.Code) == "" {
+		return "", errors.New("missing code parameter")
+	}
+
+	res, err := t.execute(ctx, args)
+	if err != nil {
+		return "", err
+	}
+	span.SetAttributes(attribute.Int("sandbox.exit_code", res.ExitCode), attribute.Bool("sandbox.timed_out", res.TimedOut))
+	data, err := json.Marshal(res)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result: %w", err)
+	}
+	return string(data), nil
+}
+
+// execute 在临时工作目录中运行脚本并收集输出和生成的文件
+func (t *SandboxTool) execute(ctx context.Context, args *sandboxArgs) (*SandboxResult, error) {
+	dir, err := os.MkdirTemp("", "deer-sandbox-")
+	if err != nil {
+		return nil, fmt.Errorf("sandbox create workdir failed: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// 不支持的语言作为执行失败返回，由模型改用 Python 重试
+	var script string
+	var argv []string
+	switch args.Language {
+	case "", sandboxLangPython:
+		script = sandboxScriptPy
+		argv = []string{t.cfg.Python, script}
+	case sandboxLangShell, "bash", "sh":
+		if !t.cfg.Shell {
+			return &SandboxResult{ExitCode: -1, Stderr: "shell scripts are disabled, use python"}, nil
+		}
+		script = sandboxScriptShell
+		argv = []string{"/bin/sh", script}
+	default:
+		return &SandboxResult{ExitCode: -1, Stderr: fmt.Sprintf("unsupported language: %s", args.Language)}, nil
+	}
+	if err := os.WriteFile(filepath.Join(dir, script), []byte(args.Code), 0o600); err != nil {
+		return nil, fmt.Errorf("sandbox write script failed: %w", err)
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, time.Duration(t.cfg.Timeout)*time.Second)
+	defer cancel()
+	stdout := &limitedBuffer{max: maxSandboxOutput}
+	stderr := &limitedBuffer{max: maxSandboxOutput}
+	cmd := sandboxCommand(runCtx, t.cfg, argv)
+	cmd.Dir = dir
+	cmd.Env = sandboxEnv(dir)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	res := &SandboxResult{}
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr):
+		res.ExitCode = exitErr.ExitCode()
+	case !t.cfg.Network:
+		// 容器等环境可能禁止创建 user 命名空间
+		return nil, fmt.Errorf("sandbox start failed, network isolation requires user namespaces, "+
+			"set sandbox.network to true to run without it: %w", err)
+	default:
+		return nil, fmt.Errorf("sandbox start failed: %w", err)
+	}
+	// 运行被取消时脚本随之终止，直接返回错误，不再保存产物
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("sandbox execution canceled: %w", context.Cause(ctx))
+	}
+	if runCtx.Err() != nil {
+		res.TimedOut = errors.Is(runCtx.Err(), context.DeadlineExceeded)
+		fmt.Fprintf(stderr, "\nexecution terminated: %v", runCtx.Err())
+	}
+	res.Stdout = stdout.String()
+	res.Stderr = stderr.String()
+
+	res.Artifacts, err = t.saveArtifacts(ctx, dir, script)
+	if err != nil {
+		slog.Error("SandboxTool failed, save artifacts err = %v", err)
+	}
+	return res, nil
+}
+
+// saveArtifacts 将工作目录中生成的文件保存到当前运行的产物存储
+func (t *SandboxTool) saveArtifacts(ctx context.Context, dir, script string) ([]*model.Artifact, error) {
+	runID := SessionID(ctx)
+	if runID == "" {
+		runID = "default"
+	}
+
+	var artifacts []*model.Artifact
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name, _ := filepath.Rel(dir, path)
+		if d.IsDir() {
+			if name != "." && (strings.HasPrefix(d.Name(), ".") || d.Name() == "__pycache__") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if name == script || strings.HasPrefix(d.Name(), ".") || !d.Type().IsRegular() {
+			return nil
+		}
+		if len(artifacts) >= maxArtifactFiles {
+			return filepath.SkipAll
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() > maxArtifactSize {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		a := artifact.New(ctx, runID, filepath.ToSlash(name), sandboxToolName)
+		if err := artifact.Put(ctx, a, f); err != nil {
+			return err
+		}
+		artifacts = append(artifacts, a)
+		return nil
+	})
+	return artifacts, err
+}
+
+// sandboxEnv 沙箱进程的环境变量，不继承宿主进程的密钥等配置
+func sandboxEnv(dir string) []string {
+	path := os.Getenv("PATH")
+	if path == "" {
+		path = "/usr/local/bin:/usr/bin:/bin"
+	}
+	return []string{
+		"PATH=" + path,
+		"HOME=" + dir,
+		"TMPDIR=" + dir,
+		"LANG=C.UTF-8",
+		"PYTHONDONTWRITEBYTECODE=1",
+		"PYTHONUNBUFFERED=1",
+		"MPLBACKEND=Agg",
+		// 限制数值计算库的线程数，避免线程栈占满内存限制
+		"OMP_NUM_THREADS=1",
+		"OPENBLAS_NUM_THREADS=1",
+		"MKL_NUM_THREADS=1",
+	}
+}
+
+// limitedBuffer 只保留前 max 个字节的输出缓冲区，超出部分丢弃并标记截断
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	max       int
+	truncated bool
+}
+
+// Write 写入输出，超出上限时丢弃但不返回错误，避免子进程因管道错误退出
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remain := b.max - b.buf.Len(); remain < len(p) {
+		b.truncated = true
+		if remain > 0 {
+			b.buf.Write(p[:remain])
+		}
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+// String 返回输出内容，被截断时追加提示
+func (b *limitedBuffer) String() string {
+	if b.truncated {
+		return b.buf.String() + "\n...[output truncated]"
+	}
+	return b.buf.String()
+}
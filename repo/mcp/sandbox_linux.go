@@ -0,0 +1,36 @@
+//go:build linux
+
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"github.com/hildam/deer-flow-go/entity/conf"
+)
+
+// sandboxSupported 当前平台是否支持沙箱
+const sandboxSupported = true
+
+// sandboxCommand 构造沙箱进程
+// 通过 sh 的 ulimit 设置 CPU 时间和虚拟内存上限，进程组在超时后整体终止；
+// 禁止网络时在新的 user 和 network 命名空间中运行，命名空间内只有未启用的回环网卡
+func sandboxCommand(ctx context.Context, cfg conf.SandboxConfig, argv []string) *exec.Cmd {
+	limits := fmt.Sprintf(`ulimit -t %d && ulimit -v %d && exec "$@"`, cfg.CPUSeconds, cfg.MemoryMB*1024)
+	cmd := exec.CommandContext(ctx, "/bin/sh", append([]string{"-c", limits, "sandbox"}, argv...)...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if !cfg.Network {
+		cmd.SysProcAttr.Cloneflags = syscall.CLONE_NEWUSER | syscall.CLONE_NEWNET
+		cmd.SysProcAttr.UidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1}}
+		cmd.SysProcAttr.GidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1}}
+	}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = time.Second
+	return cmd
+}
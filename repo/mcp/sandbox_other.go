@@ -0,0 +1,18 @@
+//go:build !linux
+
+package mcp
+
+import (
+	"context"
+	"os/exec"
+
+	"github.com/hildam/deer-flow-go/entity/conf"
+)
+
+// sandboxSupported 当前平台是否支持沙箱，资源限制和网络隔离依赖 Linux 命名空间
+const sandboxSupported = false
+
+// sandboxCommand 当前平台不支持沙箱，不会被调用
+func sandboxCommand(ctx context.Context, cfg conf.SandboxConfig, argv []string) *exec.Cmd {
+	return exec.CommandContext(ctx, argv[0], argv[1:]...)
+}
@@ -0,0 +1,98 @@
+//go:build linux
+
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/hildam/deer-flow-go/entity/conf"
+	"github.com/hildam/deer-flow-go/repo/artifact"
+)
+
+// newTestSandbox 创建测试用沙箱，缺少 Python 时跳过
+func newTestSandbox(t *testing.T, cfg conf.SandboxConfig) *SandboxTool {
+	t.Helper()
+	if _, err := exec.LookPath(defaultSandboxPython); err != nil {
+		t.Skip("python3 not found")
+	}
+	artifact.SetStore(artifact.NewLocalStore(t.TempDir()))
+	conf.SetCfg(&conf.AppConfig{Sandbox: cfg})
+	sb := newSandboxTool()
+	if _, err := sb.InvokableRun(context.Background(), `{"code": "pass"}`); err != nil {
+		t.Skipf("sandbox unavailable: %v", err)
+	}
+	return sb
+}
+
+// runSandbox 执行一段 Python 代码并解析结果
+func runSandbox(t *testing.T, sb *SandboxTool, ctx context.Context, code string) *SandboxResult {
+	t.Helper()
+	args, _ := json.Marshal(sandboxArgs{Code: code})
+	out, err := sb.InvokableRun(ctx, string(args))
+	if err != nil {
+		t.Fatalf("InvokableRun: %v", err)
+	}
+	res := &SandboxResult{}
+	if err := json.Unmarshal([]byte(out), res); err != nil {
+		t.Fatalf("unmarshal result %q: %v", out, err)
+	}
+	return res
+}
+
+func TestSandboxOutputAndArtifacts(t *testing.T) {
+	sb := newTestSandbox(t, conf.SandboxConfig{})
+	ctx := WithSession(context.Background(), "run-1")
+	res := runSandbox(t, sb, ctx, "import sys\nprint('hello')\nprint('oops', file=sys.stderr)\nopen('out.csv', 'w').write('a,b\\n1,2\\n')\nsys.exit(3)")
+
+	if res.ExitCode != 3 || res.Stdout != "hello\n" || res.Stderr != "oops\n" {
+		t.Errorf("result = %+v", res)
+	}
+	if len(res.Artifacts) != 1 || res.Artifacts[0].Name != "out.csv" {
+		t.Fatalf("artifacts = %+v, want out.csv", res.Artifacts)
+	}
+	if a := res.Artifacts[0]; a.RunID != "run-1" || a.Size != 8 || a.Tool != sandboxToolName || a.MIMEType != "text/csv; charset=utf-8" {
+		t.Errorf("artifact = %+v", a)
+	}
+	_, rc, err := artifact.Open(ctx, "run-1", "out.csv")
+	if err != nil {
+		t.Fatalf("Open artifact: %v", err)
+	}
+	defer rc.Close()
+	if data, _ := io.ReadAll(rc); string(data) != "a,b\n1,2\n" {
+		t.Errorf("artifact content = %q", data)
+	}
+}
+
+func TestSandboxLimits(t *testing.T) {
+	sb := newTestSandbox(t, conf.SandboxConfig{Timeout: 2, MemoryMB: 256})
+	ctx := context.Background()
+
+	res := runSandbox(t, sb, ctx, "import time\ntime.sleep(10)")
+	if !res.TimedOut {
+		t.Errorf("sleep: result = %+v, want timed out", res)
+	}
+
+	res = runSandbox(t, sb, ctx, "x = bytearray(512 * 1024 * 1024)")
+	if res.ExitCode == 0 || !strings.Contains(res.Stderr, "MemoryError") {
+		t.Errorf("alloc: result = %+v, want MemoryError", res)
+	}
+
+	res = runSandbox(t, sb, ctx, "print('x' * (1024 * 1024))")
+	if len(res.Stdout) > maxSandboxOutput+100 || !strings.HasSuffix(res.Stdout, "[output truncated]") {
+		t.Errorf("output: length = %d, want truncated", len(res.Stdout))
+	}
+}
+
+func TestSandboxNetworkDisabled(t *testing.T) {
+	sb := newTestSandbox(t, conf.SandboxConfig{})
+	res := runSandbox(t, sb, context.Background(),
+		"import socket\ntry:\n    socket.create_connection(('1.1.1.1', 53), timeout=2)\n    print('connected')\nexcept OSError:\n    print('blocked')")
+	if res.Stdout != "blocked\n" {
+		t.Errorf("result = %+v, want network blocked", res)
+	}
+}
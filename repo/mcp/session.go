@@ -0,0 +1,73 @@
+package mcp
+
+import (
+	"context"
+	"sync"
+
+	"github.com/HildaM/logs/slog"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+)
+
+// closeSessionTool 有状态的MCP服务（如 Python REPL）提供的会话释放工具
+// 由运行结束时调用，不提供给智能体
+const closeSessionTool = "close_session"
+
+// sessionKey 上下文中存放会话ID的 key
+type sessionKey struct{}
+
+var (
+	sessionServers   = map[string]bool{} // 提供会话释放工具的MCP服务
+	sessionServersMu sync.Mutex
+)
+
+// WithSession 将会话ID写入上下文
+// 之后的工具调用通过 _meta.session_id 携带会话ID，有状态的MCP服务据此隔离不同运行的变量和环境
+func WithSession(ctx context.Context, sessionID string) context.Context {
+	return context.WithValue(ctx, sessionKey{}, sessionID)
+}
+
+// SessionID 获取上下文中的会话ID，未设置时返回空字符串
+func SessionID(ctx context.Context) string {
+	id, _ := ctx.Value(sessionKey{}).(string)
+	return id
+}
+
+// sessionMeta 根据上下文中的会话ID构造请求元数据，未设置会话时返回 nil
+func sessionMeta(ctx context.Context) *mcpgo.Meta {
+	id := SessionID(ctx)
+	if id == "" {
+		return nil
+	}
+	return &mcpgo.Meta{AdditionalFields: map[string]any{"session_id": id}}
+}
+
+// addSessionServer 记录提供会话释放工具的MCP服务
+func addSessionServer(serverName string) {
+	sessionServersMu.Lock()
+	defer sessionServersMu.Unlock()
+	sessionServers[serverName] = true
+}
+
+// CloseSession 通知所有有状态的MCP服务释放会话，失败时仅记录日志，空闲会话最终由服务端回收
+func CloseSession(ctx context.Context, sessionID string) {
+	sessionServersMu.Lock()
+	names := make([]string, 0, len(sessionServers))
+	for name := range sessionServers {
+		names = append(names, name)
+	}
+	sessionServersMu.Unlock()
+
+	for _, name := range names {
+		cli, ok := getClient(name)
+		if !ok {
+			continue
+		}
+		req := mcpgo.CallToolRequest{}
+		req.Params.Name = closeSessionTool
+		req.Params.Arguments = map[string]any{}
+		req.Params.Meta = sessionMeta(WithSession(ctx, sessionID))
+		if _, err := cli.CallTool(ctx, req); err != nil {
+			slog.Error("CloseSession failed, server = %s, session = %s, err = %v", name, sessionID, err)
+		}
+	}
+}
@@ -4,11 +4,18 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/cloudwego/eino/components/tool"
 	"github.com/cloudwego/eino/schema"
+	"github.com/hildam/deer-flow-go/repo/metrics"
+	"github.com/hildam/deer-flow-go/repo/tracing"
 	"github.com/mark3labs/mcp-go/client"
 	mcpgo "github.com/mark3labs/mcp-go/mcp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // MCP 工具类型枚举
@@ -18,7 +25,8 @@ const (
 )
 
 var (
-	mcpServer map[string]client.MCPClient // MCP服务端客户端管理
+	mcpServer   map[string]client.MCPClient // MCP服务端客户端管理
+	mcpServerMu sync.RWMutex                // 保护 mcpServer，客户端本身支持并发调用
 )
 
 // MCPConfig MCP配置
@@ -95,6 +103,7 @@ func (w *ServerConfigWrapper) MarshalJSON() ([]byte, error) {
 // MCPTool MCP工具包装器
 type MCPTool struct {
 	cli         client.MCPClient      // MCP客户端
+	serverName  string                // MCP服务名称
 	toolName    string                // 工具名称
 	toolDesc    string                // 工具描述
 	inputSchema mcpgo.ToolInputSchema // 输入参数Schema
@@ -115,7 +124,29 @@ func (t *MCPTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
 }
 
 // InvokableRun 可调用运行
-func (t *MCPTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+func (t *MCPTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (result string, err error) {
+	// 运行被取消后不再发起新的调用，调用中的请求由 MCP 客户端随上下文取消
+	if err := ctx.Err(); err != nil {
+		return "", fmt.Errorf("MCP tool call canceled: %w", context.Cause(ctx))
+	}
+
+	// 链路追踪及指标采集
+	start := time.Now()
+	ctx, span := tracing.Tracer().Start(ctx, "mcp.call_tool", trace.WithAttributes(
+		attribute.String("mcp.server", t.serverName),
+		attribute.String("mcp.tool", t.toolName),
+		attribute.Int("mcp.args_size", len(argumentsInJSON)),
+	))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.SetAttributes(attribute.Int("mcp.result_size", len(result)))
+		span.End()
+		metrics.ObserveTool(t.serverName, t.toolName, time.Since(start), err)
+	}()
+
 	// 解析JSON参数
 	var paramsMap map[string]any
 	if err := json.Unmarshal([]byte(argumentsInJSON), &paramsMap); err != nil {
@@ -126,6 +157,7 @@ func (t *MCPTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts
 	callReq := mcpgo.CallToolRequest{}
 	callReq.Params.Name = t.toolName
 	callReq.Params.Arguments = paramsMap
+	callReq.Params.Meta = sessionMeta(ctx)
 
 	resp, err := t.cli.CallTool(ctx, callReq)
 	if err != nil {
@@ -145,6 +177,7 @@ func (t *MCPTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts
 	if len(resp.Content) == 0 {
 		return "", nil
 	}
+	resp.Content = saveContentArtifacts(ctx, t.toolName, resp.Content)
 
 	// 如果只有一个内容项，直接返回
 	if len(resp.Content) == 1 {
@@ -0,0 +1,140 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/hildam/deer-flow-go/entity/model"
+	"github.com/hildam/deer-flow-go/repo/checkpoint"
+)
+
+const namespace = "deer_flow"
+
+var (
+	runsStarted = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "runs_started_total",
+		Help:      "Number of research runs started.",
+	})
+	runsFinished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "runs_finished_total",
+		Help:      "Number of research runs finished, by status and the last agent executed.",
+	}, []string{"status", "terminal_agent"})
+
+	agentLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "agent_duration_seconds",
+		Help:      "Latency of each agent subgraph execution.",
+		Buckets:   prometheus.ExponentialBuckets(0.1, 2, 12),
+	}, []string{"agent"})
+
+	llmRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_requests_total",
+		Help:      "Number of chat model requests.",
+	}, []string{"model"})
+	llmErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_errors_total",
+		Help:      "Number of failed chat model requests.",
+	}, []string{"model"})
+
+	toolCalls = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "mcp_tool_calls_total",
+		Help:      "Number of MCP tool calls.",
+	}, []string{"server", "tool"})
+	toolErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "mcp_tool_errors_total",
+		Help:      "Number of failed MCP tool calls.",
+	}, []string{"server", "tool"})
+	toolLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "mcp_tool_duration_seconds",
+		Help:      "Latency of MCP tool calls.",
+		Buckets:   prometheus.ExponentialBuckets(0.05, 2, 12),
+	}, []string{"server", "tool"})
+
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "checkpoint_entries",
+		Help:      "Number of checkpoints held by the checkpoint store.",
+	}, func() float64 {
+		entries, _ := checkpoint.Size()
+		return float64(entries)
+	})
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "checkpoint_bytes",
+		Help:      "Total size in bytes of the checkpoints held by the checkpoint store.",
+	}, func() float64 {
+		_, size := checkpoint.Size()
+		return float64(size)
+	})
+
+	interruptedThreads = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "interrupted_threads",
+		Help:      "Number of threads currently waiting for human feedback.",
+	})
+)
+
+var (
+	interruptedMu  sync.Mutex
+	interruptedSet = map[string]struct{}{} // 等待人工反馈的线程
+)
+
+// RunStarted 记录一次运行开始，同一线程恢复运行时不再视为中断状态
+func RunStarted(threadID string) {
+	runsStarted.Inc()
+	setInterrupted(threadID, false)
+}
+
+// RunFinished 记录一次运行结束
+func RunFinished(threadID string, status model.RunStatus, terminalAgent string) {
+	runsFinished.WithLabelValues(string(status), terminalAgent).Inc()
+	setInterrupted(threadID, status == model.RunInterrupted)
+}
+
+// ObserveAgent 记录 agent 执行耗时
+func ObserveAgent(agentName string, d time.Duration) {
+	agentLatency.WithLabelValues(agentName).Observe(d.Seconds())
+}
+
+// ObserveLLM 记录一次模型调用
+func ObserveLLM(modelName string, err error) {
+	llmRequests.WithLabelValues(modelName).Inc()
+	if err != nil {
+		llmErrors.WithLabelValues(modelName).Inc()
+	}
+}
+
+// ObserveTool 记录一次 MCP 工具调用
+func ObserveTool(server, tool string, d time.Duration, err error) {
+	toolCalls.WithLabelValues(server, tool).Inc()
+	toolLatency.WithLabelValues(server, tool).Observe(d.Seconds())
+	if err != nil {
+		toolErrors.WithLabelValues(server, tool).Inc()
+	}
+}
+
+// setInterrupted 更新线程的中断状态
+func setInterrupted(threadID string, interrupted bool) {
+	if threadID == "" {
+		return
+	}
+	interruptedMu.Lock()
+	defer interruptedMu.Unlock()
+
+	if interrupted {
+		interruptedSet[threadID] = struct{}{}
+	} else {
+		delete(interruptedSet, threadID)
+	}
+	interruptedThreads.Set(float64(len(interruptedSet)))
+}
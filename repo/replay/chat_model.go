@@ -0,0 +1,188 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/cloudwego/eino/components"
+	ecmodel "github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+
+	"github.com/hildam/deer-flow-go/repo/callback"
+)
+
+// recordingChatModel 录制模型调用的包装器
+type recordingChatModel struct {
+	inner ecmodel.ToolCallingChatModel
+	tools []string // 绑定的工具名称
+}
+
+// WrapChatModel 录制模式下包装真实模型，其余模式原样返回
+func WrapChatModel(m ecmodel.ToolCallingChatModel) ecmodel.ToolCallingChatModel {
+	if !Recording() {
+		return m
+	}
+	return &recordingChatModel{inner: m}
+}
+
+// Generate 调用真实模型并录制请求与响应
+func (m *recordingChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...ecmodel.Option) (*schema.Message, error) {
+	s := current.Load()
+	if s == nil || s.mode != ModeRecord {
+		return m.inner.Generate(ctx, input, opts...)
+	}
+	idx := s.addChat(ChatExchange{Agent: callback.AgentFromContext(ctx), Tools: m.tools, Input: copyMessages(input)})
+	out, err := m.inner.Generate(ctx, input, opts...)
+	s.finishChat(idx, copyMessage(out), err)
+	return out, err
+}
+
+// Stream 调用真实模型，复制一份流在后台拼接为完整消息后录制
+func (m *recordingChatModel) Stream(ctx context.Context, input []*schema.Message, opts ...ecmodel.Option) (*schema.StreamReader[*schema.Message], error) {
+	s := current.Load()
+	if s == nil || s.mode != ModeRecord {
+		return m.inner.Stream(ctx, input, opts...)
+	}
+	idx := s.addChat(ChatExchange{Agent: callback.AgentFromContext(ctx), Tools: m.tools, Input: copyMessages(input)})
+	sr, err := m.inner.Stream(ctx, input, opts...)
+	if err != nil {
+		s.finishChat(idx, nil, err)
+		return nil, err
+	}
+
+	copies := sr.Copy(2)
+	s.pending.Add(1)
+	go func() {
+		defer s.pending.Done()
+		defer copies[1].Close()
+
+		var chunks []*schema.Message
+		for {
+			chunk, err := copies[1].Recv()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				// 输出中途失败时一并录制失败前的输出，回放时先输出再返回错误
+				var partial *schema.Message
+				if len(chunks) > 0 {
+					partial, _ = schema.ConcatMessages(chunks)
+				}
+				s.finishChat(idx, partial, err)
+				return
+			}
+			chunks = append(chunks, chunk)
+		}
+		out, err := schema.ConcatMessages(chunks)
+		s.finishChat(idx, out, err)
+	}()
+	return copies[0], nil
+}
+
+// WithTools 绑定工具，返回的模型同样会被录制
+func (m *recordingChatModel) WithTools(tools []*schema.ToolInfo) (ecmodel.ToolCallingChatModel, error) {
+	inner, err := m.inner.WithTools(tools)
+	if err != nil {
+		return nil, err
+	}
+	return &recordingChatModel{inner: inner, tools: toolNames(tools)}, nil
+}
+
+// GetType 沿用真实模型的类型
+func (m *recordingChatModel) GetType() string {
+	typ, _ := components.GetType(m.inner)
+	return typ
+}
+
+// IsCallbacksEnabled 真实模型自行触发回调时，框架不再重复注入
+func (m *recordingChatModel) IsCallbacksEnabled() bool {
+	return components.IsCallbacksEnabled(m.inner)
+}
+
+// chatModel 回放录制结果的替身模型
+type chatModel struct {
+	tools []string // 绑定的工具名称
+}
+
+// NewChatModel 创建回放模型，按发起调用的智能体和请求消息匹配录制的响应，同一智能体的调用按录制顺序依次返回
+func NewChatModel() ecmodel.ToolCallingChatModel {
+	return &chatModel{}
+}
+
+// Generate 返回下一条录制的响应
+func (m *chatModel) Generate(ctx context.Context, input []*schema.Message, opts ...ecmodel.Option) (*schema.Message, error) {
+	s := current.Load()
+	if s == nil || s.mode != ModeReplay {
+		return nil, errors.New("replay: chat model used outside replay mode")
+	}
+	ex, err := s.nextChat(callback.AgentFromContext(ctx), input)
+	if err != nil {
+		return nil, err
+	}
+	if ex.Error != "" {
+		return nil, errors.New(ex.Error)
+	}
+	return copyMessage(ex.Output), nil
+}
+
+// Stream 以单个分片的形式返回下一条录制的响应
+// 录制的是输出中途失败的调用时，先输出失败前的内容，再在流中返回错误
+func (m *chatModel) Stream(ctx context.Context, input []*schema.Message, opts ...ecmodel.Option) (*schema.StreamReader[*schema.Message], error) {
+	s := current.Load()
+	if s == nil || s.mode != ModeReplay {
+		return nil, errors.New("replay: chat model used outside replay mode")
+	}
+	ex, err := s.nextChat(callback.AgentFromContext(ctx), input)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case ex.Error == "":
+		return schema.StreamReaderFromArray([]*schema.Message{copyMessage(ex.Output)}), nil
+	case ex.Output == nil:
+		return nil, errors.New(ex.Error)
+	}
+	sr, sw := schema.Pipe[*schema.Message](2)
+	sw.Send(copyMessage(ex.Output), nil)
+	sw.Send(nil, errors.New(ex.Error))
+	sw.Close()
+	return sr, nil
+}
+
+// WithTools 绑定工具，回放时工具仅用于标识
+func (m *chatModel) WithTools(tools []*schema.ToolInfo) (ecmodel.ToolCallingChatModel, error) {
+	return &chatModel{tools: toolNames(tools)}, nil
+}
+
+// GetType 模型类型
+func (m *chatModel) GetType() string {
+	return "Replay"
+}
+
+// toolNames 返回工具名称列表
+func toolNames(tools []*schema.ToolInfo) []string {
+	names := make([]string, 0, len(tools))
+	for _, t := range tools {
+		names = append(names, t.Name)
+	}
+	return names
+}
+
+// copyMessages 复制消息列表，避免录制内容被后续修改
+func copyMessages(msgs []*schema.Message) []*schema.Message {
+	out := make([]*schema.Message, 0, len(msgs))
+	for _, msg := range msgs {
+		out = append(out, copyMessage(msg))
+	}
+	return out
+}
+
+// copyMessage 复制单条消息
+func copyMessage(msg *schema.Message) *schema.Message {
+	if msg == nil {
+		return nil
+	}
+	cp := *msg
+	return &cp
+}
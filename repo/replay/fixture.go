@@ -0,0 +1,68 @@
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Fixture 录制文件，按调用顺序保存模型及工具的全部交互
+type Fixture struct {
+	Tools     []ToolSpec     `json:"tools,omitempty"`      // 录制时加载的工具
+	Chats     []ChatExchange `json:"chats,omitempty"`      // 模型请求与响应
+	ToolCalls []ToolExchange `json:"tool_calls,omitempty"` // 工具调用与结果
+}
+
+// ToolSpec 工具元信息，回放时据此构造替身工具
+type ToolSpec struct {
+	Server string           `json:"server,omitempty"` // 工具所属的MCP服务
+	Name   string           `json:"name"`             // 工具名称
+	Desc   string           `json:"desc,omitempty"`   // 工具描述
+	Params *openapi3.Schema `json:"params,omitempty"` // 输入参数Schema
+}
+
+// ChatExchange 一次模型调用
+type ChatExchange struct {
+	Agent  string            `json:"agent,omitempty"`  // 发起调用的智能体，为空时可回放给任意智能体
+	Tools  []string          `json:"tools,omitempty"`  // 绑定的工具名称
+	Input  []*schema.Message `json:"input"`            // 请求消息，为空时不比较请求消息
+	Output *schema.Message   `json:"output,omitempty"` // 响应消息，流式调用时为拼接后的完整消息
+	Error  string            `json:"error,omitempty"`  // 调用失败时的错误信息，流式输出中途失败时 Output 为失败前输出的内容
+}
+
+// ToolExchange 一次工具调用
+type ToolExchange struct {
+	Tool      string `json:"tool"`             // 工具名称
+	Arguments string `json:"arguments"`        // JSON格式的调用参数
+	Result    string `json:"result,omitempty"` // 调用结果
+	Error     string `json:"error,omitempty"`  // 调用失败时的错误信息
+}
+
+// LoadFixture 读取录制文件
+func LoadFixture(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("LoadFixture failed, read file err: %w", err)
+	}
+	fixture := &Fixture{}
+	if err := json.Unmarshal(data, fixture); err != nil {
+		return nil, fmt.Errorf("LoadFixture failed, unmarshal err: %w", err)
+	}
+	return fixture, nil
+}
+
+// Save 写入录制文件
+func (f *Fixture) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("Save fixture failed, mkdir err: %w", err)
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Save fixture failed, marshal err: %w", err)
+	}
+	return os.WriteFile(path, data, 0o644)
+}
@@ -0,0 +1,212 @@
+package replay
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/cloudwego/eino/schema"
+
+	"github.com/hildam/deer-flow-go/entity/conf"
+)
+
+// 运行模式
+const (
+	ModeRecord = "record" // 录制真实的模型及工具交互
+	ModeReplay = "replay" // 使用录制结果替代真实调用，无需模型服务和MCP服务
+
+	defaultFixture = "data/fixture.json"
+)
+
+// session 一次录制或回放会话
+type session struct {
+	mu       sync.Mutex
+	mode     string
+	path     string
+	fixture  *Fixture
+	chatUsed []bool // 已回放的模型调用
+	used     []bool // 已回放的工具调用
+
+	pending sync.WaitGroup // 尚未读取完毕的流式响应
+}
+
+var current atomic.Pointer[session] // 当前会话，为空表示关闭录制回放
+
+// Init 根据配置开启录制或回放
+func Init() error {
+	cfg := conf.GetCfg().Replay
+	return Start(cfg.Mode, cfg.Fixture)
+}
+
+// Start 开启录制或回放，mode 为空时关闭
+func Start(mode, path string) error {
+	if path == "" {
+		path = defaultFixture
+	}
+	s := &session{mode: mode, path: path, fixture: &Fixture{}}
+	switch mode {
+	case "":
+		current.Store(nil)
+		return nil
+	case ModeRecord:
+	case ModeReplay:
+		fixture, err := LoadFixture(path)
+		if err != nil {
+			return fmt.Errorf("Init replay failed, err: %w", err)
+		}
+		s.fixture = fixture
+		s.chatUsed = make([]bool, len(fixture.Chats))
+		s.used = make([]bool, len(fixture.ToolCalls))
+	default:
+		return fmt.Errorf("Init replay failed, unknown mode: %s", mode)
+	}
+	current.Store(s)
+	return nil
+}
+
+// Close 结束会话，录制模式下写入录制文件
+func Close() error {
+	s := current.Swap(nil)
+	if s == nil || s.mode != ModeRecord {
+		return nil
+	}
+	s.pending.Wait()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fixture.Save(s.path)
+}
+
+// Recording 是否处于录制模式
+func Recording() bool {
+	s := current.Load()
+	return s != nil && s.mode == ModeRecord
+}
+
+// Replaying 是否处于回放模式
+func Replaying() bool {
+	s := current.Load()
+	return s != nil && s.mode == ModeReplay
+}
+
+// addChat 预留一次模型调用的录制位置，流式调用结束后再写入响应
+func (s *session) addChat(ex ChatExchange) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fixture.Chats = append(s.fixture.Chats, ex)
+	return len(s.fixture.Chats) - 1
+}
+
+// finishChat 写入模型调用的响应
+func (s *session) finishChat(idx int, out *schema.Message, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fixture.Chats[idx].Output = out
+	if err != nil {
+		s.fixture.Chats[idx].Error = err.Error()
+	}
+}
+
+// nextChat 按录制顺序取出第一条与本次调用匹配且未回放过的模型调用
+// 录制了智能体的调用只回放给同一智能体，录制了请求消息的调用还需请求消息一致，并发的调用据此取到各自的响应
+// 没有匹配的录制时返回错误并指出下一条未回放的录制，而不是返回其他调用的响应
+func (s *session) nextChat(agent string, input []*schema.Message) (ChatExchange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	next := -1
+	for i, ex := range s.fixture.Chats {
+		if s.chatUsed[i] {
+			continue
+		}
+		if next < 0 {
+			next = i
+		}
+		if ex.Agent != "" && agent != "" && ex.Agent != agent {
+			continue
+		}
+		if len(ex.Input) > 0 && !sameInput(ex.Input, input) {
+			continue
+		}
+		s.chatUsed[i] = true
+		return ex, nil
+	}
+	if next < 0 {
+		return ChatExchange{}, fmt.Errorf("replay: no recorded chat exchange left for agent %q in %s", agent, s.path)
+	}
+	return ChatExchange{}, fmt.Errorf("replay: no recorded chat exchange matches the call from agent %q in %s, next unreplayed exchange #%d was recorded for agent %q",
+		agent, s.path, next, s.fixture.Chats[next].Agent)
+}
+
+// sameInput 比较录制的请求消息与本次调用的请求消息
+// 系统消息由提示词模板渲染，包含当前时间等每次运行都不同的内容，不参与比较
+func sameInput(recorded, input []*schema.Message) bool {
+	a, b := conversation(recorded), conversation(input)
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Role != b[i].Role || a[i].Content != b[i].Content || a[i].ToolCallID != b[i].ToolCallID {
+			return false
+		}
+		if !slices.EqualFunc(a[i].ToolCalls, b[i].ToolCalls, func(x, y schema.ToolCall) bool {
+			return x.Function.Name == y.Function.Name && x.Function.Arguments == y.Function.Arguments
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+// conversation 去掉系统消息后的请求消息
+func conversation(msgs []*schema.Message) []*schema.Message {
+	out := make([]*schema.Message, 0, len(msgs))
+	for _, msg := range msgs {
+		if msg != nil && msg.Role != schema.System {
+			out = append(out, msg)
+		}
+	}
+	return out
+}
+
+// addTool 录制工具元信息，同名工具只记录一次
+func (s *session) addTool(spec ToolSpec) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range s.fixture.Tools {
+		if t.Name == spec.Name {
+			return
+		}
+	}
+	s.fixture.Tools = append(s.fixture.Tools, spec)
+}
+
+// addToolCall 录制一次工具调用
+func (s *session) addToolCall(ex ToolExchange) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fixture.ToolCalls = append(s.fixture.ToolCalls, ex)
+}
+
+// matchToolCall 查找未回放过的工具调用，优先匹配参数完全一致的记录，其次按同名工具的录制顺序
+func (s *session) matchToolCall(name, arguments string) (ToolExchange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	found := -1
+	for i, ex := range s.fixture.ToolCalls {
+		if s.used[i] || ex.Tool != name {
+			continue
+		}
+		if ex.Arguments == arguments {
+			found = i
+			break
+		}
+		if found < 0 {
+			found = i
+		}
+	}
+	if found < 0 {
+		return ToolExchange{}, fmt.Errorf("replay: no recorded call of tool %s in %s", name, s.path)
+	}
+	s.used[found] = true
+	return s.fixture.ToolCalls[found], nil
+}
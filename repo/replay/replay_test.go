@@ -0,0 +1,150 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+
+	ecmodel "github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+// stubModel 返回固定响应的模型
+type stubModel struct {
+	reply string
+}
+
+func (m *stubModel) Generate(ctx context.Context, input []*schema.Message, opts ...ecmodel.Option) (*schema.Message, error) {
+	return schema.AssistantMessage(m.reply, nil), nil
+}
+
+func (m *stubModel) Stream(ctx context.Context, input []*schema.Message, opts ...ecmodel.Option) (*schema.StreamReader[*schema.Message], error) {
+	return schema.StreamReaderFromArray([]*schema.Message{
+		schema.AssistantMessage(m.reply[:2], nil),
+		schema.AssistantMessage(m.reply[2:], nil),
+	}), nil
+}
+
+func (m *stubModel) WithTools(tools []*schema.ToolInfo) (ecmodel.ToolCallingChatModel, error) {
+	return m, nil
+}
+
+// stubTool 按参数回显结果的工具
+type stubTool struct{}
+
+func (t *stubTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{
+		Name: "echo",
+		Desc: "Echo the arguments.",
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"text": {Type: schema.String, Required: true},
+		}),
+	}, nil
+}
+
+func (t *stubTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	return "echo:" + argumentsInJSON, nil
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "fixture.json")
+
+	// 录制
+	if err := Start(ModeRecord, path); err != nil {
+		t.Fatalf("start record: %v", err)
+	}
+	m, _ := WrapChatModel(&stubModel{reply: "hello"}).WithTools([]*schema.ToolInfo{{Name: "echo"}})
+	if _, err := m.Generate(ctx, []*schema.Message{schema.UserMessage("hi")}); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	sr, err := m.Stream(ctx, []*schema.Message{schema.UserMessage("hi again")})
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	drain(t, sr)
+	tl := WrapTool(ctx, "stub", &stubTool{})
+	for _, args := range []string{`{"text":"a"}`, `{"text":"b"}`} {
+		if _, err := tl.InvokableRun(ctx, args); err != nil {
+			t.Fatalf("tool: %v", err)
+		}
+	}
+	if err := Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	fixture, err := LoadFixture(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(fixture.Chats) != 2 || len(fixture.ToolCalls) != 2 || len(fixture.Tools) != 1 {
+		t.Fatalf("fixture = %+v", fixture)
+	}
+	if got := fixture.Chats[1].Output.Content; got != "hello" {
+		t.Errorf("recorded stream output = %q, want hello", got)
+	}
+
+	// 回放
+	if err := Start(ModeReplay, path); err != nil {
+		t.Fatalf("start replay: %v", err)
+	}
+	defer Close()
+
+	// 请求消息与录制不一致时报错，不返回其他调用的响应
+	fake := NewChatModel()
+	if _, err := fake.Generate(ctx, []*schema.Message{schema.UserMessage("bye")}); err == nil {
+		t.Error("expected error for an unrecorded input")
+	}
+	// 按请求消息匹配，与录制顺序无关，系统消息不参与比较
+	sr, err = fake.Stream(ctx, []*schema.Message{schema.SystemMessage("now"), schema.UserMessage("hi again")})
+	if err != nil {
+		t.Fatalf("replayed stream: %v", err)
+	}
+	if got := drain(t, sr); got != "hello" {
+		t.Errorf("replayed stream = %q, want hello", got)
+	}
+	out, err := fake.Generate(ctx, []*schema.Message{schema.UserMessage("hi")})
+	if err != nil || out.Content != "hello" {
+		t.Errorf("replayed generate = %v, %v", out, err)
+	}
+	if _, err := fake.Generate(ctx, []*schema.Message{schema.UserMessage("hi")}); err == nil {
+		t.Error("expected error once recorded chats are exhausted")
+	}
+
+	tools := Tools()
+	if len(tools) != 1 {
+		t.Fatalf("replayed tools = %d, want 1", len(tools))
+	}
+	info, _ := tools[0].Info(ctx)
+	if info.Name != "echo" || info.ParamsOneOf == nil {
+		t.Errorf("replayed tool info = %+v", info)
+	}
+	// 参数一致的记录优先于录制顺序
+	replayed := tools[0].(tool.InvokableTool)
+	if got, _ := replayed.InvokableRun(ctx, `{"text":"b"}`); got != `echo:{"text":"b"}` {
+		t.Errorf("replayed tool result = %q", got)
+	}
+	if got, _ := replayed.InvokableRun(ctx, `{"text":"c"}`); got != `echo:{"text":"a"}` {
+		t.Errorf("replayed fallback result = %q", got)
+	}
+}
+
+// drain 读取整个流并拼接内容
+func drain(t *testing.T, sr *schema.StreamReader[*schema.Message]) string {
+	t.Helper()
+	defer sr.Close()
+	content := ""
+	for {
+		msg, err := sr.Recv()
+		if errors.Is(err, io.EOF) {
+			return content
+		}
+		if err != nil {
+			t.Fatalf("recv: %v", err)
+		}
+		content += msg.Content
+	}
+}
@@ -0,0 +1,89 @@
+package replay
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+)
+
+// recordingTool 录制工具调用的包装器
+type recordingTool struct {
+	tool.InvokableTool
+	name string
+}
+
+// WrapTool 录制模式下记录工具元信息并包装真实工具，其余模式原样返回
+func WrapTool(ctx context.Context, server string, t tool.InvokableTool) tool.InvokableTool {
+	s := current.Load()
+	if s == nil || s.mode != ModeRecord {
+		return t
+	}
+	info, err := t.Info(ctx)
+	if err != nil {
+		return t
+	}
+	spec := ToolSpec{Server: server, Name: info.Name, Desc: info.Desc}
+	if info.ParamsOneOf != nil {
+		spec.Params, _ = info.ParamsOneOf.ToOpenAPIV3()
+	}
+	s.addTool(spec)
+	return &recordingTool{InvokableTool: t, name: info.Name}
+}
+
+// InvokableRun 调用真实工具并录制参数与结果
+func (t *recordingTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	result, err := t.InvokableTool.InvokableRun(ctx, argumentsInJSON, opts...)
+	if s := current.Load(); s != nil && s.mode == ModeRecord {
+		ex := ToolExchange{Tool: t.name, Arguments: argumentsInJSON, Result: result}
+		if err != nil {
+			ex.Error = err.Error()
+		}
+		s.addToolCall(ex)
+	}
+	return result, err
+}
+
+// replayTool 回放录制结果的替身工具
+type replayTool struct {
+	spec ToolSpec
+}
+
+// Tools 返回录制文件中的全部工具，非回放模式下返回空
+func Tools() []tool.BaseTool {
+	s := current.Load()
+	if s == nil || s.mode != ModeReplay {
+		return nil
+	}
+	tools := make([]tool.BaseTool, 0, len(s.fixture.Tools))
+	for _, spec := range s.fixture.Tools {
+		tools = append(tools, &replayTool{spec: spec})
+	}
+	return tools
+}
+
+// Info 获取工具信息
+func (t *replayTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	info := &schema.ToolInfo{Name: t.spec.Name, Desc: t.spec.Desc}
+	if t.spec.Params != nil {
+		info.ParamsOneOf = schema.NewParamsOneOfByOpenAPIV3(t.spec.Params)
+	}
+	return info, nil
+}
+
+// InvokableRun 返回录制的调用结果
+func (t *replayTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	s := current.Load()
+	if s == nil || s.mode != ModeReplay {
+		return "", errors.New("replay: tool used outside replay mode")
+	}
+	ex, err := s.matchToolCall(t.spec.Name, argumentsInJSON)
+	if err != nil {
+		return "", err
+	}
+	if ex.Error != "" {
+		return "", errors.New(ex.Error)
+	}
+	return ex.Result, nil
+}
@@ -0,0 +1,334 @@
+package template
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HildaM/logs/slog"
+	"github.com/fsnotify/fsnotify"
+	"github.com/nikolalohinski/gonja"
+	"github.com/nikolalohinski/gonja/config"
+
+	"github.com/hildam/deer-flow-go/entity/model"
+	embedded "github.com/hildam/deer-flow-go/prompts"
+)
+
+// 模板来源
+const (
+	SourceEmbedded = "embedded" // 内置模板
+	SourceOverride = "override" // 覆盖目录中的模板
+)
+
+// reloadDelay 覆盖目录变化后延迟重新加载，合并编辑器保存时产生的多次事件
+const reloadDelay = 200 * time.Millisecond
+
+// variables 渲染模板时提供的变量，与各智能体 loadMsg 中的变量保持一致，用于校验模板
+var variables = map[string]any{
+	"locale":              "en-US",
+	"max_step_num":        3,
+	"max_plan_iterations": 1,
+	"CURRENT_TIME":        "2006-01-02 15:04:05",
+	"user_input":          []any{},
+}
+
+var (
+	// strictEnv 校验模板使用的 Jinja2 环境，引用未定义的变量时报错
+	strictEnv = gonja.NewEnvironment(strictConfig(), gonja.DefaultLoader)
+	// undefinedName 从渲染错误中提取未定义的变量名
+	undefinedName = regexp.MustCompile(`Unable to evaluate name "([^"]+)"`)
+)
+
+// Prompt 已解析并校验的提示词模板
+type Prompt struct {
+	Name      string   `json:"name"`                // 模板名称，即智能体名称
+	Variant   string   `json:"variant,omitempty"`   // 变体名称，默认模板为空
+	Locale    string   `json:"locale,omitempty"`    // 模板语言，默认模板为空
+	Path      string   `json:"path"`                // 模板相对路径，如 zh-CN/planner.concise.md
+	Source    string   `json:"source"`              // 模板来源：embedded 或 override
+	Version   string   `json:"version"`             // 模板内容摘要
+	Undefined []string `json:"undefined,omitempty"` // 引用的未定义变量，渲染时为空值
+	Content   string   `json:"-"`
+}
+
+// Ref 模板的版本信息，记录到运行元数据中
+func (p *Prompt) Ref() model.PromptRef {
+	return model.PromptRef{Variant: p.Variant, Locale: p.Locale, Version: p.Version, Source: p.Source}
+}
+
+// Registry 提示词模板注册表，启动时加载并校验内置模板和覆盖目录中的模板，覆盖目录变化时自动重新加载
+type Registry struct {
+	dir string // 覆盖目录
+
+	mu      sync.RWMutex
+	prompts map[string]*Prompt // 模板相对路径 -> 模板
+
+	watcher *fsnotify.Watcher
+	timer   *time.Timer
+}
+
+// NewRegistry 创建注册表并加载模板，模板存在语法错误时返回错误
+func NewRegistry(dir string) (*Registry, error) {
+	r := &Registry{dir: dir}
+	prompts, err := r.load(nil)
+	if err != nil {
+		return nil, err
+	}
+	r.prompts = prompts
+	return r, nil
+}
+
+// Get 按名称、变体和语言获取模板，依次查找 <locale>/<name>[.<variant>].md、<language>/<name>[.<variant>].md 和 <name>[.<variant>].md
+func (r *Registry) Get(name, variant, locale string) (*Prompt, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, p := range candidates(fileName(name, variant), locale) {
+		if prompt, ok := r.prompts[p]; ok {
+			return prompt, nil
+		}
+	}
+	if variant != "" {
+		return nil, fmt.Errorf("prompt %s variant %q not found", name, variant)
+	}
+	return nil, fmt.Errorf("prompt %s not found", name)
+}
+
+// HasVariant 判断模板是否存在指定变体的任一语言版本
+func (r *Registry) HasVariant(name, variant string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, p := range r.prompts {
+		if p.Name == name && p.Variant == variant {
+			return true
+		}
+	}
+	return false
+}
+
+// List 返回全部模板，按路径排序
+func (r *Registry) List() []*Prompt {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	prompts := make([]*Prompt, 0, len(r.prompts))
+	for _, p := range r.prompts {
+		prompts = append(prompts, p)
+	}
+	sort.Slice(prompts, func(i, j int) bool {
+		return prompts[i].Path < prompts[j].Path
+	})
+	return prompts
+}
+
+// Reload 重新加载模板，存在语法错误的覆盖模板保留之前的版本
+func (r *Registry) Reload() {
+	r.mu.RLock()
+	prev := r.prompts
+	r.mu.RUnlock()
+
+	prompts, err := r.load(prev)
+	if err != nil {
+		slog.Error("Reload failed, load prompts err = %v, dir = %s", err, r.dir)
+		return
+	}
+	r.mu.Lock()
+	r.prompts = prompts
+	r.mu.Unlock()
+	slog.Info("Reload prompts success, dir = %s, count = %d", r.dir, len(prompts))
+}
+
+// Watch 监听覆盖目录，模板变化时自动重新加载，目录不存在时不监听
+func (r *Registry) Watch() error {
+	if _, err := os.Stat(r.dir); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("Watch prompts failed, new watcher err: %w", err)
+	}
+	// 监听覆盖目录及其语言子目录
+	err = filepath.WalkDir(r.dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+		return watcher.Add(p)
+	})
+	if err != nil {
+		_ = watcher.Close()
+		return fmt.Errorf("Watch prompts failed, add dir err: %w", err)
+	}
+	r.watcher = watcher
+
+	go func() {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				// 新建的语言目录需要单独监听
+				if event.Has(fsnotify.Create) {
+					if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+						_ = watcher.Add(event.Name)
+					}
+				}
+				r.scheduleReload()
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				slog.Error("Watch prompts failed, watcher err = %v, dir = %s", err, r.dir)
+			}
+		}
+	}()
+	return nil
+}
+
+// Close 停止监听覆盖目录
+func (r *Registry) Close() error {
+	if r.watcher == nil {
+		return nil
+	}
+	r.mu.Lock()
+	if r.timer != nil {
+		r.timer.Stop()
+	}
+	r.mu.Unlock()
+	return r.watcher.Close()
+}
+
+// scheduleReload 延迟重新加载，期间的多次变化只触发一次加载
+func (r *Registry) scheduleReload() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.timer != nil {
+		r.timer.Stop()
+	}
+	r.timer = time.AfterFunc(reloadDelay, r.Reload)
+}
+
+// load 加载内置模板和覆盖目录中的模板，覆盖目录中的同路径模板优先
+// prev 不为空时为重新加载，存在语法错误的覆盖模板保留 prev 中的版本，否则返回错误
+func (r *Registry) load(prev map[string]*Prompt) (map[string]*Prompt, error) {
+	prompts := map[string]*Prompt{}
+	if err := loadFS(prompts, embedded.FS, SourceEmbedded, nil); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(r.dir); err == nil {
+		if err := loadFS(prompts, os.DirFS(r.dir), SourceOverride, prev); err != nil {
+			return nil, err
+		}
+	}
+	return prompts, nil
+}
+
+// loadFS 加载文件系统中的模板，只识别根目录和一级语言目录中的 .md 文件
+func loadFS(prompts map[string]*Prompt, fsys fs.FS, source string, prev map[string]*Prompt) error {
+	return fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != "." && (strings.Contains(p, "/") || !localePattern.MatchString(p)) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if path.Ext(p) != ".md" {
+			return nil
+		}
+		content, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		prompt, err := newPrompt(p, string(content), source)
+		if err != nil {
+			if old, ok := prev[p]; ok {
+				slog.Error("loadFS failed, keep previous version, err = %v, path = %s", err, p)
+				prompts[p] = old
+				return nil
+			}
+			return err
+		}
+		if len(prompt.Undefined) > 0 {
+			slog.Error("loadFS failed, prompt %s references undefined variables %v, source = %s", p, prompt.Undefined, source)
+		}
+		prompts[p] = prompt
+		return nil
+	})
+}
+
+// newPrompt 解析并校验模板，p 为模板相对路径
+func newPrompt(p, content, source string) (*Prompt, error) {
+	dir, file := path.Split(p)
+	name, variant, _ := strings.Cut(strings.TrimSuffix(file, ".md"), ".")
+	undefined, err := validate(content)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prompt %s: %w", p, err)
+	}
+	sum := sha256.Sum256([]byte(content))
+	return &Prompt{
+		Name:      name,
+		Variant:   variant,
+		Locale:    strings.TrimSuffix(dir, "/"),
+		Path:      p,
+		Source:    source,
+		Version:   hex.EncodeToString(sum[:4]),
+		Undefined: undefined,
+		Content:   content,
+	}, nil
+}
+
+// validate 解析模板并使用标准变量渲染，返回模板引用的未定义变量
+func validate(content string) ([]string, error) {
+	tpl, err := strictEnv.FromString(content)
+	if err != nil {
+		return nil, err
+	}
+	vars := make(map[string]any, len(variables))
+	for k, v := range variables {
+		vars[k] = v
+	}
+	var undefined []string
+	for {
+		_, err := tpl.Execute(vars)
+		if err == nil {
+			return undefined, nil
+		}
+		m := undefinedName.FindStringSubmatch(err.Error())
+		if m == nil || vars[m[1]] != nil {
+			// 以空值代替未定义变量后可能产生其他错误，此时只报告未定义的变量
+			if len(undefined) > 0 {
+				return undefined, nil
+			}
+			return nil, err
+		}
+		// 记录后以空值继续渲染，找出全部未定义的变量
+		undefined = append(undefined, m[1])
+		vars[m[1]] = ""
+	}
+}
+
+// strictConfig 引用未定义变量时报错的 Jinja2 配置
+func strictConfig() *config.Config {
+	cfg := config.NewConfig()
+	cfg.StrictUndefined = true
+	return cfg
+}
+
+// fileName 模板文件名
+func fileName(name, variant string) string {
+	if variant == "" {
+		return name + ".md"
+	}
+	return name + "." + variant + ".md"
+}
@@ -3,32 +3,127 @@ package template
 import (
 	"context"
 	"fmt"
-	"io/ioutil"
-	"os"
-	"path/filepath"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
 
 	"github.com/HildaM/logs/slog"
+
+	"github.com/hildam/deer-flow-go/entity/conf"
+	"github.com/hildam/deer-flow-go/entity/model"
 )
 
-// GetPromptTemplate 加载并返回一个提示模板
-func GetPromptTemplate(ctx context.Context, promptName string) (string, error) {
-	// 获取当前路径
-	dir, err := os.Getwd()
+// defaultDir 默认的模板覆盖目录
+const defaultDir = "prompts"
+
+// localePattern 合法的 locale，如 zh、zh-CN、en_US
+var localePattern = regexp.MustCompile(`^[A-Za-z]{2,3}([-_][A-Za-z0-9]{2,8})*$`)
+
+var (
+	registryMu sync.Mutex
+	registry   *Registry // 全局模板注册表，未调用 Init 时在首次使用时加载
+)
+
+// Init 加载并校验全部模板，监听覆盖目录的变化，模板存在语法错误时返回错误
+func Init() error {
+	r, err := NewRegistry(overrideDir())
 	if err != nil {
-		msg := fmt.Errorf("GetPromptTemplate failed, get current working directory, err: %w", err)
-		slog.Error(msg.Error())
-		return "", msg
+		return fmt.Errorf("Init prompts failed, err: %w", err)
+	}
+	if err := r.Watch(); err != nil {
+		slog.Error("Init prompts failed, watch err = %v, dir = %s", err, r.dir)
+	}
+	registryMu.Lock()
+	old := registry
+	registry = r
+	registryMu.Unlock()
+	if old != nil {
+		_ = old.Close()
 	}
+	return nil
+}
+
+// Close 停止监听覆盖目录
+func Close() error {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if registry == nil {
+		return nil
+	}
+	return registry.Close()
+}
 
-	// 构造文件路径
-	templatePath := filepath.Join(dir, "prompts", fmt.Sprintf("%s.md", promptName))
+// Default 全局模板注册表
+func Default() (*Registry, error) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if registry == nil {
+		r, err := NewRegistry(overrideDir())
+		if err != nil {
+			return nil, err
+		}
+		registry = r
+	}
+	return registry, nil
+}
 
-	// 读取文件内容
-	content, err := ioutil.ReadFile(templatePath)
+// GetPromptTemplate 加载并返回一个提示模板
+// 按运行状态中的 locale 和为该智能体选择的变体查找模板，并将使用的模板版本记录到状态中
+func GetPromptTemplate(ctx context.Context, promptName string, state *model.State) (string, error) {
+	r, err := Default()
 	if err != nil {
-		msg := fmt.Errorf("GetPromptTemplate failed, read template file, err: %w", err)
-		slog.Error(msg.Error())
-		return "", msg
+		slog.Error("GetPromptTemplate failed, load prompts err = %v", err)
+		return "", err
+	}
+	prompt, err := r.Get(promptName, state.PromptVariants[promptName], state.Locale)
+	if err != nil {
+		slog.Error("GetPromptTemplate failed, err = %v, locale = %s", err, state.Locale)
+		return "", err
+	}
+	if state.Prompts == nil {
+		state.Prompts = map[string]model.PromptRef{}
+	}
+	state.Prompts[promptName] = prompt.Ref()
+	return prompt.Content, nil
+}
+
+// ValidateVariants 校验为各智能体选择的模板变体是否存在
+func ValidateVariants(variants map[string]string) error {
+	r, err := Default()
+	if err != nil {
+		return err
+	}
+	for name, variant := range variants {
+		if variant != "" && !r.HasVariant(name, variant) {
+			return fmt.Errorf("prompt %s variant %q not found", name, variant)
+		}
+	}
+	return nil
+}
+
+// overrideDir 模板覆盖目录，未配置时使用工作目录下的 prompts
+func overrideDir() string {
+	if cfg := conf.GetCfg(); cfg != nil && cfg.Prompt.Dir != "" {
+		return cfg.Prompt.Dir
+	}
+	return defaultDir
+}
+
+// candidates 按优先级返回模板文件的候选路径
+func candidates(file, locale string) []string {
+	if !localePattern.MatchString(locale) {
+		return []string{file}
+	}
+	// 统一为 zh-CN 的形式，与模板目录名一致
+	parts := strings.Split(strings.ReplaceAll(locale, "_", "-"), "-")
+	parts[0] = strings.ToLower(parts[0])
+	if len(parts) > 1 && len(parts[1]) == 2 {
+		parts[1] = strings.ToUpper(parts[1])
+	}
+	names := []string{path.Join(strings.Join(parts, "-"), file)}
+	if len(parts) > 1 {
+		names = append(names, path.Join(parts[0], file))
 	}
-	return string(content), nil
+	return append(names, file)
 }
@@ -0,0 +1,205 @@
+package template
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hildam/deer-flow-go/entity/model"
+)
+
+func TestCandidates(t *testing.T) {
+	tests := []struct {
+		locale string
+		want   []string
+	}{
+		{"", []string{"planner.md"}},
+		{"en", []string{"en/planner.md", "planner.md"}},
+		{"zh-CN", []string{"zh-CN/planner.md", "zh/planner.md", "planner.md"}},
+		{"zh_cn", []string{"zh-CN/planner.md", "zh/planner.md", "planner.md"}},
+		{"zh-Hans-CN", []string{"zh-Hans-CN/planner.md", "zh/planner.md", "planner.md"}},
+		{"../etc", []string{"planner.md"}},
+	}
+	for _, tt := range tests {
+		if got := candidates("planner.md", tt.locale); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("candidates(%q) = %v, want %v", tt.locale, got, tt.want)
+		}
+	}
+}
+
+// writePrompt 在覆盖目录中写入模板
+func writePrompt(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRegistryGet(t *testing.T) {
+	dir := t.TempDir()
+	r, err := NewRegistry(dir)
+	if err != nil {
+		t.Fatalf("NewRegistry: %v", err)
+	}
+
+	// 覆盖目录为空时使用内置模板，按 locale 选择语言版本
+	en, err := r.Get("planner", "", "en-US")
+	if err != nil || !strings.Contains(en.Content, "You are a professional Deep Researcher") || en.Source != SourceEmbedded {
+		t.Fatalf("en-US planner = %+v, err = %v", en, err)
+	}
+	zh, err := r.Get("planner", "", "zh-CN")
+	if err != nil || !strings.Contains(zh.Content, "你是一名专业的深度研究员") || zh.Locale != "zh-CN" {
+		t.Fatalf("zh-CN planner = %+v, err = %v", zh, err)
+	}
+	// 没有对应语言版本时回退到默认模板
+	if coder, err := r.Get("coder", "", "zh-CN"); err != nil || coder.Path != "coder.md" {
+		t.Fatalf("zh-CN coder = %+v, err = %v", coder, err)
+	}
+	// 内置模板都能通过校验，且不引用未定义的变量
+	for _, p := range r.List() {
+		if len(p.Undefined) > 0 {
+			t.Errorf("%s: undefined variables %v", p.Path, p.Undefined)
+		}
+	}
+
+	// 覆盖目录中的模板优先于同一级的内置模板，但不覆盖更具体的语言版本
+	writePrompt(t, dir, "planner.md", "custom planner {{ locale }}")
+	writePrompt(t, dir, "zh/planner.md", "custom zh planner")
+	writePrompt(t, dir, "planner.concise.md", "concise planner")
+	writePrompt(t, dir, "notes/planner.md", "ignored")
+	r.Reload()
+
+	if p, _ := r.Get("planner", "", "en-US"); p.Content != "custom planner {{ locale }}" || p.Source != SourceOverride {
+		t.Errorf("en-US planner = %+v, want override", p)
+	}
+	if p, _ := r.Get("planner", "", "zh-CN"); p.Version != zh.Version {
+		t.Errorf("zh-CN planner = %+v, want embedded zh-CN", p)
+	}
+	if p, _ := r.Get("planner", "", "zh-TW"); p.Content != "custom zh planner" {
+		t.Errorf("zh-TW planner = %+v, want language override", p)
+	}
+	// 变体按相同的语言规则查找，不存在时不回退到默认模板
+	if p, err := r.Get("planner", "concise", "zh-CN"); err != nil || p.Variant != "concise" {
+		t.Errorf("concise planner = %+v, err = %v", p, err)
+	}
+	if _, err := r.Get("planner", "verbose", ""); err == nil {
+		t.Error("verbose planner: want error")
+	}
+	if !r.HasVariant("planner", "concise") || r.HasVariant("coder", "concise") {
+		t.Error("HasVariant mismatch")
+	}
+	if _, err := r.Get("missing", "", ""); err == nil {
+		t.Error("missing template: want error")
+	}
+}
+
+func TestRegistryValidate(t *testing.T) {
+	dir := t.TempDir()
+	writePrompt(t, dir, "coder.md", "{{ CURRENT_TIME }} {{ locale }} {{ step_title }}{% if verbose %}{{ verbose }}{% endif %}")
+	r, err := NewRegistry(dir)
+	if err != nil {
+		t.Fatalf("NewRegistry: %v", err)
+	}
+	p, _ := r.Get("coder", "", "")
+	if !reflect.DeepEqual(p.Undefined, []string{"step_title", "verbose"}) {
+		t.Errorf("undefined = %v, want [step_title verbose]", p.Undefined)
+	}
+
+	// 启动时模板存在语法错误返回错误
+	writePrompt(t, dir, "planner.md", "{% if locale %}unterminated")
+	if _, err := NewRegistry(dir); err == nil || !strings.Contains(err.Error(), "planner.md") {
+		t.Errorf("NewRegistry err = %v, want syntax error of planner.md", err)
+	}
+}
+
+func TestRegistryWatch(t *testing.T) {
+	dir := t.TempDir()
+	writePrompt(t, dir, "coder.md", "v1")
+	r, err := NewRegistry(dir)
+	if err != nil {
+		t.Fatalf("NewRegistry: %v", err)
+	}
+	if err := r.Watch(); err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	defer r.Close()
+
+	// waitContent 等待模板重新加载为指定内容
+	waitContent := func(name, locale, want string) {
+		t.Helper()
+		deadline := time.Now().Add(3 * time.Second)
+		for time.Now().Before(deadline) {
+			if p, err := r.Get(name, "", locale); err == nil && p.Content == want {
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		p, _ := r.Get(name, "", locale)
+		t.Fatalf("%s = %q, want %q", name, p.Content, want)
+	}
+
+	writePrompt(t, dir, "coder.md", "v2")
+	waitContent("coder", "", "v2")
+
+	// 语法错误的修改不生效，保留之前的版本
+	writePrompt(t, dir, "coder.md", "{% for x in %}")
+	time.Sleep(3 * reloadDelay)
+	waitContent("coder", "", "v2")
+
+	// 新建的语言目录同样被监听
+	if err := os.Mkdir(filepath.Join(dir, "de"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * reloadDelay)
+	writePrompt(t, dir, "de/coder.md", "de v1")
+	waitContent("coder", "de-DE", "de v1")
+}
+
+func TestGetPromptTemplate(t *testing.T) {
+	dir := t.TempDir()
+	writePrompt(t, dir, "planner.concise.md", "concise planner")
+	r, err := NewRegistry(dir)
+	if err != nil {
+		t.Fatalf("NewRegistry: %v", err)
+	}
+	registryMu.Lock()
+	old := registry
+	registry = r
+	registryMu.Unlock()
+	t.Cleanup(func() {
+		registryMu.Lock()
+		registry = old
+		registryMu.Unlock()
+	})
+
+	state := &model.State{Locale: "zh-CN", PromptVariants: map[string]string{"planner": "concise"}}
+	ctx := context.Background()
+	if content, err := GetPromptTemplate(ctx, "planner", state); err != nil || content != "concise planner" {
+		t.Fatalf("planner = %q, err = %v", content, err)
+	}
+	if _, err := GetPromptTemplate(ctx, "reporter", state); err != nil {
+		t.Fatalf("reporter err = %v", err)
+	}
+	// 记录使用的模板版本，用于比较不同变体的效果
+	if ref := state.Prompts["planner"]; ref.Variant != "concise" || ref.Source != SourceOverride || ref.Version == "" {
+		t.Errorf("planner ref = %+v", ref)
+	}
+	if ref := state.Prompts["reporter"]; ref.Variant != "" || ref.Locale != "zh-CN" || ref.Source != SourceEmbedded {
+		t.Errorf("reporter ref = %+v", ref)
+	}
+
+	if err := ValidateVariants(map[string]string{"planner": "concise", "reporter": ""}); err != nil {
+		t.Errorf("ValidateVariants: %v", err)
+	}
+	if err := ValidateVariants(map[string]string{"reporter": "concise"}); err == nil {
+		t.Error("ValidateVariants: want error for unknown variant")
+	}
+}
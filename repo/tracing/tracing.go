@@ -0,0 +1,109 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/hildam/deer-flow-go/entity/conf"
+	"github.com/hildam/deer-flow-go/entity/consts"
+)
+
+// 导出方式
+const (
+	ExporterOTLP = "otlp" // 通过 OTLP HTTP 上报
+	ExporterFile = "file" // 写入本地 JSON 文件，便于离线分析
+)
+
+const (
+	tracerName      = "github.com/hildam/deer-flow-go"
+	defaultFilePath = "logs/trace.json"
+)
+
+var (
+	provider *sdktrace.TracerProvider // 全局 TracerProvider，未启用时为 nil
+	traceOut *os.File                 // 本地文件导出时的文件句柄
+)
+
+// Init 初始化链路追踪，未启用时使用 otel 默认的空实现
+func Init() error {
+	cfg := conf.GetCfg().Trace
+	if !cfg.Enable {
+		return nil
+	}
+
+	exporter, err := newExporter(cfg)
+	if err != nil {
+		return fmt.Errorf("Init trace failed, err: %w", err)
+	}
+
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = consts.GraphName
+	}
+	res := resource.NewSchemaless(attribute.String("service.name", serviceName))
+
+	provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	return nil
+}
+
+// newExporter 根据配置创建导出器
+func newExporter(cfg conf.TraceConfig) (sdktrace.SpanExporter, error) {
+	switch cfg.Exporter {
+	case ExporterOTLP:
+		opts := []otlptracehttp.Option{}
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(context.Background(), opts...)
+	case ExporterFile, "":
+		path := cfg.FilePath
+		if path == "" {
+			path = defaultFilePath
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, err
+		}
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		traceOut = f
+		return stdouttrace.New(stdouttrace.WithWriter(f))
+	default:
+		return nil, fmt.Errorf("unknown trace exporter: %s", cfg.Exporter)
+	}
+}
+
+// Shutdown 刷新并关闭链路追踪，应在进程退出前调用
+func Shutdown(ctx context.Context) error {
+	if provider == nil {
+		return nil
+	}
+	err := provider.Shutdown(ctx)
+	if traceOut != nil {
+		_ = traceOut.Close()
+	}
+	return err
+}
+
+// Tracer 返回项目统一使用的 Tracer
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
@@ -0,0 +1,73 @@
+package usage
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cloudwego/eino/components"
+	ecmodel "github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// ErrBudgetExceeded 运行的用量已超出预算，不再发起模型调用
+var ErrBudgetExceeded = errors.New("run budget exceeded")
+
+// budgetChatModel 调用前检查预算的模型包装器
+type budgetChatModel struct {
+	inner ecmodel.ToolCallingChatModel
+}
+
+// WrapChatModel 包装模型，每次调用前检查上下文中统计器的预算，超出时返回 ErrBudgetExceeded
+// 路由只在智能体之间检查预算，智能体内部的多轮调用由此限制，超出量不超过一次调用的用量
+func WrapChatModel(m ecmodel.ToolCallingChatModel) ecmodel.ToolCallingChatModel {
+	return &budgetChatModel{inner: m}
+}
+
+// Generate 预算未超出时调用模型
+func (m *budgetChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...ecmodel.Option) (*schema.Message, error) {
+	if err := checkBudget(ctx); err != nil {
+		return nil, err
+	}
+	return m.inner.Generate(ctx, input, opts...)
+}
+
+// Stream 预算未超出时调用模型
+func (m *budgetChatModel) Stream(ctx context.Context, input []*schema.Message, opts ...ecmodel.Option) (*schema.StreamReader[*schema.Message], error) {
+	if err := checkBudget(ctx); err != nil {
+		return nil, err
+	}
+	return m.inner.Stream(ctx, input, opts...)
+}
+
+// WithTools 绑定工具，返回的模型同样检查预算
+func (m *budgetChatModel) WithTools(tools []*schema.ToolInfo) (ecmodel.ToolCallingChatModel, error) {
+	inner, err := m.inner.WithTools(tools)
+	if err != nil {
+		return nil, err
+	}
+	return &budgetChatModel{inner: inner}, nil
+}
+
+// GetType 沿用被包装模型的类型
+func (m *budgetChatModel) GetType() string {
+	typ, _ := components.GetType(m.inner)
+	return typ
+}
+
+// IsCallbacksEnabled 被包装模型自行触发回调时，框架不再重复注入
+func (m *budgetChatModel) IsCallbacksEnabled() bool {
+	return components.IsCallbacksEnabled(m.inner)
+}
+
+// checkBudget 等待之前的流式调用计入用量后检查预算，上下文中没有统计器时不限制
+func checkBudget(ctx context.Context) error {
+	t := FromContext(ctx)
+	if t == nil {
+		return nil
+	}
+	t.Wait()
+	if t.Exceeded() {
+		return ErrBudgetExceeded
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package usage
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/hildam/deer-flow-go/entity/conf"
+	"github.com/hildam/deer-flow-go/entity/model"
+)
+
+// Tracker 单次运行的 token 用量统计器，按 agent 和模型聚合并根据价格表计费
+type Tracker struct {
+	mu        sync.Mutex
+	maxTokens int     // token 预算上限，0 表示不限制
+	maxCost   float64 // 费用预算上限，0 表示不限制
+	total     model.TokenUsage
+	byAgent   map[string]*model.TokenUsage
+	byModel   map[string]*model.TokenUsage
+	pending   sync.WaitGroup // 尚未记录用量的流式调用
+}
+
+// NewTracker 创建用量统计器
+func NewTracker(maxTokens int, maxCost float64) *Tracker {
+	return &Tracker{
+		maxTokens: maxTokens,
+		maxCost:   maxCost,
+		byAgent:   make(map[string]*model.TokenUsage),
+		byModel:   make(map[string]*model.TokenUsage),
+	}
+}
+
+// Restore 以之前记录的用量汇总作为当前用量，从中断恢复的运行在检查点中的用量上继续累计，预算上限保持不变
+func (t *Tracker) Restore(prev *model.UsageSummary) {
+	if prev == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.total = prev.Total
+	t.byAgent = make(map[string]*model.TokenUsage, len(prev.ByAgent))
+	for k, v := range prev.ByAgent {
+		u := *v
+		t.byAgent[k] = &u
+	}
+	t.byModel = make(map[string]*model.TokenUsage, len(prev.ByModel))
+	for k, v := range prev.ByModel {
+		u := *v
+		t.byModel[k] = &u
+	}
+}
+
+// Add 记录一次模型调用的用量
+func (t *Tracker) Add(agentName, modelName string, u model.TokenUsage) {
+	if u.TotalTokens == 0 {
+		u.TotalTokens = u.PromptTokens + u.CompletionTokens
+	}
+	u.Requests = 1
+	u.Cost = price(modelName, u)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.total.Add(u)
+	if _, ok := t.byAgent[agentName]; !ok {
+		t.byAgent[agentName] = &model.TokenUsage{}
+	}
+	t.byAgent[agentName].Add(u)
+	if _, ok := t.byModel[modelName]; !ok {
+		t.byModel[modelName] = &model.TokenUsage{}
+	}
+	t.byModel[modelName].Add(u)
+}
+
+// Track 登记一次流式调用，用量在后台读取流后记录，记录完成后调用返回的 done
+func (t *Tracker) Track() (done func()) {
+	t.pending.Add(1)
+	return t.pending.Done
+}
+
+// Wait 等待已登记的流式调用记录完用量，检查预算或汇总前调用，保证刚结束的调用已计入
+func (t *Tracker) Wait() {
+	t.pending.Wait()
+}
+
+// Exceeded 判断是否已超出预算
+func (t *Tracker) Exceeded() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.exceeded()
+}
+
+func (t *Tracker) exceeded() bool {
+	if t.maxTokens > 0 && t.total.TotalTokens >= t.maxTokens {
+		return true
+	}
+	if t.maxCost > 0 && t.total.Cost >= t.maxCost {
+		return true
+	}
+	return false
+}
+
+// Summary 返回当前用量汇总的快照
+func (t *Tracker) Summary() *model.UsageSummary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	sum := &model.UsageSummary{
+		Total:          t.total,
+		ByAgent:        make(map[string]*model.TokenUsage, len(t.byAgent)),
+		ByModel:        make(map[string]*model.TokenUsage, len(t.byModel)),
+		MaxTokens:      t.maxTokens,
+		MaxCost:        t.maxCost,
+		BudgetExceeded: t.exceeded(),
+	}
+	for k, v := range t.byAgent {
+		u := *v
+		sum.ByAgent[k] = &u
+	}
+	for k, v := range t.byModel {
+		u := *v
+		sum.ByModel[k] = &u
+	}
+	return sum
+}
+
+// price 根据配置的价格表计算费用，未配置价格的模型费用为 0
+func price(modelName string, u model.TokenUsage) float64 {
+	pricing := conf.GetCfg().Model.Pricing
+	idx := slices.IndexFunc(pricing, func(p conf.ModelPrice) bool {
+		return p.Model == modelName
+	})
+	if idx < 0 {
+		return 0
+	}
+	p := pricing[idx]
+	cachedPrice := p.CachedInput
+	if cachedPrice == 0 {
+		cachedPrice = p.Input
+	}
+	uncached := u.PromptTokens - u.CachedPromptTokens
+	return (float64(uncached)*p.Input + float64(u.CachedPromptTokens)*cachedPrice + float64(u.CompletionTokens)*p.Output) / 1e6
+}
+
+// trackerKey 上下文中存放 Tracker 的 key
+type trackerKey struct{}
+
+// WithTracker 将用量统计器注入上下文，供回调和路由使用
+func WithTracker(ctx context.Context, t *Tracker) context.Context {
+	return context.WithValue(ctx, trackerKey{}, t)
+}
+
+// FromContext 从上下文中获取用量统计器，不存在时返回 nil
+func FromContext(ctx context.Context) *Tracker {
+	t, _ := ctx.Value(trackerKey{}).(*Tracker)
+	return t
+}
@@ -0,0 +1,93 @@
+package usage
+
+import (
+	"math"
+	"os"
+	"testing"
+
+	"github.com/hildam/deer-flow-go/entity/conf"
+	"github.com/hildam/deer-flow-go/entity/model"
+)
+
+func TestMain(m *testing.M) {
+	// 单价为每百万 token 的价格
+	conf.SetCfg(&conf.AppConfig{Model: conf.ModelConfig{Pricing: []conf.ModelPrice{
+		{Model: "gpt-4o", Input: 2.5, Output: 10, CachedInput: 1.25},
+		{Model: "deepseek-chat", Input: 0.27, Output: 1.1},
+	}}})
+	os.Exit(m.Run())
+}
+
+func TestPrice(t *testing.T) {
+	tests := []struct {
+		name  string
+		model string
+		usage model.TokenUsage
+		want  float64
+	}{
+		{"input and output", "gpt-4o", model.TokenUsage{PromptTokens: 1_000_000, CompletionTokens: 1_000_000}, 12.5},
+		{"cached input", "gpt-4o", model.TokenUsage{PromptTokens: 1_000_000, CachedPromptTokens: 400_000}, 0.6*2.5 + 0.4*1.25},
+		{"cached input without cached price", "deepseek-chat", model.TokenUsage{PromptTokens: 1_000_000, CachedPromptTokens: 400_000}, 0.27},
+		{"unpriced model", "unknown", model.TokenUsage{PromptTokens: 1_000_000, CompletionTokens: 1_000_000}, 0},
+	}
+	for _, tt := range tests {
+		if got := price(tt.model, tt.usage); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: price = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTrackerBudget(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxTokens int
+		maxCost   float64
+		calls     []model.TokenUsage
+		want      bool
+	}{
+		{"no budget", 0, 0, []model.TokenUsage{{PromptTokens: 10_000_000}}, false},
+		{"under token budget", 1000, 0, []model.TokenUsage{{PromptTokens: 400, CompletionTokens: 100}}, false},
+		{"token budget reached", 1000, 0, []model.TokenUsage{{PromptTokens: 400, CompletionTokens: 100}, {TotalTokens: 500}}, true},
+		{"under cost budget", 0, 10, []model.TokenUsage{{PromptTokens: 1_000_000, CompletionTokens: 500_000}}, false},
+		{"cost budget exceeded", 0, 10, []model.TokenUsage{{PromptTokens: 1_000_000, CompletionTokens: 500_000}, {CompletionTokens: 500_000}}, true},
+	}
+	for _, tt := range tests {
+		tracker := NewTracker(tt.maxTokens, tt.maxCost)
+		for _, u := range tt.calls {
+			tracker.Add("researcher", "gpt-4o", u)
+		}
+		if got := tracker.Exceeded(); got != tt.want {
+			t.Errorf("%s: Exceeded = %v, want %v", tt.name, got, tt.want)
+		}
+		if got := tracker.Summary().BudgetExceeded; got != tt.want {
+			t.Errorf("%s: Summary().BudgetExceeded = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTrackerSummary(t *testing.T) {
+	tracker := NewTracker(0, 0)
+	tracker.Add("planner", "gpt-4o", model.TokenUsage{PromptTokens: 1_000_000, CompletionTokens: 100_000})
+	tracker.Add("researcher", "gpt-4o", model.TokenUsage{PromptTokens: 200, CompletionTokens: 50, TotalTokens: 250})
+	tracker.Add("researcher", "deepseek-chat", model.TokenUsage{PromptTokens: 1_000_000})
+
+	sum := tracker.Summary()
+	if sum.Total.Requests != 3 || sum.Total.TotalTokens != 2_100_250 {
+		t.Errorf("total = %+v, want 3 requests and 2100250 tokens", sum.Total)
+	}
+	if got := sum.ByAgent["researcher"]; got.Requests != 2 || got.TotalTokens != 1_000_250 {
+		t.Errorf("researcher = %+v, want 2 requests and 1000250 tokens", got)
+	}
+	if got := sum.ByModel["deepseek-chat"].Cost; math.Abs(got-0.27) > 1e-9 {
+		t.Errorf("deepseek-chat cost = %v, want 0.27", got)
+	}
+	if got, want := sum.Total.Cost, 2.5+1+0.27+(200*2.5+50*10)/1e6; math.Abs(got-want) > 1e-9 {
+		t.Errorf("total cost = %v, want %v", got, want)
+	}
+
+	// 汇总为快照，之后的调用不影响已返回的汇总
+	tracker.Add("reporter", "gpt-4o", model.TokenUsage{TotalTokens: 1})
+	if sum.Total.Requests != 3 || sum.ByAgent["reporter"] != nil {
+		t.Errorf("summary changed after Add: %+v", sum.Total)
+	}
+}
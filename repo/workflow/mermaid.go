@@ -0,0 +1,64 @@
+package workflow
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cloudwego/eino/compose"
+
+	"github.com/hildam/deer-flow-go/entity/conf"
+	"github.com/hildam/deer-flow-go/entity/consts"
+)
+
+// Mermaid 中 end 是保留字，起止节点使用单独的 ID
+const (
+	mermaidStart = "__start__"
+	mermaidEnd   = "__end__"
+)
+
+// TransitionsMermaid 将完整的流转表渲染为 Mermaid 流程图，用于文档
+func TransitionsMermaid(agents []conf.AgentConfig) string {
+	table := Transitions(agents)
+	names := make([]string, 0, len(table))
+	for name := range table {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	sb := strings.Builder{}
+	sb.WriteString("flowchart TD\n")
+	sb.WriteString(fmt.Sprintf("    %s([START]) --> %s\n", mermaidStart, consts.Coordinator))
+	for _, from := range names {
+		for _, to := range table[from] {
+			sb.WriteString(fmt.Sprintf("    %s --> %s\n", from, mermaidNode(to)))
+		}
+	}
+	return sb.String()
+}
+
+// Mermaid 将工作流渲染为 Mermaid 流程图，经 routes 改写的流转以原 Goto 作为标签
+func (w *Workflow) Mermaid() string {
+	sb := strings.Builder{}
+	sb.WriteString("flowchart TD\n")
+	sb.WriteString(fmt.Sprintf("    %s([START]) --> %s\n", mermaidStart, w.Entry))
+	for _, n := range w.Nodes {
+		for _, out := range w.transitions[n.Name] {
+			to := n.next(out)
+			if to != out {
+				sb.WriteString(fmt.Sprintf("    %s -->|%s| %s\n", n.Name, out, mermaidNode(to)))
+				continue
+			}
+			sb.WriteString(fmt.Sprintf("    %s --> %s\n", n.Name, mermaidNode(to)))
+		}
+	}
+	return sb.String()
+}
+
+// mermaidNode 节点在流程图中的写法
+func mermaidNode(name string) string {
+	if name == compose.END {
+		return mermaidEnd + "([END])"
+	}
+	return name
+}
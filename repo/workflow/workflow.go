@@ -0,0 +1,328 @@
+package workflow
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cloudwego/eino/compose"
+	"gopkg.in/yaml.v3"
+
+	"github.com/hildam/deer-flow-go/entity/conf"
+	"github.com/hildam/deer-flow-go/entity/consts"
+	"github.com/hildam/deer-flow-go/entity/model"
+	embedded "github.com/hildam/deer-flow-go/workflows"
+)
+
+// 默认配置
+const (
+	defaultDir  = "workflows"     // 默认的工作流目录
+	DefaultName = "deep_research" // 未指定工作流时使用的工作流
+)
+
+// Workflow 工作流定义，声明参与的节点、节点间允许的流转和入口节点
+type Workflow struct {
+	Name        string              `yaml:"-" json:"name"`                            // 工作流名称，即文件名
+	Description string              `yaml:"description" json:"description,omitempty"` // 工作流说明
+	Entry       string              `yaml:"entry" json:"entry"`                       // 入口节点
+	Nodes       []Node              `yaml:"nodes" json:"nodes"`                       // 参与的节点
+	Source      string              `yaml:"-" json:"source"`                          // 定义来源：embedded 或 override
+	Custom      bool                `yaml:"-" json:"custom_agents,omitempty"`         // 是否自动加入了自定义智能体
+	transitions map[string][]string // 智能体 -> 可设置的 Goto，即流转表
+	targets     map[string][]string // 节点 -> 可流转到的节点
+}
+
+// Node 工作流节点
+type Node struct {
+	Name   string            `yaml:"name" json:"name"`                         // 智能体名称
+	Routes map[string]string `yaml:"routes,omitempty" json:"routes,omitempty"` // 改写智能体设置的 Goto，如 planner: researcher，未列出的目标保持不变
+}
+
+// 定义来源
+const (
+	SourceEmbedded = "embedded" // 内置定义
+	SourceOverride = "override" // 工作流目录中的定义
+)
+
+var (
+	mu        sync.Mutex
+	workflows map[string]*Workflow // 工作流名称 -> 工作流，未调用 Init 时在首次使用时加载
+)
+
+// Init 加载并校验全部工作流，存在无效定义时返回错误
+func Init() error {
+	loaded, err := Load(dir(), agents())
+	if err != nil {
+		return fmt.Errorf("Init workflows failed, err: %w", err)
+	}
+	mu.Lock()
+	workflows = loaded
+	mu.Unlock()
+	return nil
+}
+
+// Get 获取工作流并按 agents 重新校验，名称为空时使用配置的默认工作流
+// 返回的工作流为副本，自定义智能体与构建运行图时使用的配置一致
+func Get(name string, agents []conf.AgentConfig) (*Workflow, error) {
+	if name == "" {
+		name = defaultName()
+	}
+	all, err := loaded()
+	if err != nil {
+		return nil, err
+	}
+	wf, ok := all[name]
+	if !ok {
+		return nil, fmt.Errorf("workflow %s not found", name)
+	}
+	return wf.bind(agents)
+}
+
+// Check 校验工作流存在且与当前配置的自定义智能体兼容，名称为空时校验默认工作流
+func Check(name string) error {
+	_, err := Get(name, agents())
+	return err
+}
+
+// List 返回全部工作流，按名称排序
+func List() ([]*Workflow, error) {
+	all, err := loaded()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*Workflow, 0, len(all))
+	for _, wf := range all {
+		list = append(list, wf)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list, nil
+}
+
+// loaded 返回已加载的工作流，未初始化时加载
+func loaded() (map[string]*Workflow, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if workflows == nil {
+		all, err := Load(dir(), agents())
+		if err != nil {
+			return nil, err
+		}
+		workflows = all
+	}
+	return workflows, nil
+}
+
+// Load 加载内置工作流和 dir 中的工作流并逐一校验，dir 中的同名定义优先
+func Load(dir string, agents []conf.AgentConfig) (map[string]*Workflow, error) {
+	all := map[string]*Workflow{}
+	if err := loadFS(all, embedded.FS, SourceEmbedded); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(dir); err == nil {
+		if err := loadFS(all, os.DirFS(dir), SourceOverride); err != nil {
+			return nil, err
+		}
+	}
+	for _, wf := range all {
+		if _, err := wf.bind(agents); err != nil {
+			return nil, err
+		}
+	}
+	return all, nil
+}
+
+// loadFS 加载文件系统根目录中的 .yaml 文件
+func loadFS(all map[string]*Workflow, fsys fs.FS, source string) error {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() || (path.Ext(e.Name()) != ".yaml" && path.Ext(e.Name()) != ".yml") {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return err
+		}
+		wf := &Workflow{}
+		if err := yaml.Unmarshal(data, wf); err != nil {
+			return fmt.Errorf("invalid workflow %s: %w", e.Name(), err)
+		}
+		wf.Name = strings.TrimSuffix(e.Name(), path.Ext(e.Name()))
+		wf.Source = source
+		all[wf.Name] = wf
+	}
+	return nil
+}
+
+// Validate 校验工作流并计算各节点可流转到的节点
+// 节点必须是内置或自定义智能体，改写的 Goto 必须是智能体可能设置的值，流转目标必须在工作流中，且所有节点都能从入口到达
+// 包含 research_team 的工作流自动加入全部自定义智能体，由其执行自定义类型的步骤
+func (w *Workflow) Validate(agents []conf.AgentConfig) error {
+	outs := Transitions(agents)
+	if slices.ContainsFunc(w.Nodes, func(n Node) bool { return n.Name == consts.ResearchTeam }) {
+		for _, a := range agents {
+			if !slices.ContainsFunc(w.Nodes, func(n Node) bool { return n.Name == a.Name }) {
+				w.Nodes = append(w.Nodes, Node{Name: a.Name})
+				w.Custom = true
+			}
+		}
+	}
+
+	nodes := map[string]*Node{}
+	for i := range w.Nodes {
+		n := &w.Nodes[i]
+		if _, ok := outs[n.Name]; !ok {
+			return fmt.Errorf("workflow %s: unknown agent %q", w.Name, n.Name)
+		}
+		if nodes[n.Name] != nil {
+			return fmt.Errorf("workflow %s: duplicate node %s", w.Name, n.Name)
+		}
+		nodes[n.Name] = n
+	}
+	if nodes[w.Entry] == nil {
+		return fmt.Errorf("workflow %s: entry %q is not a node of the workflow", w.Name, w.Entry)
+	}
+
+	targets := map[string][]string{}
+	for _, n := range w.Nodes {
+		for from := range n.Routes {
+			if !slices.Contains(outs[n.Name], from) {
+				return fmt.Errorf("workflow %s: node %s never routes to %s", w.Name, n.Name, from)
+			}
+		}
+		for _, out := range outs[n.Name] {
+			to := n.next(out)
+			if to != compose.END && nodes[to] == nil {
+				return fmt.Errorf("workflow %s: node %s routes to %s which is not a node of the workflow", w.Name, n.Name, to)
+			}
+			if !slices.Contains(targets[n.Name], to) {
+				targets[n.Name] = append(targets[n.Name], to)
+			}
+		}
+	}
+
+	// 从入口遍历，找出无法到达的节点
+	reached := map[string]bool{w.Entry: true}
+	queue := []string{w.Entry}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, to := range targets[cur] {
+			if to != compose.END && !reached[to] {
+				reached[to] = true
+				queue = append(queue, to)
+			}
+		}
+	}
+	for _, n := range w.Nodes {
+		if !reached[n.Name] {
+			return fmt.Errorf("workflow %s: node %s is unreachable from entry %s", w.Name, n.Name, w.Entry)
+		}
+	}
+	w.transitions = outs
+	w.targets = targets
+	return nil
+}
+
+// bind 复制工作流并按 agents 校验，加载的定义保持原样，自定义智能体变化后仍可重新校验
+func (w *Workflow) bind(agents []conf.AgentConfig) (*Workflow, error) {
+	cp := *w
+	cp.Nodes = slices.Clone(w.Nodes)
+	if err := cp.Validate(agents); err != nil {
+		return nil, err
+	}
+	return &cp, nil
+}
+
+// NodeNames 工作流中的全部节点
+func (w *Workflow) NodeNames() []string {
+	names := make([]string, 0, len(w.Nodes))
+	for _, n := range w.Nodes {
+		names = append(names, n.Name)
+	}
+	return names
+}
+
+// Targets 节点可流转到的节点，包括 END
+func (w *Workflow) Targets(node string) []string {
+	return w.targets[node]
+}
+
+// Next 按工作流改写节点设置的 Goto，返回实际流转到的节点
+// Goto 不在智能体的流转表中，或改写后的目标不在工作流中时返回 *model.RouteError
+func (w *Workflow) Next(node, next string) (string, error) {
+	i := slices.IndexFunc(w.Nodes, func(n Node) bool { return n.Name == node })
+	if i < 0 {
+		return "", fmt.Errorf("workflow %s: unknown node %s", w.Name, node)
+	}
+	if !slices.Contains(w.transitions[node], next) {
+		return "", &model.RouteError{Workflow: w.Name, Agent: node, Goto: next, Allowed: w.transitions[node]}
+	}
+	to := w.Nodes[i].next(next)
+	if !slices.Contains(w.targets[node], to) {
+		return "", &model.RouteError{Workflow: w.Name, Agent: node, Goto: to, Allowed: w.targets[node]}
+	}
+	return to, nil
+}
+
+// next 改写后的 Goto
+func (n *Node) next(out string) string {
+	if to, ok := n.Routes[out]; ok {
+		return to
+	}
+	return out
+}
+
+// Transitions 流转表，即各智能体的 router 可能设置的 Goto，运行时设置表外的 Goto 会使运行失败
+// 注意：需要与各智能体 router 的实现保持一致
+func Transitions(agents []conf.AgentConfig) map[string][]string {
+	team := []string{consts.Planner, consts.Researcher, consts.Coder, consts.Reporter}
+	m := map[string][]string{
+		consts.Coordinator:            {consts.Planner, consts.BackgroundInvestigator, compose.END},
+		consts.BackgroundInvestigator: {consts.Planner},
+		consts.Planner:                {consts.Reporter, consts.Human, compose.END},
+		consts.Human:                  {consts.Planner, consts.ResearchTeam},
+		consts.Researcher:             {consts.ResearchTeam},
+		consts.Coder:                  {consts.ResearchTeam},
+		consts.Reporter:               {compose.END},
+	}
+	for _, a := range agents {
+		team = append(team, a.Name)
+		m[a.Name] = []string{consts.ResearchTeam}
+	}
+	m[consts.ResearchTeam] = team
+	return m
+}
+
+// dir 工作流目录，未配置时使用工作目录下的 workflows
+func dir() string {
+	if cfg := conf.GetCfg(); cfg != nil && cfg.Workflow.Dir != "" {
+		return cfg.Workflow.Dir
+	}
+	return defaultDir
+}
+
+// defaultName 配置的默认工作流
+func defaultName() string {
+	if cfg := conf.GetCfg(); cfg != nil && cfg.Workflow.Default != "" {
+		return cfg.Workflow.Default
+	}
+	return DefaultName
+}
+
+// agents 配置中的自定义智能体
+func agents() []conf.AgentConfig {
+	if cfg := conf.GetCfg(); cfg != nil {
+		return cfg.Agents
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package workflow
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/compose"
+
+	"github.com/hildam/deer-flow-go/entity/conf"
+	"github.com/hildam/deer-flow-go/entity/consts"
+	"github.com/hildam/deer-flow-go/entity/model"
+)
+
+func TestLoadEmbedded(t *testing.T) {
+	all, err := Load(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	for _, name := range []string{DefaultName, "quick_answer", "plan_only", "code_analysis"} {
+		if wf, ok := all[name]; !ok || wf.Source != SourceEmbedded {
+			t.Errorf("workflow %s = %+v, want embedded", name, wf)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		def  string
+		want string // 错误信息片段，为空表示合法
+	}{
+		{"valid", "entry: coordinator\nnodes:\n  - name: coordinator\n    routes: {planner: reporter, background_investigator: reporter}\n  - name: reporter\n", ""},
+		{"unknown agent", "entry: coordinator\nnodes:\n  - name: coordinator\n  - name: translator\n", `unknown agent "translator"`},
+		{"missing entry", "entry: planner\nnodes:\n  - name: reporter\n", "entry"},
+		{"invalid goto target", "entry: coordinator\nnodes:\n  - name: coordinator\n", "routes to planner which is not a node"},
+		{"invalid route", "entry: reporter\nnodes:\n  - name: reporter\n    routes: {planner: end}\n", "never routes to planner"},
+		{"unreachable", "entry: reporter\nnodes:\n  - name: reporter\n  - name: coder\n    routes: {research_team: end}\n", "coder is unreachable"},
+		{"duplicate", "entry: reporter\nnodes:\n  - name: reporter\n  - name: reporter\n", "duplicate node"},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "custom.yaml"), []byte(tt.def), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := Load(dir, nil)
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("%s: Load err = %v", tt.name, err)
+		case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+			t.Errorf("%s: Load err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	all, err := Load(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	wf, err := all["quick_answer"].bind(nil)
+	if err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	if next, err := wf.Next(consts.Coordinator, consts.Planner); err != nil || next != consts.Researcher {
+		t.Errorf("Next(coordinator, planner) = %q, %v, want researcher", next, err)
+	}
+	if next, err := wf.Next(consts.Coordinator, compose.END); err != nil || next != compose.END {
+		t.Errorf("Next(coordinator, end) = %q, %v, want end", next, err)
+	}
+	if _, err := wf.Next(consts.Researcher, consts.Coder); err == nil {
+		t.Error("Next(researcher, coder): want error")
+	}
+	if got := wf.Targets(consts.Coordinator); !reflect.DeepEqual(got, []string{consts.Researcher, compose.END}) {
+		t.Errorf("Targets(coordinator) = %v", got)
+	}
+}
+
+func TestBindCustomAgents(t *testing.T) {
+	all, err := Load(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	agents := []conf.AgentConfig{{Name: "legal_reviewer", StepType: "legal_review"}}
+	// 包含 research_team 的工作流自动加入自定义智能体
+	wf, err := all[DefaultName].bind(agents)
+	if err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	if !wf.Custom || wf.Targets("legal_reviewer")[0] != consts.ResearchTeam {
+		t.Errorf("deep_research nodes = %v, want legal_reviewer", wf.NodeNames())
+	}
+	// 加载的定义保持原样
+	if all[DefaultName].Custom || len(all[DefaultName].Nodes) == len(wf.Nodes) {
+		t.Error("bind modified the loaded workflow")
+	}
+	if wf, _ := all["quick_answer"].bind(agents); wf.Custom {
+		t.Errorf("quick_answer nodes = %v, want no custom agents", wf.NodeNames())
+	}
+}
+
+func TestNextRouteError(t *testing.T) {
+	all, err := Load(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	wf, err := all[DefaultName].bind(nil)
+	if err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	_, err = wf.Next(consts.Planner, consts.Researcher)
+	var re *model.RouteError
+	if !errors.As(err, &re) {
+		t.Fatalf("Next(planner, researcher) err = %v, want *model.RouteError", err)
+	}
+	want := []string{consts.Reporter, consts.Human, compose.END}
+	if re.Agent != consts.Planner || re.Goto != consts.Researcher || !reflect.DeepEqual(re.Allowed, want) {
+		t.Errorf("RouteError = %+v", re)
+	}
+}
+
+func TestMermaid(t *testing.T) {
+	all, err := Load(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	wf, err := all["quick_answer"].bind(nil)
+	if err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	got := wf.Mermaid()
+	for _, line := range []string{
+		"__start__([START]) --> coordinator",
+		"coordinator -->|planner| researcher",
+		"researcher -->|research_team| reporter",
+		"reporter --> __end__([END])",
+	} {
+		if !strings.Contains(got, line) {
+			t.Errorf("Mermaid() missing %q:\n%s", line, got)
+		}
+	}
+
+	agents := []conf.AgentConfig{{Name: "legal_reviewer", StepType: "legal_review"}}
+	got = TransitionsMermaid(agents)
+	for _, line := range []string{
+		"planner --> human_feedback",
+		"research_team --> legal_reviewer",
+		"legal_reviewer --> research_team",
+	} {
+		if !strings.Contains(got, line) {
+			t.Errorf("TransitionsMermaid() missing %q:\n%s", line, got)
+		}
+	}
+}
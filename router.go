@@ -0,0 +1,24 @@
+package main
+
+import (
+	"github.com/cloudwego/hertz/pkg/app/server"
+
+	"github.com/hildam/deer-flow-go/biz/handler"
+)
+
+// register 注册HTTP路由
+func register(r *server.Hertz) {
+	r.POST("/api/chat/stream", handler.ChatStream)
+	r.POST("/api/chat/:thread_id/cancel", handler.CancelChat)
+	r.GET("/api/runs", handler.ListRuns)
+	r.GET("/api/runs/:thread_id", handler.GetRun)
+	r.DELETE("/api/runs/:thread_id", handler.DeleteRun)
+	r.GET("/api/runs/:thread_id/export", handler.ExportRun)
+	r.GET("/api/runs/:thread_id/artifacts", handler.ListArtifacts)
+	r.GET("/api/runs/:thread_id/artifacts/*name", handler.DownloadArtifact)
+	r.GET("/api/prompts", handler.ListPrompts)
+	r.GET("/api/workflows", handler.ListWorkflows)
+	r.GET("/api/workflows/:name", handler.GetWorkflow)
+	r.GET("/api/transitions", handler.GetTransitions)
+	r.GET("/metrics", handler.Metrics)
+}
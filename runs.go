@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hildam/deer-flow-go/entity/model"
+	"github.com/hildam/deer-flow-go/repo/artifact"
+	"github.com/hildam/deer-flow-go/repo/export"
+	"github.com/hildam/deer-flow-go/repo/history"
+)
+
+const runsUsage = `用法：
+  deer-flow-go runs list   [--status STATUS] [--since RFC3339] [--until RFC3339] [--limit N] [--offset N] [--format text|json]
+  deer-flow-go runs search KEYWORD [--limit N] [--format text|json]
+  deer-flow-go runs get    THREAD_ID [--report]
+  deer-flow-go runs delete THREAD_ID
+  deer-flow-go runs export THREAD_ID [--format html|pdf|docx|json|md] [--output FILE] [--base-url URL]
+`
+
+// runRuns 运行历史子命令
+func runRuns(args []string) error {
+	if len(args) == 0 {
+		fmt.Print(runsUsage)
+		return exitWith(exitUsage, nil)
+	}
+	cmd, args := args[0], args[1:]
+	fs := flag.NewFlagSet("runs "+cmd, flag.ExitOnError)
+	fs.StringVar(&configPath, "config", configPath, "配置文件路径")
+	status := fs.String("status", "", "按运行状态过滤：running/completed/failed/interrupted/cancelled")
+	since := fs.String("since", "", "开始时间下限（RFC3339）")
+	until := fs.String("until", "", "开始时间上限（RFC3339）")
+	limit := fs.Int("limit", 20, "最多返回的记录数，0 表示不限制")
+	offset := fs.Int("offset", 0, "分页偏移")
+	reportOnly := fs.Bool("report", false, "仅输出最终报告")
+	format := fs.String("format", "", "list/search 的输出格式：text 或 json；export 的导出格式："+strings.Join(export.Formats(), "/"))
+	output := fs.String("output", "", "export 的输出文件，默认 <thread_id>.<格式后缀>，- 表示标准输出")
+	baseURL := fs.String("base-url", "", "export 时报告中产物链接使用的服务地址，如 http://localhost:8888")
+
+	// 位置参数在前，flag 在后
+	var positional string
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		positional, args = args[0], args[1:]
+	}
+	_ = fs.Parse(args)
+	if positional == "" {
+		positional = fs.Arg(0)
+	}
+
+	if *limit < 0 || *offset < 0 {
+		fmt.Fprintln(os.Stderr, "--limit and --offset must not be negative")
+		fmt.Print(runsUsage)
+		return exitWith(exitUsage, nil)
+	}
+
+	// 仅删除需要写入运行历史，其余子命令只读打开，可在服务或控制台运行时使用
+	initHistory := history.InitReadOnly
+	if cmd == "delete" {
+		initHistory = history.Init
+	}
+	defer func() {
+		_ = history.Close()
+	}()
+	if err := initAll(initConf, initHistory, artifact.Init); err != nil {
+		return err
+	}
+
+	var err error
+	switch cmd {
+	case "list", "search":
+		opt := history.ListOption{Status: *status, Limit: *limit, Offset: *offset}
+		if opt.Since, err = parseTime(*since); err != nil {
+			break
+		}
+		if opt.Until, err = parseTime(*until); err != nil {
+			break
+		}
+		var records []*model.RunRecord
+		if cmd == "search" {
+			records, err = history.Search(positional, opt)
+		} else {
+			records, err = history.List(opt)
+		}
+		if err != nil {
+			break
+		}
+		if *format == formatJSON {
+			err = writeJSON("", records)
+		} else {
+			printRuns(records)
+		}
+	case "get":
+		var rec *model.RunRecord
+		if rec, err = history.Get(positional); err != nil {
+			break
+		}
+		if *reportOnly {
+			fmt.Println(rec.Report)
+			break
+		}
+		err = writeJSON("", rec)
+	case "export":
+		var rec *model.RunRecord
+		if rec, err = history.Get(positional); err != nil {
+			break
+		}
+		opt := export.Options{Format: *format, BaseURL: *baseURL}
+		if opt.Format == "" {
+			opt.Format = export.FormatHTML
+		}
+		var buf bytes.Buffer
+		if err = export.Export(context.Background(), rec, opt, &buf); err != nil {
+			break
+		}
+		path := *output
+		if path == "" {
+			path = export.FileName(rec.ID, opt.Format)
+		}
+		if err = writeOutput(path, buf.Bytes()); err == nil && path != "-" {
+			fmt.Fprintf(os.Stderr, "exported %s\n", path)
+		}
+	case "delete":
+		if err = history.Delete(positional); err != nil {
+			break
+		}
+		if err = artifact.DeleteRun(context.Background(), positional); err == nil {
+			fmt.Printf("deleted %s\n", positional)
+		}
+	default:
+		fmt.Print(runsUsage)
+		return exitWith(exitUsage, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("runs %s failed, err: %w", cmd, err)
+	}
+	return nil
+}
+
+// printRuns 以表格形式输出运行记录
+func printRuns(records []*model.RunRecord) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "THREAD_ID\tSTATUS\tSTARTED\tDURATION\tTOKENS\tQUERY")
+	for _, rec := range records {
+		tokens := 0
+		if rec.Usage != nil {
+			tokens = rec.Usage.Total.TotalTokens
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\n", rec.ID, rec.Status,
+			rec.StartedAt.Format(time.DateTime), rec.Duration().Round(time.Second), tokens, truncate(rec.Query, 60))
+	}
+	_ = w.Flush()
+}
+
+// parseTime 解析 RFC3339 时间，空字符串返回零值
+func parseTime(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+// truncate 按字符截断字符串
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "..."
+}
@@ -0,0 +1,11 @@
+// Package workflows 内置的工作流定义，编译进二进制文件
+//
+// 每个文件定义一个工作流：参与的节点、节点间允许的流转和入口节点，文件名即工作流名称
+package workflows
+
+import "embed"
+
+// FS 内置的工作流定义
+//
+//go:embed *.yaml
+var FS embed.FS